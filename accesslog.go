@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clfTimeFormat is the timestamp layout of the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// openAccessLog resolves MIRAIO_ACCESS_LOG: "" disables access logging, "-"
+// means stdout, anything else is a file path opened for appending.
+func openAccessLog(dest string) (io.Writer, error) {
+	switch dest {
+	case "":
+		return nil, nil
+	case "-":
+		return os.Stdout, nil
+	default:
+		return os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+}
+
+// accessLog writes one Common Log Format line per request to w, separately
+// from the application log.
+func accessLog(w io.Writer) gin.HandlerFunc {
+	logger := log.New(w, "", 0)
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		size := "-"
+		if n := c.Writer.Size(); n > 0 {
+			size = fmt.Sprint(n)
+		}
+		logger.Printf("%s - - [%s] \"%s %s %s\" %d %s",
+			c.ClientIP(),
+			start.Format(clfTimeFormat),
+			c.Request.Method,
+			c.Request.URL.RequestURI(),
+			c.Request.Proto,
+			c.Writer.Status(),
+			size,
+		)
+	}
+}
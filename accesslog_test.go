@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+
+	router := gin.New()
+	router.Use(accessLog(&buf))
+	router.GET("/hello", func(c *gin.Context) { c.String(http.StatusOK, "hi") })
+	router.DELETE("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req, err := http.NewRequest("GET", "/hello?name=x", nil)
+	require.NoError(t, err)
+	req.RemoteAddr = "192.0.2.1:1234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, err = http.NewRequest("DELETE", "/empty", nil)
+	require.NoError(t, err)
+	req.RemoteAddr = "192.0.2.1:1234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	clf := regexp.MustCompile(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /hello\?name=x HTTP/1\.1" 200 2$`)
+	assert.Regexp(t, clf, string(lines[0]))
+	assert.Contains(t, string(lines[1]), `"DELETE /empty HTTP/1.1" 204 -`)
+}
+
+func TestOpenAccessLog(t *testing.T) {
+	w, err := openAccessLog("")
+	require.NoError(t, err)
+	assert.Nil(t, w)
+
+	w, err = openAccessLog(t.TempDir() + "/access.log")
+	require.NoError(t, err)
+	assert.NotNil(t, w)
+
+	_, err = openAccessLog("/nonexistent/dir/access.log")
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// Context keys handlers use to tell audited which objects they touched.
+const (
+	auditKeyContext    = "miraio.audit.key"
+	auditTargetContext = "miraio.audit.target"
+	auditDetailContext = "miraio.audit.detail"
+)
+
+// auditLog receives one record per mutating operation (MIRAIO_AUDIT_LOG).
+// Nil disables auditing.
+var auditLog *auditLogger
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	APIKey    string    `json:"apiKey,omitempty"`
+	ClientIP  string    `json:"clientIp"`
+	Key       string    `json:"key,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	VersionID string    `json:"versionId,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	Status    int       `json:"status"`
+	Result    string    `json:"result"`
+}
+
+// auditLogger appends JSON lines to a file, syncing each one to disk before
+// the operation's response is sent.
+type auditLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openAuditLog opens MIRAIO_AUDIT_LOG for appending. "-" writes to stdout,
+// "" disables auditing.
+func openAuditLog(dest string) (*auditLogger, error) {
+	switch dest {
+	case "":
+		return nil, nil
+	case "-":
+		return &auditLogger{file: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLogger{file: f}, nil
+}
+
+// write appends rec and waits for it to reach the disk.
+func (l *auditLogger) write(rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	if l.file == os.Stdout {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// auditKey records the object key an audited operation acts on.
+func auditKey(c *gin.Context, key string) {
+	c.Set(auditKeyContext, key)
+}
+
+// auditTarget records the second object of an operation, such as the
+// destination of a copy.
+func auditTarget(c *gin.Context, key string) {
+	c.Set(auditTargetContext, key)
+}
+
+// auditDetail adds a short note to the record, such as purge counts.
+func auditDetail(c *gin.Context, detail string) {
+	c.Set(auditDetailContext, detail)
+}
+
+// auditWriter holds back the response until the audit record is written,
+// so a client never sees a success that was not recorded.
+type auditWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// WriteHeaderNow is deferred to flush.
+func (w *auditWriter) WriteHeaderNow() {}
+
+func (w *auditWriter) flush() {
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+// audited records operation in the audit log: who called (the masked API
+// key and client IP), the object, and the outcome. Failed attempts are
+// recorded too. If the record cannot be written the response still goes
+// out, since the operation has already happened, and the failure is logged.
+func audited(operation string) gin.HandlerFunc {
+	return func(c *gin.Context) { auditRequest(c, operation) }
+}
+
+// auditRequest is the body of audited, for middleware that only learns the
+// operation from the request.
+func auditRequest(c *gin.Context, operation string) {
+	if auditLog == nil {
+		c.Next()
+		return
+	}
+	w := &auditWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	defer func() {
+		// A panic discards the buffered response; recovery answers
+		// with 500, which is what gets recorded.
+		if p := recover(); p != nil {
+			c.Writer = w.ResponseWriter
+			writeAuditRecord(c, operation, http.StatusInternalServerError)
+			panic(p)
+		}
+	}()
+
+	c.Next()
+
+	c.Writer = w.ResponseWriter
+	writeAuditRecord(c, operation, w.Status())
+	w.flush()
+}
+
+func writeAuditRecord(c *gin.Context, operation string, status int) {
+	rec := auditRecord{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		ClientIP:  c.ClientIP(),
+		Key:       c.GetString(auditKeyContext),
+		Target:    c.GetString(auditTargetContext),
+		VersionID: c.Query("versionId"),
+		Detail:    c.GetString(auditDetailContext),
+		Status:    status,
+		Result:    "success",
+	}
+	if key := requestAPIKey(c); key != "" {
+		rec.APIKey = utils.MaskSecret(key)
+	}
+	if rec.Key == "" {
+		rec.Key = c.Param("filename")
+	}
+	if status >= 400 {
+		rec.Result = "failure"
+	}
+	if err := auditLog.write(rec); err != nil {
+		utils.LogError("Failed to write audit record for %s %s: %v", operation, rec.Key, err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useAuditLog enables auditing to a temporary file for the test and returns
+// a function that reads back the records written so far.
+func useAuditLog(t *testing.T) func() []auditRecord {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := openAuditLog(path)
+	require.NoError(t, err)
+	auditLog = l
+	t.Cleanup(func() {
+		auditLog = nil
+		l.file.Close()
+	})
+
+	return func() []auditRecord {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		var records []auditRecord
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var rec auditRecord
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+			records = append(records, rec)
+		}
+		return records
+	}
+}
+
+func TestOpenAuditLog(t *testing.T) {
+	l, err := openAuditLog("")
+	require.NoError(t, err)
+	assert.Nil(t, l)
+
+	l, err = openAuditLog("-")
+	require.NoError(t, err)
+	assert.Equal(t, os.Stdout, l.file)
+
+	_, err = openAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
+
+func TestAudited(t *testing.T) {
+	records := useAuditLog(t)
+
+	router := gin.New()
+	router.DELETE("/objects/:filename", audited("delete"), func(c *gin.Context) {
+		auditKey(c, "uploads/"+c.Param("filename"))
+		if c.Param("filename") == "missing.txt" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/copy", audited("copy"), func(c *gin.Context) {
+		auditKey(c, "a.txt")
+		auditTarget(c, "b.txt")
+		auditDetail(c, "move")
+		c.JSON(http.StatusOK, gin.H{"key": "b.txt"})
+	})
+
+	send := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-API-Key", "supersecretkey")
+		req.RemoteAddr = "192.0.2.7:4000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/objects/cat.jpg?versionId=v1").Code)
+	w := send("DELETE", "/objects/missing.txt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Object not found")
+	w = send("POST", "/copy")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"key":"b.txt"}`, w.Body.String())
+
+	got := records()
+	require.Len(t, got, 3)
+
+	assert.Equal(t, "delete", got[0].Operation)
+	assert.Equal(t, "uploads/cat.jpg", got[0].Key)
+	assert.Equal(t, "v1", got[0].VersionID)
+	assert.Equal(t, "supe****", got[0].APIKey)
+	assert.Equal(t, "192.0.2.7", got[0].ClientIP)
+	assert.Equal(t, http.StatusNoContent, got[0].Status)
+	assert.Equal(t, "success", got[0].Result)
+	assert.False(t, got[0].Time.IsZero())
+
+	assert.Equal(t, "failure", got[1].Result)
+	assert.Equal(t, http.StatusNotFound, got[1].Status)
+
+	assert.Equal(t, "copy", got[2].Operation)
+	assert.Equal(t, "a.txt", got[2].Key)
+	assert.Equal(t, "b.txt", got[2].Target)
+	assert.Equal(t, "move", got[2].Detail)
+}
+
+func TestAudited_RecordsPanics(t *testing.T) {
+	records := useAuditLog(t)
+
+	router := gin.New()
+	router.Use(recovery())
+	router.DELETE("/objects/:filename", audited("delete"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"partial": true})
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/objects/cat.jpg", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "partial")
+
+	got := records()
+	require.Len(t, got, 1)
+	assert.Equal(t, "cat.jpg", got[0].Key)
+	assert.Equal(t, "failure", got[0].Result)
+}
+
+func TestAudited_Disabled(t *testing.T) {
+	router := gin.New()
+	router.DELETE("/objects/:filename", audited("delete"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/objects/cat.jpg", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
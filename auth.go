@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKey is the shared secret required by administrative endpoints. When it
+// is empty, API-key auth is disabled and those endpoints are not registered.
+var apiKey string
+
+// authEnabled reports whether API-key auth is configured.
+func authEnabled() bool {
+	return apiKey != ""
+}
+
+// requestAPIKey extracts the caller's key from the X-API-Key header or an
+// Authorization: Bearer header.
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// validAPIKey reports whether the request presents the configured API key.
+// It is always false when auth is disabled.
+func validAPIKey(c *gin.Context) bool {
+	return matchesAPIKey(requestAPIKey(c))
+}
+
+// matchesAPIKey reports whether key is the configured API key, in constant
+// time. It is always false when auth is disabled.
+func matchesAPIKey(key string) bool {
+	return authEnabled() && key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1
+}
+
+// requireAPIKey rejects requests that do not present the configured API key.
+func requireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !validAPIKey(c) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing API key"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	apiKey = "secret-key"
+	defer func() { apiKey = "" }()
+
+	router := gin.New()
+	router.GET("/protected", requireAPIKey(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{"No key", "", "", http.StatusUnauthorized},
+		{"Wrong key", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"X-API-Key header", "X-API-Key", "secret-key", http.StatusOK},
+		{"Bearer token", "Authorization", "Bearer secret-key", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/protected", nil)
+			require.NoError(t, err)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// RetryAfterSeconds is the Retry-After hint sent when the backend is down.
+const RetryAfterSeconds = "5"
+
+// isTransientError reports whether err looks like a connection-level failure
+// (unreachable host, refused connection, timeout) rather than a definitive
+// answer from MinIO.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// BackendAuthErrorCode marks 500 responses caused by MinIO rejecting the
+// server's own credentials, which no retry will fix.
+const BackendAuthErrorCode = "backend_auth_error"
+
+// isAuthError reports whether MinIO rejected the request's credentials or
+// permissions, which points at misconfiguration rather than an outage.
+func isAuthError(err error) bool {
+	switch minio.ToErrorResponse(err).Code {
+	case "SignatureDoesNotMatch", "InvalidAccessKeyId", "AccessDenied":
+		return true
+	}
+	return false
+}
+
+// respondBackendError maps a failed MinIO call to an HTTP response. Transient
+// failures become 503 with Retry-After so clients back off and retry;
+// credential and permission errors are a 500 with BackendAuthErrorCode;
+// anything else is a 500 carrying message. Calls cut short by the handler
+// timeout get 504.
+func respondBackendError(c *gin.Context, ctx context.Context, err error, message string) {
+	if handlerTimedOut(c) {
+		respondTimeout(c)
+		return
+	}
+	logContextAbort(ctx, c, err)
+
+	if isTransientError(err) {
+		utils.LogError("Storage backend unavailable during %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.Header("Retry-After", RetryAfterSeconds)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Storage backend unavailable"})
+		return
+	}
+
+	if isAuthError(err) {
+		utils.LogError("MinIO rejected the server's credentials during %s %s; check the access key, secret key and bucket policy: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": BackendAuthErrorCode})
+		return
+	}
+
+	utils.LogError("%s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransientError(t *testing.T) {
+	assert.False(t, isTransientError(nil))
+	assert.True(t, isTransientError(context.DeadlineExceeded))
+	assert.False(t, isTransientError(errors.New("AccessDenied")))
+}
+
+func TestIsAuthError(t *testing.T) {
+	for _, code := range []string{"SignatureDoesNotMatch", "InvalidAccessKeyId", "AccessDenied"} {
+		assert.True(t, isAuthError(minio.ErrorResponse{Code: code}), code)
+	}
+	assert.False(t, isAuthError(minio.ErrorResponse{Code: "NoSuchKey"}))
+	assert.False(t, isAuthError(context.DeadlineExceeded))
+}
+
+func TestConfirmHandler_BackendAuthError(t *testing.T) {
+	setupTestEnvironment()
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Minio-Error-Code", "InvalidAccessKeyId")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	router := gin.New()
+	router.GET("/confirm", confirmHandler)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/confirm?filename=a.txt", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.JSONEq(t, `{"error":"Could not confirm upload","code":"`+BackendAuthErrorCode+`"}`, recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+}
+
+func TestPresignHandler_BackendUnavailable(t *testing.T) {
+	setupTestEnvironment()
+
+	// Without a pinned region the client must ask the (unreachable) server
+	// for the bucket location before signing.
+	var err error
+	minioClient, err = minio.New("127.0.0.1:1", &minio.Options{
+		Creds:      credentials.NewStaticV4("minio", "minio123", ""),
+		MaxRetries: 1,
+	})
+	require.NoError(t, err)
+	signer = newSigner()
+	defer setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	req, err := http.NewRequest("GET", "/presign?filename=test.txt&type=text/plain", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, RetryAfterSeconds, recorder.Header().Get("Retry-After"))
+	assert.Contains(t, recorder.Body.String(), "Storage backend unavailable")
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/pkg/presign"
+	"github.com/mirago/miraio/utils"
+)
+
+// DefaultBackendName names the backend configured by the MIRAIO_MINIO_*
+// variables.
+const DefaultBackendName = "default"
+
+// backendRefSeparator separates the backend name from the object name in a
+// reference such as "eu:photos/cat.jpg".
+const backendRefSeparator = ":"
+
+// backend is one MinIO deployment and bucket that uploads can be sent to.
+type backend struct {
+	name   string
+	client *minio.Client
+	signer *presign.Signer
+	weight int
+	// postClient signs POST policies when it differs from client, i.e.
+	// for a public endpoint.
+	postClient *minio.Client
+}
+
+// policyClient returns the client that signs POST policies for b.
+func (b *backend) policyClient() *minio.Client {
+	if b.postClient != nil {
+		return b.postClient
+	}
+	return b.client
+}
+
+// ref returns the reference clients pass back as filename to reach key on
+// this backend.
+func (b *backend) ref(key string) string {
+	return b.name + backendRefSeparator + strings.TrimPrefix(key, keyPrefix)
+}
+
+// backendRegistry holds the backends configured with MIRAIO_BACKENDS in
+// addition to the default one, and spreads uploads over all of them by
+// weight. The default backend always reflects the current minioClient and
+// signer globals.
+type backendRegistry struct {
+	mu            sync.Mutex
+	extra         []*backend
+	defaultWeight int
+	// current holds the smooth weighted round-robin state, default first.
+	current []int
+}
+
+var backends = &backendRegistry{defaultWeight: 1}
+
+// multi reports whether more than the default backend is configured. Only
+// then are backend references used.
+func (r *backendRegistry) multi() bool {
+	return len(r.extra) > 0
+}
+
+// primary returns the default backend.
+func (r *backendRegistry) primary() *backend {
+	return &backend{name: DefaultBackendName, client: minioClient, signer: signer, weight: r.defaultWeight, postClient: postPolicyClient}
+}
+
+// all returns the default backend followed by the configured ones.
+func (r *backendRegistry) all() []*backend {
+	return append([]*backend{r.primary()}, r.extra...)
+}
+
+// pick selects the backend for a new upload using smooth weighted
+// round-robin, which interleaves backends instead of sending bursts to one.
+// Backends whose bucket bucketWatch found missing are skipped while any
+// other is left.
+func (r *backendRegistry) pick() *backend {
+	if !r.multi() {
+		return r.primary()
+	}
+	all := r.all()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.current) != len(all) {
+		r.current = make([]int, len(all))
+	}
+	best, total := -1, 0
+	for i, b := range all {
+		if !bucketWatch.available(b) {
+			continue
+		}
+		r.current[i] += b.weight
+		total += b.weight
+		if best < 0 || r.current[i] > r.current[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return all[0]
+	}
+	r.current[best] -= total
+	return all[best]
+}
+
+// resolve splits a backend reference into its backend and object name.
+// Names without a known backend prefix address the default backend.
+func (r *backendRegistry) resolve(filename string) (*backend, string) {
+	all := r.all()
+	if !r.multi() {
+		return all[0], filename
+	}
+	if name, rest, ok := strings.Cut(filename, backendRefSeparator); ok {
+		for _, b := range all {
+			if b.name == name {
+				return b, rest
+			}
+		}
+	}
+	return all[0], filename
+}
+
+// byBucket returns the backend serving bucket, or nil when none does.
+func (r *backendRegistry) byBucket(bucket string) *backend {
+	for _, b := range r.all() {
+		if b.signer.Bucket == bucket {
+			return b
+		}
+	}
+	return nil
+}
+
+// validBackendName accepts lower-case letters, digits and hyphens, which
+// map directly onto environment variable names.
+func validBackendName(name string) bool {
+	if name == "" || name == DefaultBackendName {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseWeight reads a backend weight; empty means 1 and 0 stops new uploads
+// while keeping existing objects reachable.
+func parseWeight(v string) (int, error) {
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("weight must be a non-negative integer, got %q", v)
+	}
+	return n, nil
+}
+
+// loadBackends configures the backends listed in MIRAIO_BACKENDS. Each name
+// is read from MIRAIO_BACKEND_<NAME>_ENDPOINT, _ACCESS_KEY, _SECRET_KEY,
+// _SESSION_TOKEN, _USE_SSL, _BUCKET, _PUBLIC_URL, _PUBLIC_ENDPOINT, _REGION
+// and _WEIGHT, with
+// hyphens in the name written as underscores. The credentials may be given
+// as _ACCESS_KEY_FILE and so on.
+func loadBackends(names string) error {
+	var err error
+	if backends.defaultWeight, err = parseWeight(os.Getenv("MIRAIO_MINIO_WEIGHT")); err != nil {
+		return fmt.Errorf("MIRAIO_MINIO_WEIGHT: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	total := backends.defaultWeight
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !validBackendName(name) || seen[name] {
+			return fmt.Errorf("invalid or duplicate backend name %q", name)
+		}
+		seen[name] = true
+
+		b, err := newBackend(name)
+		if err != nil {
+			return fmt.Errorf("backend %s: %w", name, err)
+		}
+		total += b.weight
+		backends.extra = append(backends.extra, b)
+		utils.LogInfo("Backend %s: bucket %s, weight %d", name, b.signer.Bucket, b.weight)
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one backend needs a positive weight")
+	}
+	return nil
+}
+
+// newBackend builds a backend from its MIRAIO_BACKEND_<NAME>_* variables.
+func newBackend(name string) (*backend, error) {
+	prefix := "MIRAIO_BACKEND_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	env := func(key string) string { return os.Getenv(prefix + key) }
+
+	endpoint, bucket := env("ENDPOINT"), env("BUCKET")
+	if endpoint == "" || bucket == "" {
+		return nil, fmt.Errorf("%sENDPOINT and %sBUCKET are required", prefix, prefix)
+	}
+	weight, err := parseWeight(env("WEIGHT"))
+	if err != nil {
+		return nil, err
+	}
+	region := env("REGION")
+	if region == "" {
+		region = minioRegion
+	}
+
+	accessKey, secretKey, sessionToken, err := credentialEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := staticCredentials(accessKey, secretKey, sessionToken)
+	useSSL := env("USE_SSL") == "true"
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    useSSL,
+		Region:    region,
+		Transport: minioTransport,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// As for the default backend, URLs for a separate public endpoint are
+	// signed by a client for that host.
+	presignClient := client
+	var postClient *minio.Client
+	if publicEndpoint := env("PUBLIC_ENDPOINT"); publicEndpoint != "" {
+		if region == "" {
+			region = detectRegion(client, bucket)
+		}
+		if presignClient, err = newPublicPresignClient(publicEndpoint, creds, useSSL, region); err != nil {
+			return nil, fmt.Errorf("%sPUBLIC_ENDPOINT: %w", prefix, err)
+		}
+		postClient = presignClient
+	}
+
+	publicURL, _ := resolvePublicURLScheme(strings.TrimRight(env("PUBLIC_URL"), "/"), useSSL)
+	s := presign.New(withTracing(withPresignCache(withRetries(presignClient))), bucket, publicURL)
+	s.URLStyle = urlStyle
+	return &backend{name: name, client: client, signer: s, weight: weight, postClient: postClient}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/pkg/presign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withBackends installs extra backends for the duration of a test.
+func withBackends(t *testing.T, defaultWeight int, extra ...*backend) {
+	t.Helper()
+	saved := backends
+	backends = &backendRegistry{defaultWeight: defaultWeight, extra: extra}
+	t.Cleanup(func() { backends = saved })
+}
+
+func testBackend(name string, weight int) *backend {
+	s := presign.New(withRetries(minioClient), name+"-bucket", "http://"+name+".example.com")
+	return &backend{name: name, client: minioClient, signer: s, weight: weight}
+}
+
+func TestBackendRegistryPick(t *testing.T) {
+	setupTestEnvironment()
+	withBackends(t, 1, testBackend("eu", 2))
+
+	counts := make(map[string]int)
+	var sequence []string
+	for i := 0; i < 6; i++ {
+		name := backends.pick().name
+		counts[name]++
+		sequence = append(sequence, name)
+	}
+	assert.Equal(t, map[string]int{DefaultBackendName: 2, "eu": 4}, counts)
+	assert.Equal(t, []string{"eu", DefaultBackendName, "eu", "eu", DefaultBackendName, "eu"}, sequence, "smooth round-robin interleaves backends")
+
+	withBackends(t, 0, testBackend("eu", 1))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "eu", backends.pick().name, "weight 0 receives no uploads")
+	}
+}
+
+func TestBackendRegistryResolve(t *testing.T) {
+	setupTestEnvironment()
+
+	b, name := backends.resolve("eu:photo.jpg")
+	assert.Equal(t, DefaultBackendName, b.name, "references are ignored with a single backend")
+	assert.Equal(t, "eu:photo.jpg", name)
+
+	withBackends(t, 1, testBackend("eu", 1))
+	b, name = backends.resolve("eu:photos/cat.jpg")
+	assert.Equal(t, "eu", b.name)
+	assert.Equal(t, "photos/cat.jpg", name)
+
+	b, name = backends.resolve("default:cat.jpg")
+	assert.Equal(t, DefaultBackendName, b.name)
+	assert.Equal(t, "cat.jpg", name)
+
+	b, name = backends.resolve("notes:monday.txt")
+	assert.Equal(t, DefaultBackendName, b.name)
+	assert.Equal(t, "notes:monday.txt", name)
+}
+
+func TestLoadBackends(t *testing.T) {
+	setupTestEnvironment()
+	withBackends(t, 1)
+
+	t.Setenv("MIRAIO_BACKEND_EU_WEST_ENDPOINT", "eu.example.com:9000")
+	t.Setenv("MIRAIO_BACKEND_EU_WEST_BUCKET", "uploads-eu")
+	t.Setenv("MIRAIO_BACKEND_EU_WEST_PUBLIC_URL", "https://cdn-eu.example.com/")
+	t.Setenv("MIRAIO_BACKEND_EU_WEST_USE_SSL", "true")
+	t.Setenv("MIRAIO_BACKEND_EU_WEST_WEIGHT", "3")
+	require.NoError(t, loadBackends("eu-west"))
+
+	require.Len(t, backends.extra, 1)
+	b := backends.extra[0]
+	assert.Equal(t, "eu-west", b.name)
+	assert.Equal(t, 3, b.weight)
+	assert.Equal(t, "https://cdn-eu.example.com/uploads-eu/a.jpg", b.signer.ObjectURL("a.jpg"))
+
+	t.Run("Public endpoint", func(t *testing.T) {
+		withBackends(t, 1)
+		t.Setenv("MIRAIO_BACKEND_EU_WEST_PUBLIC_ENDPOINT", "https://files-eu.example.com")
+		t.Setenv("MIRAIO_BACKEND_EU_WEST_REGION", "eu-west-1")
+		t.Setenv("MIRAIO_BACKEND_EU_WEST_ACCESS_KEY", "minio")
+		t.Setenv("MIRAIO_BACKEND_EU_WEST_SECRET_KEY", "minio123")
+		require.NoError(t, loadBackends("eu-west"))
+
+		signed, _, err := backends.extra[0].signer.GetURL(context.Background(), "a.jpg", time.Minute, nil)
+		require.NoError(t, err)
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		assert.Equal(t, "files-eu.example.com", u.Host)
+		assert.Equal(t, "/uploads-eu/a.jpg", u.Path)
+		assert.Contains(t, u.Query().Get("X-Amz-Credential"), "/eu-west-1/s3/")
+		// Server-side calls still use the internal endpoint.
+		assert.Equal(t, "eu.example.com:9000", backends.extra[0].client.EndpointURL().Host)
+	})
+
+	for _, names := range []string{"default", "EU", "eu-west,eu-west", "missing"} {
+		withBackends(t, 1)
+		assert.Error(t, loadBackends(names), names)
+	}
+}
+
+func TestPresignHandler_MultipleBackends(t *testing.T) {
+	setupTestEnvironment()
+	withBackends(t, 0, testBackend("eu", 1))
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/presign-get", presignGetHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=cat.jpg&type=image/jpeg", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var upload map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+	assert.Equal(t, "eu", upload["backend"])
+	assert.Equal(t, "eu:cat.jpg", upload["ref"])
+	assert.Equal(t, "http://eu.example.com/eu-bucket/cat.jpg", upload["publicUrl"])
+	assert.Contains(t, upload["url"], "/eu-bucket/cat.jpg")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-get?filename="+url.QueryEscape("eu:cat.jpg"), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var download PresignResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &download))
+	assert.Equal(t, "http://eu.example.com/eu-bucket/cat.jpg", download.PublicURL)
+	assert.Contains(t, download.URL, "/eu-bucket/cat.jpg")
+}
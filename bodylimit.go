@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when
+// MIRAIO_MAX_BODY_BYTES is unset. Request bodies are small JSON documents.
+const DefaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes is the largest request body accepted (MIRAIO_MAX_BODY_BYTES).
+var maxBodyBytes int64 = DefaultMaxBodyBytes
+
+// parseMaxBodyBytes reads MIRAIO_MAX_BODY_BYTES, defaulting to
+// DefaultMaxBodyBytes.
+func parseMaxBodyBytes(v string) (int64, error) {
+	if v == "" {
+		return DefaultMaxBodyBytes, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("MIRAIO_MAX_BODY_BYTES must be a positive number of bytes")
+	}
+	return n, nil
+}
+
+// limitBody caps request bodies at maxBodyBytes. Requests that declare a
+// larger Content-Length are rejected up front; bodies sent without one fail
+// while being read, which handlers report through respondBindError.
+func limitBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBodyBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		c.Next()
+	}
+}
+
+// respondBindError answers a failed body binding: 413 when the body limit
+// was hit, otherwise 400 with message.
+func respondBindError(c *gin.Context, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": message})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitBody(t *testing.T) {
+	setupTestEnvironment()
+	maxBodyBytes = 16
+	defer func() { maxBodyBytes = DefaultMaxBodyBytes }()
+
+	router := gin.New()
+	router.PUT("/objects/:filename/tags", limitBody(), putObjectTagsHandler)
+
+	t.Run("Declared length over limit", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/objects/a.txt/tags", strings.NewReader(`{"project":"a-long-value"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Streamed body over limit", func(t *testing.T) {
+		// Hiding the reader's length leaves ContentLength unknown, so the limit
+		// is only hit while binding.
+		body := io.MultiReader(strings.NewReader(`{"project":"a-long-value"}`))
+		req := httptest.NewRequest("PUT", "/objects/a.txt/tags", body)
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("Small invalid body is still a 400", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/objects/a.txt/tags", strings.NewReader(`[1]`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestParseMaxBodyBytes(t *testing.T) {
+	n, err := parseMaxBodyBytes("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(DefaultMaxBodyBytes), n)
+
+	n, err = parseMaxBodyBytes("4096")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), n)
+
+	for _, v := range []string{"0", "-1", "1k"} {
+		_, err := parseMaxBodyBytes(v)
+		assert.Error(t, err, v)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// BucketMissingCode marks 503 responses refused because a configured bucket
+// no longer exists.
+const BucketMissingCode = "bucket_missing"
+
+// BucketCheckTimeout bounds one round of bucket checks.
+const BucketCheckTimeout = 5 * time.Second
+
+// bucketWatch refuses MinIO-backed requests while a configured bucket is
+// missing (MIRAIO_VERIFY_BUCKET). Nil disables the check.
+var bucketWatch *bucketGuard
+
+// bucketGuard remembers which backends the last check found without their
+// bucket. Checks run in the background, so requests only read the result.
+type bucketGuard struct {
+	mu      sync.RWMutex
+	missing []*backend
+	// check returns the backends whose bucket does not exist.
+	check func(ctx context.Context) ([]*backend, error)
+}
+
+func newBucketGuard(check func(ctx context.Context) ([]*backend, error)) *bucketGuard {
+	return &bucketGuard{check: check}
+}
+
+// parseVerifyBucket reads MIRAIO_VERIFY_BUCKET, the interval between bucket
+// checks. Unset or zero disables them.
+func parseVerifyBucket(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, errors.New("MIRAIO_VERIFY_BUCKET must be a non-negative duration such as 30s")
+	}
+	return d, nil
+}
+
+// missingBuckets checks the bucket of every backend.
+func missingBuckets(ctx context.Context) ([]*backend, error) {
+	var missing []*backend
+	for _, b := range backends.all() {
+		exists, err := b.client.BucketExists(ctx, b.signer.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, b)
+		}
+	}
+	return missing, nil
+}
+
+// bucketNames lists the buckets of bs for logs and errors.
+func bucketNames(bs []*backend) string {
+	names := make([]string, len(bs))
+	for i, b := range bs {
+		names[i] = b.signer.Bucket
+	}
+	return strings.Join(names, ", ")
+}
+
+// available reports whether the last check found the bucket of b. It is
+// always true when g is nil.
+func (g *bucketGuard) available(b *backend) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return !slices.ContainsFunc(g.missing, func(m *backend) bool { return m.name == b.name })
+}
+
+// checkOnce runs one check. When MinIO cannot be reached the previous
+// result is kept; requests then fail on their own with a 503.
+func (g *bucketGuard) checkOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, BucketCheckTimeout)
+	defer cancel()
+
+	missing, err := g.check(ctx)
+	if err != nil {
+		utils.LogWarning("Bucket check failed: %v", err)
+		return
+	}
+
+	g.mu.Lock()
+	before := g.missing
+	g.missing = missing
+	g.mu.Unlock()
+
+	switch {
+	case len(missing) > 0 && bucketNames(missing) != bucketNames(before):
+		utils.LogError("Bucket missing: %s; refusing requests for it until it exists again", bucketNames(missing))
+	case len(missing) == 0 && len(before) > 0:
+		utils.LogInfo("Bucket available again: %s", bucketNames(before))
+	}
+}
+
+// start checks now and then every interval until stop is called.
+func (g *bucketGuard) start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		g.checkOnce(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				g.checkOnce(context.Background())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// middleware answers 503 while the bucket of the backend a request
+// addresses is missing, instead of handing out URLs that cannot be used.
+// New uploads are refused only when every backend's bucket is missing,
+// since pick skips the others.
+func (g *bucketGuard) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		g.mu.RLock()
+		missing := g.missing
+		g.mu.RUnlock()
+
+		if len(missing) > 0 {
+			b := targetBackend(c)
+			if b != nil && !g.available(b) {
+				abortBucketMissing(c, []*backend{b})
+				return
+			}
+			if b == nil && len(missing) == len(backends.all()) {
+				abortBucketMissing(c, missing)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func abortBucketMissing(c *gin.Context, missing []*backend) {
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error": "Bucket missing: " + bucketNames(missing),
+		"code":  BucketMissingCode,
+	})
+}
+
+// uploadRoutes place new objects on a backend chosen by pick.
+var uploadRoutes = map[string]bool{
+	"/presign":          true,
+	"/upload-bundle":    true,
+	"/presign-post":     true,
+	"/upload/*filename": true,
+}
+
+// targetBackend returns the backend a request addresses: the bucket of a
+// bucket-scoped token, or the backend named in a filename reference, or
+// else the default backend. It returns nil for new uploads without either,
+// which pick places.
+func targetBackend(c *gin.Context) *backend {
+	if s := requestScope(c); s != nil && s.Bucket != "" {
+		if b := backends.byBucket(s.Bucket); b != nil {
+			return b
+		}
+	}
+	filename := c.Query("filename")
+	if filename == "" {
+		filename = strings.TrimPrefix(c.Param("filename"), "/")
+	}
+	if b, name := backends.resolve(filename); name != filename {
+		return b
+	}
+	if uploadRoutes[c.FullPath()] && strings.EqualFold(c.DefaultQuery("method", http.MethodPut), http.MethodPut) {
+		return nil
+	}
+	return backends.primary()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVerifyBucket(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": 0, "0": 0, "30s": 30 * time.Second} {
+		got, err := parseVerifyBucket(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, bad := range []string{"30", "-1s", "often"} {
+		_, err := parseVerifyBucket(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBucketGuard(t *testing.T) {
+	setupTestEnvironment()
+	var missing []*backend
+	var checkErr error
+	g := newBucketGuard(func(context.Context) ([]*backend, error) { return missing, checkErr })
+
+	router := gin.New()
+	router.GET("/presign", g.middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/presign", nil))
+		return w
+	}
+
+	g.checkOnce(context.Background())
+	assert.Equal(t, http.StatusOK, get().Code)
+
+	missing = []*backend{backends.primary()}
+	g.checkOnce(context.Background())
+	w := get()
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, BucketMissingCode, body["code"])
+	assert.Contains(t, body["error"], "test-bucket")
+
+	// An unreachable MinIO says nothing about the bucket.
+	missing, checkErr = nil, errors.New("connection refused")
+	g.checkOnce(context.Background())
+	assert.Equal(t, http.StatusServiceUnavailable, get().Code)
+
+	checkErr = nil
+	g.checkOnce(context.Background())
+	assert.Equal(t, http.StatusOK, get().Code)
+}
+
+func TestBucketGuard_PerBackend(t *testing.T) {
+	setupTestEnvironment()
+	eu := testBackend("eu", 1)
+	withBackends(t, 1, eu)
+	g := newBucketGuard(func(context.Context) ([]*backend, error) { return []*backend{eu}, nil })
+	g.checkOnce(context.Background())
+	saved := bucketWatch
+	bucketWatch = g
+	t.Cleanup(func() { bucketWatch = saved })
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/presign", g.middleware(), ok)
+	router.GET("/presign-get", g.middleware(), ok)
+	for path, want := range map[string]int{
+		"/presign?filename=a.txt":               http.StatusOK,
+		"/presign?filename=eu:a.txt&method=GET": http.StatusServiceUnavailable,
+		"/presign-get?filename=a.txt":           http.StatusOK,
+		"/presign-get?filename=eu:a.txt":        http.StatusServiceUnavailable,
+		"/presign-get?filename=default:a.txt":   http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, DefaultBackendName, backends.pick().name, "uploads skip the backend without its bucket")
+	}
+}
+
+func TestMissingBuckets(t *testing.T) {
+	setupTestEnvironment()
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, "/test-bucket/", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	missing, err := missingBuckets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "test-bucket", missing[0].signer.Bucket)
+}
+
+func TestBucketGuardStartStop(t *testing.T) {
+	checked := make(chan struct{}, 10)
+	g := newBucketGuard(func(context.Context) ([]*backend, error) {
+		checked <- struct{}{}
+		return nil, nil
+	})
+
+	stop := g.start(10 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-checked:
+		case <-time.After(time.Second):
+			t.Fatal("bucket check did not run")
+		}
+	}
+	stop()
+}
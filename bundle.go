@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadBundle is everything a client needs to perform a presigned upload
+// without knowing which headers the signature covers.
+type uploadBundle struct {
+	Method    string            `json:"method"`
+	Key       string            `json:"key"`
+	Backend   string            `json:"backend,omitempty"`
+	Ref       string            `json:"ref,omitempty"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	PublicURL string            `json:"publicUrl" snake:"public_url"`
+	ExpiresAt string            `json:"expiresAt" snake:"expires_at"`
+}
+
+// uploadBundleHandler accepts the same parameters as /presign plus an
+// optional expiry in seconds, and returns the upload as a single bundle.
+func uploadBundleHandler(c *gin.Context) {
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:       c.Query("filename"),
+		ContentType:    c.Query("type"),
+		Params:         c.Request.URL.Query(),
+		SSECustomerKey: c.GetHeader(SSECustomerKeyHeader),
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+	expiry := uploadExpiryFor(key, reqParams.Get("Content-Type"))
+	if v := c.Query("expiry"); v != "" {
+		if expiry, err = parseExpiry(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkPrefixExpiry(key, expiry); err != nil {
+			respondUploadError(c, err)
+			return
+		}
+	}
+
+	b := pickBackend(c)
+	if !checkScope(c, b, key) {
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now()
+	if key, err = resolveCollision(key, existsOn(ctx, b)); err != nil {
+		respondCollisionError(c, ctx, err)
+		return
+	}
+	signed, public, err := b.signer.PutURLWithHeaders(ctx, key, http.Header(reqParams), expiry)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
+		return
+	}
+	webhook.notifyPresign(http.MethodPut, c.Query("filename"), key, reqParams.Get("Content-Type"), c.ClientIP())
+
+	// Every header in reqParams is part of the signature, so the client must
+	// send all of them verbatim.
+	headers := make(map[string]string, len(reqParams))
+	for name := range reqParams {
+		headers[name] = reqParams.Get(name)
+	}
+
+	bundle := uploadBundle{
+		Method:    http.MethodPut,
+		Key:       key,
+		URL:       signed,
+		Headers:   headers,
+		PublicURL: requestPublicURL(c, b.signer, key, public),
+		ExpiresAt: formatExpiresAt(signed, now, expiry),
+	}
+	if backends.multi() {
+		bundle.Backend, bundle.Ref = b.name, b.ref(key)
+	}
+	respondJSON(c, http.StatusOK, bundle)
+}
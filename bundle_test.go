@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadBundleHandler(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/upload-bundle", uploadBundleHandler)
+
+	t.Run("Returns bundle", func(t *testing.T) {
+		before := time.Now().Truncate(time.Second)
+		req := httptest.NewRequest("GET", "/upload-bundle?filename=photo.jpg&type=image/jpeg&meta.owner=alice&expiry=300", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var bundle uploadBundle
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bundle))
+		assert.Equal(t, http.MethodPut, bundle.Method)
+		assert.Contains(t, bundle.URL, "photo.jpg")
+		assert.Equal(t, "http://localhost:9000/test-bucket/photo.jpg", bundle.PublicURL)
+		assert.Equal(t, map[string]string{
+			"Content-Type":     "image/jpeg",
+			"x-amz-meta-owner": "alice",
+		}, bundle.Headers)
+
+		expiresAt, err := time.Parse(time.RFC3339, bundle.ExpiresAt)
+		require.NoError(t, err)
+		assert.WithinDuration(t, before.Add(300*time.Second), expiresAt, 2*time.Second)
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		for _, query := range []string{"", "?filename=a.txt&type=text/plain&expiry=-1"} {
+			req := httptest.NewRequest("GET", "/upload-bundle"+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+}
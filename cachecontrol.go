@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// cacheControlParam is the upload parameter carrying the Cache-Control value
+// stored with the object.
+const cacheControlParam = "cacheControl"
+
+// MaxCacheControlLength bounds the Cache-Control value; real directives are
+// a few dozen bytes.
+const MaxCacheControlLength = 1024
+
+// defaultCacheControl is signed into uploads that do not pass cacheControl
+// (MIRAIO_DEFAULT_CACHE_CONTROL). Empty leaves the header out.
+var defaultCacheControl string
+
+// parseCacheControl validates a Cache-Control value. It must fit in a
+// single header line.
+func parseCacheControl(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" || len(v) > MaxCacheControlLength || strings.ContainsFunc(v, isControlRune) {
+		return "", errors.New("Invalid cacheControl: must be a non-empty header value without control characters")
+	}
+	return v, nil
+}
+
+func isControlRune(r rune) bool {
+	return r < 0x20 && r != '\t' || r == 0x7f
+}
+
+// addCacheControl signs the cacheControl parameter, or the configured
+// default, into reqParams as Cache-Control, so MinIO stores it with the
+// object and serves it on every GET.
+func addCacheControl(query url.Values, reqParams url.Values) error {
+	if !query.Has(cacheControlParam) {
+		if defaultCacheControl != "" {
+			reqParams.Set("Cache-Control", defaultCacheControl)
+		}
+		return nil
+	}
+	v, err := parseCacheControl(query.Get(cacheControlParam))
+	if err != nil {
+		return err
+	}
+	reqParams.Set("Cache-Control", v)
+	return nil
+}
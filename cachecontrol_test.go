@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddCacheControl(t *testing.T) {
+	reqParams := make(url.Values)
+	require.NoError(t, addCacheControl(url.Values{"cacheControl": {" max-age=3600, public "}}, reqParams))
+	assert.Equal(t, "max-age=3600, public", reqParams.Get("Cache-Control"))
+
+	reqParams = make(url.Values)
+	require.NoError(t, addCacheControl(url.Values{}, reqParams))
+	assert.Empty(t, reqParams)
+
+	defaultCacheControl = "no-cache"
+	defer func() { defaultCacheControl = "" }()
+
+	reqParams = make(url.Values)
+	require.NoError(t, addCacheControl(url.Values{}, reqParams))
+	assert.Equal(t, "no-cache", reqParams.Get("Cache-Control"))
+
+	reqParams = make(url.Values)
+	require.NoError(t, addCacheControl(url.Values{"cacheControl": {"max-age=60"}}, reqParams))
+	assert.Equal(t, "max-age=60", reqParams.Get("Cache-Control"), "parameter overrides the default")
+
+	for _, bad := range []string{"", "  ", "max-age=60\r\nX-Evil: 1", "a\x00b", strings.Repeat("a", MaxCacheControlLength+1)} {
+		assert.Error(t, addCacheControl(url.Values{"cacheControl": {bad}}, make(url.Values)), "%q", bad)
+	}
+}
+
+func TestPresignPutHandler_CacheControl(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&cacheControl="+url.QueryEscape("max-age=3600"), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "max-age=3600", resp.CacheControl)
+	signed, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	assert.Contains(t, signed.Query().Get("X-Amz-SignedHeaders"), "cache-control")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&cacheControl=a%0Ab", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIntegrationPresignCacheControl(t *testing.T) {
+	client := integrationClient(t)
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	filename := "cache-control.txt"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename="+filename+"&type=text/plain&cacheControl="+url.QueryEscape("public, max-age=86400"), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	upload, err := http.NewRequest(http.MethodPut, resp.URL, strings.NewReader("hello"))
+	require.NoError(t, err)
+	upload.Header.Set("Content-Type", resp.ContentType)
+	upload.Header.Set("Cache-Control", resp.CacheControl)
+	uploadResp, err := http.DefaultClient.Do(upload)
+	require.NoError(t, err)
+	uploadResp.Body.Close()
+	require.Equal(t, http.StatusOK, uploadResp.StatusCode)
+	defer client.RemoveObject(context.Background(), bucketName, resp.Key, minio.RemoveObjectOptions{})
+
+	info, err := client.StatObject(context.Background(), bucketName, resp.Key, minio.StatObjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "public, max-age=86400", info.Metadata.Get("Cache-Control"))
+}
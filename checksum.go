@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/url"
+)
+
+// contentMD5Param is the upload parameter carrying the base64 MD5 digest the
+// client commits to.
+const contentMD5Param = "contentMd5"
+
+// addContentMD5 signs the contentMd5 parameter into reqParams as Content-MD5,
+// so MinIO rejects an upload whose body does not match the digest.
+func addContentMD5(query url.Values, reqParams url.Values) error {
+	v := query.Get(contentMD5Param)
+	if v == "" {
+		return nil
+	}
+	digest, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(digest) != md5.Size {
+		return errors.New("Invalid contentMd5: must be the base64-encoded 16-byte MD5 digest of the body")
+	}
+	reqParams.Set("Content-MD5", v)
+	return nil
+}
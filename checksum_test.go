@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddContentMD5(t *testing.T) {
+	sum := md5.Sum([]byte("hello"))
+	digest := base64.StdEncoding.EncodeToString(sum[:])
+
+	reqParams := make(url.Values)
+	require.NoError(t, addContentMD5(url.Values{"contentMd5": {digest}}, reqParams))
+	assert.Equal(t, digest, reqParams.Get("Content-MD5"))
+
+	reqParams = make(url.Values)
+	require.NoError(t, addContentMD5(url.Values{}, reqParams))
+	assert.Empty(t, reqParams)
+
+	for _, bad := range []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte("short")),
+		base64.RawStdEncoding.EncodeToString(sum[:]),
+	} {
+		assert.Error(t, addContentMD5(url.Values{"contentMd5": {bad}}, make(url.Values)), bad)
+	}
+}
+
+func TestPresignPutHandler_ContentMD5(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	sum := md5.Sum([]byte("hello"))
+	digest := base64.StdEncoding.EncodeToString(sum[:])
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&contentMd5="+url.QueryEscape(digest), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, digest, resp.ContentMD5)
+	signed, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	assert.Contains(t, signed.Query().Get("X-Amz-SignedHeaders"), "content-md5")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&contentMd5=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
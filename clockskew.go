@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// DefaultMaxClockSkew is the clock difference to MinIO tolerated before
+// warning, when MIRAIO_MAX_CLOCK_SKEW is unset.
+const DefaultMaxClockSkew = 30 * time.Second
+
+// maxClockSkew is the configured tolerance (MIRAIO_MAX_CLOCK_SKEW). Zero
+// disables the check.
+var maxClockSkew = DefaultMaxClockSkew
+
+// measureClockSkew compares the Date header of an anonymous HEAD request to
+// baseURL with the local clock at the midpoint of the round trip. Any
+// response carries the header, so no credentials are needed. Positive
+// results mean MinIO's clock is ahead.
+func measureClockSkew(ctx context.Context, client *http.Client, baseURL string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	remote, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("response has no valid Date header")
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	return remote.Sub(local), nil
+}
+
+// checkClockSkew warns when the local clock is more than maxClockSkew away
+// from MinIO's. Presigned URLs are dated by this server and checked against
+// MinIO's clock, so drift shows up as "Request has expired" or "request
+// time too skewed" 403s. Failures to measure are only logged.
+func checkClockSkew(endpoint string, useSSL bool) {
+	scheme := "http"
+	if useSSL {
+		scheme = "https"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), StartupCheckTimeout)
+	defer cancel()
+
+	skew, err := measureClockSkew(ctx, &http.Client{Timeout: StartupCheckTimeout, Transport: minioTransport}, scheme+"://"+endpoint)
+	if err != nil {
+		utils.LogWarning("Could not compare clock with MinIO: %v", err)
+		return
+	}
+	// The Date header has one-second resolution.
+	if skew.Abs() > maxClockSkew+time.Second {
+		utils.LogWarning("Clock differs from MinIO by %s (tolerance %s); presigned URLs may be rejected as expired or not yet valid. Sync this host's clock (NTP).",
+			skew.Round(time.Second), maxClockSkew)
+		return
+	}
+	utils.LogDebug("Clock skew to MinIO: %s", skew.Round(time.Millisecond))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureClockSkew(t *testing.T) {
+	offset := 2 * time.Minute
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	skew, err := measureClockSkew(context.Background(), server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.InDelta(t, offset.Seconds(), skew.Seconds(), 1.5)
+}
+
+func TestMeasureClockSkew_NoDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+	}))
+	defer server.Close()
+
+	_, err := measureClockSkew(context.Background(), server.Client(), server.URL)
+	assert.Error(t, err)
+}
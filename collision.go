@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Strategies for uploads whose key is already taken
+// (MIRAIO_COLLISION_STRATEGY).
+const (
+	CollisionOverwrite = "overwrite"
+	CollisionReject    = "reject"
+	CollisionIncrement = "increment"
+)
+
+// CollisionMaxProbes bounds the StatObject calls the increment strategy
+// makes for one upload.
+const CollisionMaxProbes = 20
+
+// collisionStrategy is the configured strategy; overwrite needs no lookup.
+var collisionStrategy = CollisionOverwrite
+
+// errKeyExists is returned when an upload may not take an existing key.
+var errKeyExists = errors.New("An object with this name already exists")
+
+// parseCollisionStrategy reads MIRAIO_COLLISION_STRATEGY.
+func parseCollisionStrategy(v string) (string, error) {
+	switch strings.ToLower(v) {
+	case "", CollisionOverwrite:
+		return CollisionOverwrite, nil
+	case CollisionReject, CollisionIncrement:
+		return strings.ToLower(v), nil
+	default:
+		return "", fmt.Errorf("unsupported MIRAIO_COLLISION_STRATEGY %q: use overwrite, reject or increment", v)
+	}
+}
+
+// resolveCollision returns the key an upload should use under the
+// configured strategy, asking exists whether a candidate is taken. Increment
+// candidates must stay within MIRAIO_MAX_KEY_LENGTH. The check
+// happens when the URL is signed, not when the upload runs, so two clients
+// presigning the same name at once can still both get it.
+func resolveCollision(key string, exists func(key string) (bool, error)) (string, error) {
+	switch collisionStrategy {
+	case CollisionReject:
+		taken, err := exists(key)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", errKeyExists
+		}
+		return key, nil
+	case CollisionIncrement:
+		maxKeyLength := currentConfig().maxKeyLength
+		for n := 0; n < CollisionMaxProbes; n++ {
+			candidate := incrementKey(key, n)
+			if len(candidate) > maxKeyLength {
+				// Later suffixes are never shorter, so none will fit.
+				return "", fmt.Errorf("%w; no free name fits the %d byte key limit", errKeyExists, maxKeyLength)
+			}
+			taken, err := exists(candidate)
+			if err != nil {
+				return "", err
+			}
+			if !taken {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("%w; no free name found after %d attempts", errKeyExists, CollisionMaxProbes)
+	default:
+		return key, nil
+	}
+}
+
+// incrementKey inserts -n before the extension of key's last segment:
+// photos/cat.jpg becomes photos/cat-2.jpg. n == 0 returns key unchanged.
+func incrementKey(key string, n int) string {
+	if n == 0 {
+		return key
+	}
+	dir, base := path.Split(key)
+	ext := path.Ext(base)
+	if ext == base {
+		// Dotfiles such as ".env" have no extension to preserve.
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(base, ext) + "-" + strconv.Itoa(n) + ext
+}
+
+// existsOn returns an existence check against b for resolveCollision.
+func existsOn(ctx context.Context, b *backend) func(string) (bool, error) {
+	return func(key string) (bool, error) {
+		err := withRetry(ctx, "stat "+key, func() error {
+			_, err := b.storage().Stat(ctx, key, "")
+			return err
+		})
+		if err == nil {
+			return true, nil
+		}
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+}
+
+// respondCollisionError answers a failed resolveCollision: 409 when the
+// name is taken, otherwise a backend error.
+func respondCollisionError(c *gin.Context, ctx context.Context, err error) {
+	if errors.Is(err, errKeyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	respondBackendError(c, ctx, err, "Could not check for an existing object")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementKey(t *testing.T) {
+	for _, tc := range []struct {
+		key  string
+		n    int
+		want string
+	}{
+		{"photo.jpg", 0, "photo.jpg"},
+		{"photo.jpg", 1, "photo-1.jpg"},
+		{"uploads/2025/photo.tar.gz", 2, "uploads/2025/photo.tar-2.gz"},
+		{"README", 3, "README-3"},
+		{"dir.d/.env", 1, "dir.d/.env-1"},
+	} {
+		assert.Equal(t, tc.want, incrementKey(tc.key, tc.n), tc.key)
+	}
+}
+
+func TestResolveCollision(t *testing.T) {
+	defer func() { collisionStrategy = CollisionOverwrite }()
+
+	taken := map[string]bool{"a.txt": true, "a-1.txt": true}
+	var probes int
+	exists := func(key string) (bool, error) {
+		probes++
+		return taken[key], nil
+	}
+
+	t.Run("Overwrite", func(t *testing.T) {
+		collisionStrategy, probes = CollisionOverwrite, 0
+		key, err := resolveCollision("a.txt", exists)
+		require.NoError(t, err)
+		assert.Equal(t, "a.txt", key)
+		assert.Zero(t, probes, "overwrite never checks")
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		collisionStrategy = CollisionReject
+		_, err := resolveCollision("a.txt", exists)
+		assert.ErrorIs(t, err, errKeyExists)
+
+		key, err := resolveCollision("b.txt", exists)
+		require.NoError(t, err)
+		assert.Equal(t, "b.txt", key)
+	})
+
+	t.Run("Increment", func(t *testing.T) {
+		collisionStrategy = CollisionIncrement
+		key, err := resolveCollision("a.txt", exists)
+		require.NoError(t, err)
+		assert.Equal(t, "a-2.txt", key)
+	})
+
+	t.Run("Increment gives up", func(t *testing.T) {
+		collisionStrategy, probes = CollisionIncrement, 0
+		_, err := resolveCollision("a.txt", func(string) (bool, error) {
+			probes++
+			return true, nil
+		})
+		assert.ErrorIs(t, err, errKeyExists)
+		assert.Equal(t, CollisionMaxProbes, probes)
+	})
+
+	t.Run("Increment within the key limit", func(t *testing.T) {
+		withConfig(t, func(cfg *reloadableConfig) { cfg.maxKeyLength = len("a-1.txt") - 1 })
+		collisionStrategy, probes = CollisionIncrement, 0
+		_, err := resolveCollision("a.txt", exists)
+		assert.ErrorIs(t, err, errKeyExists)
+		assert.Contains(t, err.Error(), "6 byte key limit")
+		assert.Equal(t, 1, probes, "a-1.txt is too long to probe")
+	})
+
+	t.Run("Backend error", func(t *testing.T) {
+		collisionStrategy = CollisionReject
+		boom := errors.New("boom")
+		_, err := resolveCollision("a.txt", func(string) (bool, error) { return false, boom })
+		assert.ErrorIs(t, err, boom)
+	})
+}
+
+func TestRespondCollisionError(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/presign", nil)
+	respondCollisionError(c, c.Request.Context(), errKeyExists)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestParseCollisionStrategy(t *testing.T) {
+	for _, v := range []string{"", "overwrite", "reject", "Increment"} {
+		_, err := parseCollisionStrategy(v)
+		assert.NoError(t, err, v)
+	}
+	_, err := parseCollisionStrategy("rename")
+	assert.Error(t, err)
+}
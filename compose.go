@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// Compose limits imposed by S3 multipart copy: every source except the last
+// becomes a part and must be at least ComposeMinPartSize bytes.
+const (
+	ComposeMinPartSize = 5 << 20
+	ComposeMaxSources  = 10000
+)
+
+// composeRequest is the body of POST /presign-compose. Listing the
+// destination as the first source appends the others to it.
+type composeRequest struct {
+	Sources     []string `json:"sources" binding:"required,min=1"`
+	Destination string   `json:"destination" binding:"required"`
+	Expiry      int      `json:"expiry" binding:"gte=0"`
+}
+
+// composeResponse describes the composed object and a download URL for it.
+type composeResponse struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	URL       string `json:"url"`
+	PublicURL string `json:"publicUrl" snake:"public_url"`
+	Expiry    int    `json:"expiry"`
+	ExpiresAt string `json:"expiresAt" snake:"expires_at"`
+}
+
+// composeHandler concatenates existing objects server-side with
+// ComposeObject, which plain S3 offers in place of appends, and presigns a
+// download of the result. Sources are stat'ed first so that parts below the
+// multipart minimum are reported by name instead of failing midway.
+func composeHandler(c *gin.Context) {
+	var req composeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if fields := fieldErrors(err); fields != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "fields": fields})
+			return
+		}
+		respondBindError(c, err, "Malformed JSON body")
+		return
+	}
+	if !checkBodyExpiry(c, req.Expiry) {
+		return
+	}
+	if len(req.Sources) > ComposeMaxSources {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d sources can be composed", ComposeMaxSources)})
+		return
+	}
+
+	dstKey, err := objectKey(req.Destination)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	auditKey(c, dstKey)
+	auditDetail(c, fmt.Sprintf("sources=%d", len(req.Sources)))
+	srcKeys := make([]string, len(req.Sources))
+	for i, source := range req.Sources {
+		if srcKeys[i], err = objectKey(source); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	expiry := DefaultExpiry
+	if req.Expiry > 0 {
+		expiry = time.Duration(req.Expiry) * time.Second
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	srcs := make([]minio.CopySrcOptions, len(srcKeys))
+	for i, key := range srcKeys {
+		var info minio.ObjectInfo
+		err := withRetry(ctx, "stat "+key, func() error {
+			var err error
+			info, err = minioClient.StatObject(ctx, bucketName, key, minio.StatObjectOptions{})
+			return err
+		})
+		if err != nil {
+			if isNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Source object not found: " + req.Sources[i]})
+				return
+			}
+			respondBackendError(c, ctx, err, "Could not compose objects")
+			return
+		}
+		if i < len(srcKeys)-1 && info.Size < ComposeMinPartSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf(
+				"Source %q is %d bytes; every source except the last must be at least %d bytes (5 MiB) to compose",
+				req.Sources[i], info.Size, ComposeMinPartSize)})
+			return
+		}
+		// Pin the stat'ed version so a concurrent overwrite fails the
+		// compose instead of being mixed in.
+		srcs[i] = minio.CopySrcOptions{Bucket: bucketName, Object: key, MatchETag: info.ETag}
+	}
+
+	info, err := minioClient.ComposeObject(ctx, minio.CopyDestOptions{Bucket: bucketName, Object: dstKey}, srcs...)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not compose objects")
+		return
+	}
+	utils.LogInfo("Composed %d objects into %s (%d bytes)", len(srcKeys), dstKey, info.Size)
+
+	now := time.Now()
+	signed, public, err := signer.GetURL(ctx, dstKey, expiry, nil)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
+		return
+	}
+	respondJSON(c, http.StatusOK, composeResponse{
+		Key:       strings.TrimPrefix(dstKey, keyPrefix),
+		Size:      info.Size,
+		URL:       signed,
+		PublicURL: requestPublicURL(c, signer, dstKey, public),
+		Expiry:    int(expiry.Seconds()),
+		ExpiresAt: formatExpiresAt(signed, now, expiry),
+	})
+}
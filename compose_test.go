@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func composeRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/presign-compose", composeHandler)
+	return router
+}
+
+func postCompose(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/presign-compose", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestComposeHandler_Validation(t *testing.T) {
+	setupTestEnvironment()
+	router := composeRouter()
+
+	for _, body := range []string{
+		`{"destination":"log.txt"}`,
+		`{"sources":[],"destination":"log.txt"}`,
+		`{"sources":["a.txt"]}`,
+		`{"sources":["../a.txt"],"destination":"log.txt"}`,
+		`{"sources":["a.txt"],"destination":"log.txt","expiry":-1}`,
+		`{"sources":["a.txt"],"destination":"log.txt","expiry":604801}`,
+		`{"sources":`,
+	} {
+		w := postCompose(router, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestIntegrationCompose(t *testing.T) {
+	client := integrationClient(t)
+	router := composeRouter()
+	ctx := context.Background()
+
+	put := func(key string, size int) {
+		_, err := client.PutObject(ctx, bucketName, key, bytes.NewReader(bytes.Repeat([]byte("x"), size)), int64(size), minio.PutObjectOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() { client.RemoveObject(ctx, bucketName, key, minio.RemoveObjectOptions{}) })
+	}
+	put("compose-big.log", ComposeMinPartSize)
+	put("compose-small.log", 10)
+	t.Cleanup(func() { client.RemoveObject(ctx, bucketName, "compose-out.log", minio.RemoveObjectOptions{}) })
+
+	t.Run("Part too small", func(t *testing.T) {
+		w := postCompose(router, `{"sources":["compose-small.log","compose-big.log"],"destination":"compose-out.log"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "compose-small.log")
+	})
+
+	t.Run("Missing source", func(t *testing.T) {
+		w := postCompose(router, `{"sources":["compose-big.log","compose-missing.log"],"destination":"compose-out.log"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Composes", func(t *testing.T) {
+		w := postCompose(router, `{"sources":["compose-big.log","compose-small.log"],"destination":"compose-out.log"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp composeResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "compose-out.log", resp.Key)
+		assert.EqualValues(t, ComposeMinPartSize+10, resp.Size)
+		assert.Contains(t, resp.URL, "compose-out.log")
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// DefaultGzipMinBytes is the smallest response body worth compressing; the
+// typical presign response is well below it.
+const DefaultGzipMinBytes = 1024
+
+// gzipWriter buffers the response body so the compression decision can be
+// made once its final size and content type are known.
+type gzipWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// gzipJSON compresses JSON responses of at least minBytes for clients that
+// accept gzip. Other responses pass through unchanged.
+func gzipJSON(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &gzipWriter{ResponseWriter: original}
+		c.Writer = w
+		defer func() { c.Writer = original }()
+
+		c.Next()
+
+		body := w.buf.Bytes()
+		header := original.Header()
+		if len(body) < minBytes || !strings.HasPrefix(header.Get("Content-Type"), "application/json") {
+			original.Write(body)
+			return
+		}
+
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		gz := gzip.NewWriter(original)
+		if _, err := gz.Write(body); err != nil {
+			utils.LogError("Error compressing response for %s: %v", c.Request.URL.Path, err)
+		}
+		gz.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipJSON(t *testing.T) {
+	large := strings.Repeat("x", 2048)
+
+	router := gin.New()
+	router.Use(gzipJSON(DefaultGzipMinBytes))
+	router.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": large}) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": "x"}) })
+	router.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, large) })
+
+	get := func(path string, acceptGzip bool) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		require.NoError(t, err)
+		if acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip, deflate")
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Large JSON is compressed", func(t *testing.T) {
+		recorder := get("/large", true)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+
+		gz, err := gzip.NewReader(recorder.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), large)
+	})
+
+	t.Run("Small JSON is not compressed", func(t *testing.T) {
+		recorder := get("/small", true)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.JSONEq(t, `{"data":"x"}`, recorder.Body.String())
+	})
+
+	t.Run("Non-JSON is not compressed", func(t *testing.T) {
+		recorder := get("/text", true)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, recorder.Body.String())
+	})
+
+	t.Run("Client without gzip", func(t *testing.T) {
+		recorder := get("/large", false)
+		assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+		assert.Contains(t, recorder.Body.String(), large)
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyRetryAfterSeconds is the Retry-After hint sent when every
+// concurrency slot is taken. Slots free up as fast as MinIO answers, so it
+// is shorter than RetryAfterSeconds.
+const ConcurrencyRetryAfterSeconds = "1"
+
+// concurrencySlotContext holds the release func of a request's slot.
+const concurrencySlotContext = "miraio.concurrency.release"
+
+// concurrencyLimiter bounds how many requests that call MinIO run at once.
+// A nil slots channel means no limit; requests are still counted so the
+// in-flight figure in /stats can be used to choose one.
+type concurrencyLimiter struct {
+	slots    chan struct{}
+	inFlight atomic.Int64
+}
+
+// concurrency is the limiter shared by every MinIO-backed route
+// (MIRAIO_MAX_CONCURRENCY).
+var concurrency = newConcurrencyLimiter(0)
+
+// newConcurrencyLimiter returns a limiter admitting max requests at once, or
+// any number when max is 0.
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// limit returns the configured maximum, 0 when unlimited.
+func (l *concurrencyLimiter) limit() int {
+	return cap(l.slots)
+}
+
+// acquire takes a slot, reporting false when none is free. The returned
+// release may be called more than once.
+func (l *concurrencyLimiter) acquire() (release func(), ok bool) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return nil, false
+		}
+	}
+	l.inFlight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.inFlight.Add(-1)
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}, true
+}
+
+// middleware runs the request if a slot is free and otherwise answers 503
+// with Retry-After immediately, so a burst cannot queue up unboundedly.
+func (l *concurrencyLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := l.acquire()
+		if !ok {
+			c.Header("Retry-After", ConcurrencyRetryAfterSeconds)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Too many concurrent requests"})
+			return
+		}
+		defer release()
+		c.Set(concurrencySlotContext, release)
+		c.Next()
+	}
+}
+
+// releaseConcurrencySlot gives up the request's slot before it finishes, for
+// handlers whose remaining work is paced by the client rather than MinIO.
+func releaseConcurrencySlot(c *gin.Context) {
+	if release, ok := c.Value(concurrencySlotContext).(func()); ok {
+		release()
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter_RejectsWhenFull(t *testing.T) {
+	limiter := newConcurrencyLimiter(1)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/slow", limiter.middleware(), func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fast", limiter.middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+		done <- w.Code
+	}()
+	<-entered
+	assert.EqualValues(t, 1, limiter.inFlight.Load())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, ConcurrencyRetryAfterSeconds, w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Too many concurrent requests"}`, w.Body.String())
+
+	close(release)
+	select {
+	case code := <-done:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request did not finish")
+	}
+	assert.EqualValues(t, 0, limiter.inFlight.Load())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimiter_ReleaseTwice(t *testing.T) {
+	limiter := newConcurrencyLimiter(1)
+	release, ok := limiter.acquire()
+	require.True(t, ok)
+	_, ok = limiter.acquire()
+	assert.False(t, ok)
+
+	release()
+	release()
+	assert.EqualValues(t, 0, limiter.inFlight.Load())
+	assert.Len(t, limiter.slots, 0)
+}
+
+func TestConcurrencyLimiter_UnlimitedCountsInFlight(t *testing.T) {
+	limiter := newConcurrencyLimiter(0)
+	assert.Equal(t, 0, limiter.limit())
+
+	router := gin.New()
+	router.GET("/x", limiter.middleware(), func(c *gin.Context) {
+		assert.EqualValues(t, 1, limiter.inFlight.Load())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/x", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 0, limiter.inFlight.Load())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// minioConfigLine formats the MinIO connection settings for logging. The
+// access key is masked and the secret key is only reported as set or unset.
+func minioConfigLine(endpoint, accessKey, secretKey string, useSSL bool) string {
+	secretState := "unset"
+	if secretKey != "" {
+		secretState = "set"
+	}
+	return fmt.Sprintf("MinIO config: endpoint=%s ssl=%t bucket=%s accessKey=%s secretKey=<%s>",
+		endpoint, useSSL, bucketName, utils.MaskSecret(accessKey), secretState)
+}
+
+// effectiveConfig is the settings reported by logEffectiveConfig that are
+// not held in package-level variables.
+type effectiveConfig struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	token     string
+	useSSL    bool
+	listen    string
+	tls       bool
+	grpcPort  string
+}
+
+// logEffectiveConfig logs the resolved configuration as one block, so a
+// deployment can be checked from its startup log. Credentials go through
+// MaskSecret; the secret key is only reported as set or unset.
+func logEffectiveConfig(cfg effectiveConfig) {
+	utils.LogInfo("Effective configuration:\n%s", strings.Join(effectiveConfigLines(cfg), "\n"))
+}
+
+func effectiveConfigLines(cfg effectiveConfig) []string {
+	secretState := "<unset>"
+	if cfg.secretKey != "" {
+		secretState = "<set>"
+	}
+	region := minioRegion
+	if region == "" {
+		region = "(from bucket location)"
+	}
+	logOutput := "stdout"
+	if path := utils.LogPath(); path != "" {
+		logOutput = "dir=" + filepath.Dir(path)
+	}
+	tokenState := "<unset>"
+	if cfg.token != "" {
+		tokenState = "<set>"
+	}
+	apiKeyState := "<unset>"
+	if apiKey != "" {
+		apiKeyState = utils.MaskSecret(apiKey)
+	}
+
+	lines := []string{
+		"  minio.endpoint     " + cfg.endpoint,
+		"  minio.ssl          " + strconv.FormatBool(cfg.useSSL),
+		"  minio.region       " + region,
+		"  minio.bucket       " + bucketName,
+		"  minio.accessKey    " + utils.MaskSecret(cfg.accessKey),
+		"  minio.secretKey    " + secretState,
+		"  minio.sessionToken " + tokenState,
+		"  minio.signature    " + signatureVersion,
+		"  publicURL          " + publicURL + " (" + urlStyle + " style)",
+		"  keyPrefix          " + keyPrefix,
+		"  listen             " + cfg.listen,
+		"  apiKey             " + apiKeyState,
+		"  log                " + logOutput + " level=all",
+		"  features           " + strings.Join(enabledFeatures(cfg), ", "),
+	}
+	return lines
+}
+
+// enabledFeatures names the optional features switched on.
+func enabledFeatures(cfg effectiveConfig) []string {
+	var features []string
+	add := func(on bool, name string) {
+		if on {
+			features = append(features, name)
+		}
+	}
+	add(cfg.tls, "tls")
+	add(cfg.grpcPort != "", "grpc(:"+cfg.grpcPort+")")
+	add(authEnabled(), "api-key-auth")
+	add(jwtAuth != nil, "jwt-auth")
+	add(backends.multi(), fmt.Sprintf("backends(%d)", len(backends.all())))
+	add(keyTemplate != "", "key-template")
+	add(collisionStrategy != CollisionOverwrite, "collision-"+collisionStrategy)
+	add(versioned, "versioning")
+	add(objectLockEnabled, "object-lock("+retentionMode+")")
+	add(fsStore != nil, "fs-backend")
+	add(createBucket, "create-bucket")
+	add(bucketPolicyTemplate != "", "bucket-policy-template")
+	add(maxRetries > 0, fmt.Sprintf("retries(%d)", maxRetries))
+	add(presignCacheSize > 0, fmt.Sprintf("presign-cache(%d)", presignCacheSize))
+	add(requestTimeout > 0, "request-timeout("+requestTimeout.String()+")")
+	add(handlerTimeout > 0, "handler-timeout("+handlerTimeout.String()+")")
+	add(concurrency.limit() > 0, fmt.Sprintf("max-concurrency(%d)", concurrency.limit()))
+	add(idempotency != nil, "idempotency")
+	add(bucketWatch != nil, "verify-bucket")
+	add(webhook != nil, "webhook")
+	add(tracer != nil, "tracing")
+	add(jsonCase != JSONCaseCamel, "json-"+jsonCase)
+	add(os.Getenv("MIRAIO_ENABLE_GZIP") == "true", "gzip")
+	add(os.Getenv("MIRAIO_ACCESS_LOG") != "", "access-log")
+	add(auditLog != nil, "audit-log")
+	add(os.Getenv("MIRAIO_ALLOW_CIDRS") != "" || os.Getenv("MIRAIO_DENY_CIDRS") != "", "ip-filter")
+	add(os.Getenv("MIRAIO_SERVE_DEMO") == "true", "demo-page")
+	if len(features) == 0 {
+		return []string{"none"}
+	}
+	return features
+}
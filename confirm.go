@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// confirmResponse describes an uploaded object.
+type confirmResponse struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"lastModified" snake:"last_modified"`
+}
+
+// confirmHandler lets a client report that it finished a presigned upload.
+// The object is stat'ed server-side, so the client learns the stored size
+// and ETag without being granted stat access, and the completion is logged
+// for the backend that issued the URL.
+func confirmHandler(c *gin.Context) {
+	filename := c.Query("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing filename"})
+		return
+	}
+	b, name := backends.resolve(filename)
+	key, err := objectKey(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkScope(c, b, key) {
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var info storedObject
+	err = withRetry(ctx, "stat "+key, func() error {
+		var err error
+		info, err = b.storage().Stat(ctx, key, "")
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not confirm upload")
+		return
+	}
+
+	utils.LogInfo("Upload confirmed: %s on %s (%d bytes, etag %s) from %s", key, b.name, info.Size, info.ETag, c.ClientIP())
+	respondJSON(c, http.StatusOK, confirmResponse{
+		Key:          key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		LastModified: info.LastModified.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmHandler_InvalidInput(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/confirm", confirmHandler)
+
+	for _, query := range []string{"", "?filename=../secret"} {
+		req := httptest.NewRequest("GET", "/confirm"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestIntegrationConfirm(t *testing.T) {
+	client := integrationClient(t)
+
+	router := gin.New()
+	router.GET("/confirm", confirmHandler)
+
+	get := func(filename string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/confirm?filename="+filename, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	key := "confirm-test.txt"
+	assert.Equal(t, http.StatusNotFound, get(key).Code)
+
+	body := []byte("uploaded")
+	info, err := client.PutObject(context.Background(), bucketName, key, bytes.NewReader(body), int64(len(body)), minio.PutObjectOptions{})
+	require.NoError(t, err)
+	defer client.RemoveObject(context.Background(), bucketName, key, minio.RemoveObjectOptions{})
+
+	w := get(key)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.EqualValues(t, len(body), resp["size"])
+	assert.Equal(t, info.ETag, resp["etag"])
+}
@@ -0,0 +1,108 @@
+//go:build testcontainers
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// MinIOImage is the server image used for hermetic integration tests.
+const MinIOImage = "minio/minio:RELEASE.2025-04-22T22-12-26Z"
+
+// startMinIOContainer runs a throwaway MinIO, creates the test bucket and
+// points the package globals at it. The container is removed when the test
+// ends. Requires Docker; build with -tags testcontainers.
+func startMinIOContainer(t *testing.T) *minio.Client {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping container test in short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	ctr, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        MinIOImage,
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     "minio",
+				"MINIO_ROOT_PASSWORD": "minio123",
+			},
+			WaitingFor: wait.ForHTTP("/minio/health/ready").WithPort("9000/tcp"),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, ctr)
+	require.NoError(t, err)
+
+	endpoint, err := ctr.PortEndpoint(ctx, "9000/tcp", "")
+	require.NoError(t, err)
+
+	setupTestEnvironment()
+	publicURL = "http://" + endpoint
+	minioClient, err = minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4("minio", "minio123", ""),
+		Region: minioRegion,
+	})
+	require.NoError(t, err)
+	require.NoError(t, minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: minioRegion}))
+	signer = newSigner()
+	return minioClient
+}
+
+func TestContainerUploadWorkflow(t *testing.T) {
+	startMinIOContainer(t)
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/presign-get", presignGetHandler)
+
+	content := "uploaded through a presigned URL"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=workflow.txt&type=text/plain&meta.owner=alice", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var upload PresignResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upload))
+
+	req, err := http.NewRequest(http.MethodPut, upload.URL, strings.NewReader(content))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Amz-Meta-Owner", "alice")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-get?filename=workflow.txt", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var download PresignResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &download))
+
+	resp, err = http.Get(download.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, content, string(body))
+	assert.Equal(t, "alice", resp.Header.Get("X-Amz-Meta-Owner"))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"mime"
+	"path"
+)
+
+// DefaultContentType is used when inference cannot determine a type.
+const DefaultContentType = "application/octet-stream"
+
+var (
+	// inferContentType enables extension-based content type inference
+	// (MIRAIO_INFER_CONTENT_TYPE).
+	inferContentType bool
+	// defaultContentType is the fallback when inference finds nothing
+	// (MIRAIO_DEFAULT_CONTENT_TYPE).
+	defaultContentType = DefaultContentType
+)
+
+// resolveContentType returns the content type to sign for an upload. When
+// inference is enabled and the client sent no type or the generic
+// application/octet-stream, the type is derived from the file extension.
+func resolveContentType(filename, contentType string) string {
+	if !inferContentType || (contentType != "" && contentType != DefaultContentType) {
+		return contentType
+	}
+	if inferred := mime.TypeByExtension(path.Ext(filename)); inferred != "" {
+		return inferred
+	}
+	return defaultContentType
+}
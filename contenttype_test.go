@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveContentType(t *testing.T) {
+	defer func() {
+		inferContentType = false
+		defaultContentType = DefaultContentType
+	}()
+
+	t.Run("Disabled", func(t *testing.T) {
+		inferContentType = false
+		assert.Equal(t, "", resolveContentType("photo.png", ""))
+		assert.Equal(t, DefaultContentType, resolveContentType("photo.png", DefaultContentType))
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		inferContentType = true
+		assert.Equal(t, "image/png", resolveContentType("photo.png", ""))
+		assert.Equal(t, "image/png", resolveContentType("dir/photo.PNG", DefaultContentType))
+		assert.Equal(t, "text/plain", resolveContentType("photo.png", "text/plain"))
+		assert.Equal(t, DefaultContentType, resolveContentType("archive.unknownext", ""))
+	})
+
+	t.Run("Custom default", func(t *testing.T) {
+		inferContentType = true
+		defaultContentType = "binary/octet-stream"
+		assert.Equal(t, "binary/octet-stream", resolveContentType("noext", ""))
+	})
+}
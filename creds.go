@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/mirago/miraio/utils"
+)
+
+// MinIO authentication modes (MIRAIO_MINIO_AUTH).
+const (
+	AuthStatic = "static"
+	AuthSTS    = "sts"
+)
+
+// Signature versions (MIRAIO_SIGNATURE_VERSION).
+const (
+	SignatureV2 = "v2"
+	SignatureV4 = "v4"
+)
+
+// signatureVersion selects how requests to MinIO and presigned URLs are
+// signed. v2 exists only for old S3-compatible stores that reject v4.
+var signatureVersion = SignatureV4
+
+// parseSignatureVersion reads MIRAIO_SIGNATURE_VERSION.
+func parseSignatureVersion(v string) (string, error) {
+	switch strings.ToLower(v) {
+	case "", SignatureV4:
+		return SignatureV4, nil
+	case SignatureV2:
+		return SignatureV2, nil
+	default:
+		return "", fmt.Errorf("unsupported MIRAIO_SIGNATURE_VERSION %q: use v2 or v4", v)
+	}
+}
+
+// secretEnv reads a credential from the file named by name+"_FILE", as
+// mounted by Docker and Kubernetes secrets, or else from name itself, so the
+// value need not appear in the process environment. Trailing newlines are
+// trimmed. An unreadable or empty file is an error rather than a silent
+// fallback to the inline variable.
+func secretEnv(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	v := strings.TrimRight(string(data), "\r\n")
+	if v == "" {
+		return "", fmt.Errorf("%s_FILE: %s is empty", name, path)
+	}
+	return v, nil
+}
+
+// credentialEnv reads the ACCESS_KEY, SECRET_KEY and SESSION_TOKEN
+// variables under prefix, each of which may be given as a _FILE.
+func credentialEnv(prefix string) (accessKey, secretKey, sessionToken string, err error) {
+	if accessKey, err = secretEnv(prefix + "ACCESS_KEY"); err != nil {
+		return "", "", "", err
+	}
+	if secretKey, err = secretEnv(prefix + "SECRET_KEY"); err != nil {
+		return "", "", "", err
+	}
+	if sessionToken, err = secretEnv(prefix + "SESSION_TOKEN"); err != nil {
+		return "", "", "", err
+	}
+	return accessKey, secretKey, sessionToken, nil
+}
+
+// staticCredentials returns fixed credentials that sign with the configured
+// signature version. sessionToken is set for temporary credentials, e.g. from
+// an assumed role; presigned URLs then carry it as X-Amz-Security-Token.
+func staticCredentials(accessKey, secretKey, sessionToken string) *credentials.Credentials {
+	if signatureVersion == SignatureV2 {
+		return credentials.NewStaticV2(accessKey, secretKey, sessionToken)
+	}
+	return credentials.NewStaticV4(accessKey, secretKey, sessionToken)
+}
+
+// loadCredentials builds the MinIO credentials provider. The default static
+// mode signs with the configured keys directly. In sts mode those keys only
+// authenticate AssumeRole calls, and requests are signed with the temporary
+// credentials it returns, which minio-go renews shortly before they expire.
+//
+// STS settings: MIRAIO_STS_ENDPOINT (default: the MinIO endpoint),
+// MIRAIO_STS_ROLE_ARN, MIRAIO_STS_SESSION_NAME and MIRAIO_STS_DURATION
+// (seconds, default 3600).
+func loadCredentials(endpoint string, useSSL bool, accessKey, secretKey, sessionToken string) (*credentials.Credentials, error) {
+	switch mode := os.Getenv("MIRAIO_MINIO_AUTH"); mode {
+	case "", AuthStatic:
+		return staticCredentials(accessKey, secretKey, sessionToken), nil
+	case AuthSTS:
+		if signatureVersion == SignatureV2 {
+			return nil, fmt.Errorf("MIRAIO_MINIO_AUTH=sts requires MIRAIO_SIGNATURE_VERSION=v4")
+		}
+		stsEndpoint := os.Getenv("MIRAIO_STS_ENDPOINT")
+		if stsEndpoint == "" {
+			stsEndpoint = "http://" + endpoint
+			if useSSL {
+				stsEndpoint = "https://" + endpoint
+			}
+		}
+
+		opts := credentials.STSAssumeRoleOptions{
+			AccessKey:       accessKey,
+			SecretKey:       secretKey,
+			SessionToken:    sessionToken,
+			Location:        minioRegion,
+			RoleARN:         os.Getenv("MIRAIO_STS_ROLE_ARN"),
+			RoleSessionName: os.Getenv("MIRAIO_STS_SESSION_NAME"),
+		}
+		if v := os.Getenv("MIRAIO_STS_DURATION"); v != "" {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				return nil, fmt.Errorf("invalid MIRAIO_STS_DURATION %q: must be a positive number of seconds", v)
+			}
+			opts.DurationSeconds = seconds
+		}
+
+		creds, err := credentials.NewSTSAssumeRole(stsEndpoint, opts)
+		if err != nil {
+			return nil, fmt.Errorf("configuring STS credentials: %w", err)
+		}
+		if minioTransport != nil {
+			creds = credentials.New(&credentials.STSAssumeRole{
+				Client:      &http.Client{Transport: minioTransport},
+				STSEndpoint: stsEndpoint,
+				Options:     opts,
+			})
+		}
+		utils.LogInfo("Using temporary credentials from STS endpoint %s", stsEndpoint)
+		return creds, nil
+	default:
+		return nil, fmt.Errorf("unsupported MIRAIO_MINIO_AUTH %q: use static or sts", mode)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	t.Run("Static by default", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "")
+		creds, err := loadCredentials("localhost:9000", false, "minio", "minio123", "")
+		require.NoError(t, err)
+
+		value, err := creds.GetWithContext(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "minio", value.AccessKeyID)
+		assert.Empty(t, value.SessionToken)
+	})
+
+	t.Run("Static with session token", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "")
+		creds, err := loadCredentials("localhost:9000", false, "ASIATEMP", "temp-secret", "session-token")
+		require.NoError(t, err)
+
+		value, err := creds.GetWithContext(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "session-token", value.SessionToken)
+
+		client, err := minio.New("localhost:9000", &minio.Options{Creds: creds, Region: "us-east-1"})
+		require.NoError(t, err)
+		signed, err := client.PresignedGetObject(context.Background(), "test-bucket", "a.txt", time.Minute, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "session-token", signed.Query().Get("X-Amz-Security-Token"))
+	})
+
+	t.Run("STS", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "sts")
+		t.Setenv("MIRAIO_STS_DURATION", "900")
+		creds, err := loadCredentials("localhost:9000", false, "minio", "minio123", "")
+		require.NoError(t, err)
+		assert.NotNil(t, creds)
+	})
+
+	t.Run("STS requires base keys", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "sts")
+		_, err := loadCredentials("localhost:9000", false, "", "", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid STS duration", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "sts")
+		t.Setenv("MIRAIO_STS_DURATION", "soon")
+		_, err := loadCredentials("localhost:9000", false, "minio", "minio123", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_AUTH", "ldap")
+		_, err := loadCredentials("localhost:9000", false, "minio", "minio123", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestParseSignatureVersion(t *testing.T) {
+	for in, want := range map[string]string{"": SignatureV4, "v4": SignatureV4, "V2": SignatureV2} {
+		got, err := parseSignatureVersion(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseSignatureVersion("v3")
+	assert.Error(t, err)
+}
+
+func TestLoadCredentials_SignatureV2(t *testing.T) {
+	old := signatureVersion
+	signatureVersion = SignatureV2
+	t.Cleanup(func() { signatureVersion = old })
+
+	t.Setenv("MIRAIO_MINIO_AUTH", "")
+	creds, err := loadCredentials("localhost:9000", false, "minio", "minio123", "")
+	require.NoError(t, err)
+	value, err := creds.GetWithContext(nil)
+	require.NoError(t, err)
+	assert.True(t, value.SignerType.IsV2())
+
+	t.Setenv("MIRAIO_MINIO_AUTH", "sts")
+	_, err = loadCredentials("localhost:9000", false, "minio", "minio123", "")
+	assert.Error(t, err, "STS credentials are always v4")
+}
+
+func TestSecretEnv(t *testing.T) {
+	t.Setenv("MIRAIO_MINIO_SECRET_KEY", "inline-secret")
+	t.Setenv("MIRAIO_MINIO_SECRET_KEY_FILE", "")
+	v, err := secretEnv("MIRAIO_MINIO_SECRET_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "inline-secret", v)
+
+	path := filepath.Join(t.TempDir(), "secret_key")
+	require.NoError(t, os.WriteFile(path, []byte("file-secret\n\n"), 0o600))
+	t.Setenv("MIRAIO_MINIO_SECRET_KEY_FILE", path)
+	v, err = secretEnv("MIRAIO_MINIO_SECRET_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret", v, "the file wins and trailing newlines are trimmed")
+
+	t.Setenv("MIRAIO_MINIO_SECRET_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = secretEnv("MIRAIO_MINIO_SECRET_KEY")
+	assert.ErrorContains(t, err, "MIRAIO_MINIO_SECRET_KEY_FILE")
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	t.Setenv("MIRAIO_MINIO_SECRET_KEY_FILE", path)
+	_, err = secretEnv("MIRAIO_MINIO_SECRET_KEY")
+	assert.ErrorContains(t, err, "is empty")
+}
+
+func TestCredentialEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "access"), []byte("file-access\n"), 0o600))
+	t.Setenv("MIRAIO_BACKEND_EU_ACCESS_KEY", "inline-access")
+	t.Setenv("MIRAIO_BACKEND_EU_ACCESS_KEY_FILE", filepath.Join(dir, "access"))
+	t.Setenv("MIRAIO_BACKEND_EU_SECRET_KEY", "inline-secret")
+	t.Setenv("MIRAIO_BACKEND_EU_SESSION_TOKEN", "")
+
+	access, secret, token, err := credentialEnv("MIRAIO_BACKEND_EU_")
+	require.NoError(t, err)
+	assert.Equal(t, "file-access", access)
+	assert.Equal(t, "inline-secret", secret)
+	assert.Empty(t, token)
+}
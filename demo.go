@@ -0,0 +1,19 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// demoPage is a self-contained upload form for checking a deployment end to
+// end (MIRAIO_SERVE_DEMO).
+//
+//go:embed static/demo.html
+var demoPage []byte
+
+// demoHandler serves the upload test page.
+func demoHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", demoPage)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// parseDownloadTypes reads MIRAIO_DOWNLOAD_TYPES, the MIME patterns whose
+// presigned downloads default to Content-Disposition: attachment. It is a
+// comma-separated list, e.g. "application/pdf,video/*", matched like
+// MIRAIO_EXPIRY_BY_TYPE.
+func parseDownloadTypes(v string) (map[string]bool, error) {
+	patterns := make(map[string]bool)
+	for _, pattern := range strings.Split(v, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if !validMIMEPattern(pattern) {
+			return nil, fmt.Errorf("invalid pattern %q: want type/subtype, type/* or */*", pattern)
+		}
+		patterns[pattern] = true
+	}
+	return patterns, nil
+}
+
+// isDownloadType reports whether contentType matches a download pattern.
+func isDownloadType(downloadTypes map[string]bool, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+	typ, _, _ := strings.Cut(mediaType, "/")
+	return downloadTypes[mediaType] || downloadTypes[typ+"/*"] || downloadTypes["*/*"]
+}
+
+// addDownloadDisposition makes a presigned download of key an attachment
+// when its type, contentType or else the one implied by the key's
+// extension, is a download type. An explicit disposition in reqParams is
+// left alone.
+func addDownloadDisposition(reqParams url.Values, key, contentType string) {
+	downloadTypes := currentConfig().downloadTypes
+	if len(downloadTypes) == 0 || reqParams.Has("response-content-disposition") {
+		return
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" || !isDownloadType(downloadTypes, contentType) {
+		return
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	reqParams.Set("response-content-disposition", disposition)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDownloadTypes(t *testing.T) {
+	patterns, err := parseDownloadTypes(" application/PDF , video/* ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"application/pdf": true, "video/*": true}, patterns)
+
+	for _, bad := range []string{"pdf", "*/pdf", "a/b/c"} {
+		_, err := parseDownloadTypes(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAddDownloadDisposition(t *testing.T) {
+	withConfig(t, func(cfg *reloadableConfig) {
+		cfg.downloadTypes = map[string]bool{"application/pdf": true, "video/*": true}
+	})
+
+	params := make(url.Values)
+	addDownloadDisposition(params, "docs/report.pdf", "")
+	assert.Equal(t, `attachment; filename=report.pdf`, params.Get("response-content-disposition"))
+
+	params = make(url.Values)
+	addDownloadDisposition(params, "clip.bin", "video/mp4")
+	assert.Equal(t, `attachment; filename=clip.bin`, params.Get("response-content-disposition"))
+
+	params = make(url.Values)
+	addDownloadDisposition(params, "photo.jpg", "")
+	assert.Empty(t, params)
+
+	params = url.Values{"response-content-disposition": {"inline"}}
+	addDownloadDisposition(params, "report.pdf", "")
+	assert.Equal(t, "inline", params.Get("response-content-disposition"))
+}
+
+func TestPresignGetHandler_DownloadTypes(t *testing.T) {
+	setupTestEnvironment()
+	withConfig(t, func(cfg *reloadableConfig) {
+		cfg.downloadTypes = map[string]bool{"application/pdf": true}
+	})
+
+	router := gin.New()
+	router.GET("/presign-get", presignGetHandler)
+
+	for filename, want := range map[string]string{
+		"report.pdf": "attachment; filename=report.pdf",
+		"photo.jpg":  "",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-get?filename="+filename, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp PresignResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		signed, err := url.Parse(resp.URL)
+		require.NoError(t, err)
+		assert.Equal(t, want, signed.Query().Get("response-content-disposition"), filename)
+	}
+}
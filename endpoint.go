@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/mirago/miraio/utils"
+)
+
+// parseEndpoint accepts either host[:port] or a URL such as
+// https://host[:port] and returns the host and whether TLS is used. A bare
+// host inherits defaultSecure.
+func parseEndpoint(v string, defaultSecure bool) (string, bool, error) {
+	if !strings.Contains(v, "://") {
+		return v, defaultSecure, nil
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("invalid endpoint %q", v)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", false, fmt.Errorf("endpoint %q must not contain a path", v)
+	}
+	switch u.Scheme {
+	case "http":
+		return u.Host, false, nil
+	case "https":
+		return u.Host, true, nil
+	default:
+		return "", false, fmt.Errorf("endpoint %q must use http or https", v)
+	}
+}
+
+// newPublicPresignClient returns a client used only for signing URLs against
+// the public endpoint (MIRAIO_MINIO_PUBLIC_ENDPOINT). The host is part of the
+// signature, so rewriting the host of a URL signed for the internal endpoint
+// would invalidate it; signing directly for the public host keeps it valid.
+// Presigning is purely local once the region is known, so the public host
+// never has to be reachable from the server.
+func newPublicPresignClient(publicEndpoint string, creds *credentials.Credentials, useSSL bool, region string) (*minio.Client, error) {
+	host, secure, err := parseEndpoint(publicEndpoint, useSSL)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo("Signing URLs for public endpoint %s (ssl=%t, region=%s)", host, secure, region)
+	return minio.New(host, &minio.Options{
+		Creds:     creds,
+		Secure:    secure,
+		Region:    region,
+		Transport: minioTransport,
+	})
+}
+
+// detectRegion asks the internal endpoint for the bucket location, falling
+// back to us-east-1 (MinIO's default) when it cannot be determined.
+func detectRegion(client *minio.Client, bucket string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), StartupCheckTimeout)
+	defer cancel()
+
+	region, err := client.GetBucketLocation(ctx, bucket)
+	if err != nil || region == "" {
+		utils.LogWarning("Could not detect region of bucket %s (%v); assuming us-east-1. Set MIRAIO_MINIO_REGION (or the backend's _REGION) to silence this.", bucket, err)
+		return "us-east-1"
+	}
+	return region
+}
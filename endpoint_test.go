@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		in     string
+		host   string
+		secure bool
+		valid  bool
+	}{
+		{"minio.internal:9000", "minio.internal:9000", false, true},
+		{"http://files.example.com", "files.example.com", false, true},
+		{"https://files.example.com:8443", "files.example.com:8443", true, true},
+		{"https://files.example.com/", "files.example.com", true, true},
+		{"https://files.example.com/path", "", false, false},
+		{"ftp://files.example.com", "", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			host, secure, err := parseEndpoint(tc.in, false)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.host, host)
+			assert.Equal(t, tc.secure, secure)
+		})
+	}
+}
+
+func TestPublicPresignClient(t *testing.T) {
+	setupTestEnvironment()
+	defer setupTestEnvironment()
+
+	creds := credentials.NewStaticV4("minio", "minio123", "")
+	client, err := newPublicPresignClient("https://files.example.com", creds, false, "us-east-1")
+	require.NoError(t, err)
+	signer.Client = client
+
+	signed, public, err := signer.GetURL(context.Background(), "a.txt", time.Minute, nil)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "https", u.Scheme)
+	assert.Equal(t, "files.example.com", u.Host)
+	assert.Equal(t, "/test-bucket/a.txt", u.Path)
+	assert.Contains(t, u.Query().Get("X-Amz-Credential"), "/us-east-1/s3/")
+	assert.Equal(t, "http://localhost:9000/test-bucket/a.txt", public)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseExpiryByType reads a comma-separated list of pattern=seconds pairs,
+// e.g. "video/*=900,image/*=120".
+func parseExpiryByType(v string) (map[string]time.Duration, error) {
+	rules := make(map[string]time.Duration)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, seconds, ok := strings.Cut(entry, "=")
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if !ok || !validMIMEPattern(pattern) {
+			return nil, fmt.Errorf("invalid entry %q: want type/subtype=seconds", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(seconds))
+		if err != nil || !validExpirySeconds(n) {
+			return nil, fmt.Errorf("invalid expiry in %q: must be between %d and %d seconds", entry, int(MinExpiry.Seconds()), int(MaxExpiry.Seconds()))
+		}
+		rules[pattern] = time.Duration(n) * time.Second
+	}
+	return rules, nil
+}
+
+// validMIMEPattern accepts type/subtype where subtype may be "*", and "*/*".
+func validMIMEPattern(p string) bool {
+	typ, subtype, ok := strings.Cut(p, "/")
+	if !ok || typ == "" || subtype == "" || strings.Contains(subtype, "/") {
+		return false
+	}
+	return typ != "*" || subtype == "*"
+}
+
+// uploadExpiry returns the lifetime for an upload URL of contentType. The
+// most specific matching pattern wins; without a match it is DefaultExpiry.
+func uploadExpiry(contentType string) time.Duration {
+	expiryByType := currentConfig().expiryByType
+	if len(expiryByType) == 0 {
+		return DefaultExpiry
+	}
+	for _, pattern := range mediaTypePatterns(contentType) {
+		if expiry, ok := expiryByType[pattern]; ok {
+			return expiry
+		}
+	}
+	return DefaultExpiry
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpiryByType(t *testing.T) {
+	rules, err := parseExpiryByType("video/*=900, image/*=120,image/gif=30,*/*=240")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"video/*":   900 * time.Second,
+		"image/*":   120 * time.Second,
+		"image/gif": 30 * time.Second,
+		"*/*":       240 * time.Second,
+	}, rules)
+
+	rules, err = parseExpiryByType("")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	rules, err = parseExpiryByType("video/*=604800")
+	require.NoError(t, err)
+	assert.Equal(t, MaxExpiry, rules["video/*"])
+
+	for _, v := range []string{"video/*", "video=900", "*/mp4=10", "video/*=0", "video/*=abc", "video/*=604801"} {
+		_, err := parseExpiryByType(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	for v, want := range map[string]time.Duration{
+		"":       DefaultExpiry,
+		"1":      MinExpiry,
+		"3600":   time.Hour,
+		"604800": MaxExpiry,
+	} {
+		got, err := parseExpiry(v)
+		require.NoError(t, err, v)
+		assert.Equal(t, want, got, v)
+	}
+	for _, v := range []string{"0", "-1", "604801", "99999999999999999999", "1.5", "soon"} {
+		_, err := parseExpiry(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestPresignHandler_ExpiryBounds(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/upload-bundle", uploadBundleHandler)
+
+	for path, want := range map[string]int{
+		"/presign?method=GET&filename=a.txt&expiry=1":                 http.StatusOK,
+		"/presign?method=GET&filename=a.txt&expiry=604800":            http.StatusOK,
+		"/presign?method=GET&filename=a.txt&expiry=0":                 http.StatusBadRequest,
+		"/presign?method=GET&filename=a.txt&expiry=604801":            http.StatusBadRequest,
+		"/presign?method=HEAD&filename=a.txt&expiry=604801":           http.StatusBadRequest,
+		"/upload-bundle?filename=a.txt&type=text/plain&expiry=604800": http.StatusOK,
+		"/upload-bundle?filename=a.txt&type=text/plain&expiry=604801": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+		if want == http.StatusBadRequest {
+			assert.Contains(t, w.Body.String(), "between 1 and 604800 seconds", path)
+		}
+	}
+}
+
+func TestUploadExpiry(t *testing.T) {
+	assert.Equal(t, DefaultExpiry, uploadExpiry("video/mp4"))
+
+	expiryByType, _ := parseExpiryByType("video/*=900,image/*=120,image/gif=30")
+	withConfig(t, func(cfg *reloadableConfig) { cfg.expiryByType = expiryByType })
+	assert.Equal(t, 900*time.Second, uploadExpiry("video/mp4"))
+	assert.Equal(t, 900*time.Second, uploadExpiry("Video/MP4"))
+	assert.Equal(t, 120*time.Second, uploadExpiry("image/png"))
+	assert.Equal(t, 30*time.Second, uploadExpiry("image/gif"))
+	assert.Equal(t, 120*time.Second, uploadExpiry("image/svg+xml; charset=utf-8"))
+	assert.Equal(t, DefaultExpiry, uploadExpiry("text/plain"))
+
+	expiryByType["*/*"] = 240 * time.Second
+	assert.Equal(t, 240*time.Second, uploadExpiry("text/plain"))
+}
+
+func TestPresignHandler_ExpiryByType(t *testing.T) {
+	setupTestEnvironment()
+	withConfig(t, func(cfg *reloadableConfig) {
+		cfg.expiryByType = map[string]time.Duration{"video/*": 900 * time.Second}
+	})
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	for contentType, expected := range map[string]float64{"video/mp4": 900, "image/png": 60} {
+		req := httptest.NewRequest("GET", "/presign?filename=clip&type="+url.QueryEscape(contentType), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, expected, response["expiry"], contentType)
+		assert.Contains(t, response["url"], "X-Amz-Expires="+strconv.Itoa(int(expected)))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errExtensionNotAllowed rejects uploads whose filename extension is not in
+// MIRAIO_ALLOWED_EXTENSIONS; HTTP handlers answer it with 415.
+var errExtensionNotAllowed = errors.New("File extension not allowed")
+
+// parseAllowedExtensions reads MIRAIO_ALLOWED_EXTENSIONS, a comma-separated
+// list such as "jpg,png,pdf". Entries are lowercased and may start with a
+// dot; compound extensions such as "tar.gz" are allowed.
+func parseAllowedExtensions(v string) ([]string, error) {
+	var exts []string
+	for _, ext := range strings.Split(v, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext == "" {
+			continue
+		}
+		if strings.ContainsAny(ext, "/ ") || strings.HasSuffix(ext, ".") || strings.Contains(ext, "..") {
+			return nil, fmt.Errorf("invalid extension %q", ext)
+		}
+		exts = append(exts, ext)
+	}
+	return exts, nil
+}
+
+// checkExtension returns errExtensionNotAllowed unless filename ends in one
+// of the allowed extensions, ignoring case. Only the last path segment
+// counts, and it needs a name before the extension, so ".pdf" or "a/.pdf"
+// do not pass for "pdf". With no allowed extensions every name passes.
+func checkExtension(allowed []string, filename string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	base := strings.ToLower(path.Base(filename))
+	for _, ext := range allowed {
+		if stem, ok := strings.CutSuffix(base, "."+ext); ok && strings.Trim(stem, ".") != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: use %s", errExtensionNotAllowed, strings.Join(allowed, ", "))
+}
+
+// respondUploadError answers a prepareUpload error: 415 for a disallowed
+// extension or content type, 403 for an expiry beyond the prefix policy,
+// 400 for anything else.
+func respondUploadError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errExtensionNotAllowed), errors.Is(err, errTypeNotAllowed):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, errExpiryNotAllowed):
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedExtensions(t *testing.T) {
+	exts, err := parseAllowedExtensions(" JPG, .png ,tar.gz,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jpg", "png", "tar.gz"}, exts)
+
+	for _, bad := range []string{"a/b", "jp g", "gz.", "tar..gz"} {
+		_, err := parseAllowedExtensions(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCheckExtension(t *testing.T) {
+	allowed := []string{"jpg", "pdf", "tar.gz"}
+
+	for _, name := range []string{"cat.jpg", "CAT.JPG", "photos/cat.Jpg", "my.holiday.photo.jpg", "report.v2.pdf", "backup.tar.gz", "backup.2024.tar.gz"} {
+		assert.NoError(t, checkExtension(allowed, name), name)
+	}
+	for _, name := range []string{"cat.png", "report.pdf.exe", "jpg", ".jpg", "dir/.pdf", "cat.jpg.", "photos.jpg/cat", "archive.gz", "nodot"} {
+		err := checkExtension(allowed, name)
+		assert.ErrorIs(t, err, errExtensionNotAllowed, name)
+	}
+
+	assert.NoError(t, checkExtension(nil, "anything.exe"))
+}
+
+func TestPresignHandler_AllowedExtensions(t *testing.T) {
+	setupTestEnvironment()
+	withConfig(t, func(cfg *reloadableConfig) { cfg.allowedExtensions = []string{"jpg", "png"} })
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.POST("/presign", presignPostHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=cat.JPG&type=image/jpeg", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=cat.jpg.exe&type=image/jpeg", nil))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "File extension not allowed: use jpg, png")
+
+	req := httptest.NewRequest("POST", "/presign", strings.NewReader(`{"filename":"notes.txt","type":"text/plain"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
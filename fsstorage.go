@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// FSRoutePrefix is where the server serves objects of the fs backend.
+const FSRoutePrefix = "/fs"
+
+// Defaults for the fs backend.
+const (
+	DefaultFSRoot   = "data"
+	DefaultFSBucket = "uploads"
+)
+
+// Query parameters of URLs signed by the fs backend.
+const (
+	fsExpiresParam       = "X-Miraio-Expires"
+	fsSignedHeadersParam = "X-Miraio-SignedHeaders"
+	fsSignatureParam     = "X-Miraio-Signature"
+)
+
+// fsTempPrefix marks uploads still being written; they are hidden from
+// listings.
+const fsTempPrefix = ".miraio-upload-"
+
+// fsStore is the filesystem Storage, set when MIRAIO_BACKEND=fs.
+var fsStore *fsStorage
+
+// fsStorage implements Storage on a local directory, for development
+// without MinIO. Objects live under root/<bucket>/<key> and are served by
+// the server itself at baseURL/<bucket>/<key>, behind URLs signed with an
+// HMAC of the method, path, query and signed headers. It also implements
+// presign.Client, so every presign endpoint works on top of it.
+type fsStorage struct {
+	root    string
+	bucket  string
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+}
+
+// newFSStorage returns a store for bucket under root, creating the bucket
+// directory if needed. baseURL is the absolute URL of FSRoutePrefix as
+// clients reach it.
+func newFSStorage(root, bucket, baseURL string, secret []byte) (*fsStorage, error) {
+	if bucket == "" {
+		return nil, errors.New("a bucket name is required")
+	}
+	if err := os.MkdirAll(filepath.Join(root, bucket), 0o755); err != nil {
+		return nil, err
+	}
+	return &fsStorage{
+		root:    root,
+		bucket:  bucket,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+		now:     time.Now,
+	}, nil
+}
+
+// initFSStorage sets up fsStore from MIRAIO_FS_ROOT, MIRAIO_FS_BASE_URL and
+// MIRAIO_FS_SECRET. The bucket is MIRAIO_MINIO_BUCKET, or DefaultFSBucket.
+func initFSStorage() error {
+	root := os.Getenv("MIRAIO_FS_ROOT")
+	if root == "" {
+		root = DefaultFSRoot
+	}
+	if bucketName == "" {
+		bucketName = DefaultFSBucket
+	}
+
+	base := os.Getenv("MIRAIO_FS_BASE_URL")
+	if base == "" {
+		port := os.Getenv("MIRAIO_PORT")
+		if port == "" {
+			port = DefaultPort
+		}
+		base = "http://localhost:" + port
+	}
+	if u, err := url.Parse(base); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("MIRAIO_FS_BASE_URL must be an absolute URL, got %q", base)
+	}
+
+	secret := []byte(os.Getenv("MIRAIO_FS_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		utils.LogWarning("MIRAIO_FS_SECRET not set; using a random key, so signed URLs stop working on restart")
+	}
+
+	store, err := newFSStorage(root, bucketName, strings.TrimRight(base, "/")+FSRoutePrefix, secret)
+	if err != nil {
+		return err
+	}
+	fsStore = store
+	utils.LogInfo("Using the fs backend: objects in %s, served at %s", filepath.Join(root, bucketName), store.baseURL)
+	return nil
+}
+
+// path maps key to a file below the bucket directory, rejecting keys that
+// could escape it.
+func (s *fsStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return "", errObjectNotFound
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", errObjectNotFound
+		}
+	}
+	return filepath.Join(s.root, s.bucket, filepath.FromSlash(key)), nil
+}
+
+// PresignHeader signs method on key. reqParams and the values of
+// extraHeaders are covered by the signature, as with S3.
+func (s *fsStorage) PresignHeader(_ context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	u, err := url.Parse(s.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + bucketName + "/" + objectName
+
+	query := make(url.Values, len(reqParams)+3)
+	for k, v := range reqParams {
+		query[k] = append([]string(nil), v...)
+	}
+	query.Set(fsExpiresParam, strconv.FormatInt(s.now().Add(expires).Unix(), 10))
+
+	// Callers may pass non-canonical names (e.g. x-amz-meta-*); canonicalise
+	// them so signing and verification look values up the same way.
+	headers := make(http.Header, len(extraHeaders))
+	names := make([]string, 0, len(extraHeaders))
+	for name, values := range extraHeaders {
+		headers[http.CanonicalHeaderKey(name)] = values
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		query.Set(fsSignedHeadersParam, strings.Join(names, ";"))
+	}
+	query.Set(fsSignatureParam, s.signature(method, bucketName, objectName, query, headers))
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// signature is the hex HMAC-SHA256 of the request description. query must
+// not contain fsSignatureParam.
+func (s *fsStorage) signature(method, bucket, key string, query url.Values, headers http.Header) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s/%s\n", method, bucket, key)
+	unsigned := make(url.Values, len(query))
+	for k, v := range query {
+		if k != fsSignatureParam {
+			unsigned[k] = v
+		}
+	}
+	io.WriteString(mac, unsigned.Encode())
+	if signed := query.Get(fsSignedHeadersParam); signed != "" {
+		for _, name := range strings.Split(signed, ";") {
+			fmt.Fprintf(mac, "\n%s:%s", name, headers.Get(name))
+		}
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *fsStorage) PresignPut(ctx context.Context, key string, headers http.Header, expiry time.Duration) (string, error) {
+	u, err := s.PresignHeader(ctx, http.MethodPut, s.bucket, key, expiry, nil, headers)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *fsStorage) PresignGet(ctx context.Context, key string, expiry time.Duration, reqParams url.Values) (string, error) {
+	u, err := s.PresignHeader(ctx, http.MethodGet, s.bucket, key, expiry, reqParams, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Stat describes key. The fs backend keeps no versions, so any versionID
+// is reported as missing.
+func (s *fsStorage) Stat(_ context.Context, key, versionID string) (storedObject, error) {
+	if versionID != "" {
+		return storedObject{}, errObjectNotFound
+	}
+	p, err := s.path(key)
+	if err != nil {
+		return storedObject{}, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return storedObject{}, errObjectNotFound
+	}
+	if err != nil {
+		return storedObject{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return storedObject{}, err
+	}
+	if info.IsDir() {
+		return storedObject{}, errObjectNotFound
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return storedObject{}, err
+	}
+	return storedObject{
+		Key:          key,
+		Size:         info.Size(),
+		ETag:         hex.EncodeToString(hash.Sum(nil)),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// List walks the bucket directory. It reads every key, which is fine for
+// the development data sets this backend is meant for.
+func (s *fsStorage) List(ctx context.Context, prefix, startAfter string, max int) ([]string, bool, error) {
+	dir := filepath.Join(s.root, s.bucket)
+	var keys []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), fsTempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) && key > startAfter {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Strings(keys)
+	if len(keys) > max {
+		return keys[:max], true, nil
+	}
+	return keys, false, nil
+}
+
+// Remove deletes key. Like S3, removing a missing object succeeds.
+func (s *fsStorage) Remove(_ context.Context, key, versionID string) error {
+	if versionID != "" {
+		return errObjectNotFound
+	}
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ready reports whether the bucket directory is usable.
+func (s *fsStorage) ready() error {
+	info, err := os.Stat(filepath.Join(s.root, s.bucket))
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", filepath.Join(s.root, s.bucket))
+	}
+	return nil
+}
+
+// serve answers the signed URLs generated by PresignHeader.
+func (s *fsStorage) serve(c *gin.Context) {
+	bucket, key := c.Param("bucket"), strings.TrimPrefix(c.Param("key"), "/")
+	query := c.Request.URL.Query()
+
+	expires, err := strconv.ParseInt(query.Get(fsExpiresParam), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid signature"})
+		return
+	}
+	want := s.signature(c.Request.Method, bucket, key, query, c.Request.Header)
+	if !hmac.Equal([]byte(want), []byte(query.Get(fsSignatureParam))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing or invalid signature"})
+		return
+	}
+	if s.now().Unix() > expires {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Request has expired"})
+		return
+	}
+	if bucket != s.bucket {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bucket not found"})
+		return
+	}
+	p, err := s.path(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serveFile(c, p, query)
+	case http.MethodPut:
+		s.storeFile(c, p)
+	case http.MethodDelete:
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			utils.LogError("Could not remove %s: %v", p, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove object"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	default:
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Method not allowed"})
+	}
+}
+
+// serveFile answers GET and HEAD, applying response-content-type and
+// response-content-disposition overrides like S3.
+func (s *fsStorage) serveFile(c *gin.Context, p string, query url.Values) {
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if err != nil {
+		utils.LogError("Could not open %s: %v", p, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read object"})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	if v := query.Get("response-content-type"); v != "" {
+		c.Header("Content-Type", v)
+	}
+	if v := query.Get("response-content-disposition"); v != "" {
+		c.Header("Content-Disposition", v)
+	}
+	http.ServeContent(c.Writer, c.Request, filepath.Base(p), info.ModTime(), f)
+}
+
+// storeFile answers PUT. The body is written to a temporary file and
+// renamed into place, so readers never see a partial object. A signed
+// Content-MD5 is checked like S3 does.
+func (s *fsStorage) storeFile(c *gin.Context, p string) {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		utils.LogError("Could not create directory for %s: %v", p, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store object"})
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), fsTempPrefix+"*")
+	if err != nil {
+		utils.LogError("Could not create temporary file for %s: %v", p, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store object"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), c.Request.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		utils.LogError("Could not write %s: %v", p, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store object"})
+		return
+	}
+	sum := hash.Sum(nil)
+	if want := c.GetHeader("Content-MD5"); want != "" && want != base64.StdEncoding.EncodeToString(sum) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-MD5 does not match the body"})
+		return
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		utils.LogError("Could not store %s: %v", p, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store object"})
+		return
+	}
+	c.Header("ETag", `"`+hex.EncodeToString(sum)+`"`)
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFSStorage returns a store in a temporary directory and a server
+// answering its signed URLs.
+func newTestFSStorage(t *testing.T) (*fsStorage, *httptest.Server) {
+	t.Helper()
+	router := gin.New()
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	store, err := newFSStorage(t.TempDir(), "uploads", server.URL+FSRoutePrefix, []byte("test-secret"))
+	require.NoError(t, err)
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete} {
+		router.Handle(method, FSRoutePrefix+"/:bucket/*key", store.serve)
+	}
+	return store, server
+}
+
+// fsRequest sends a request to a URL signed by the fs backend.
+func fsRequest(t *testing.T, method, rawURL, body string, headers http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, rawURL, strings.NewReader(body))
+	require.NoError(t, err)
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestFSStorage_RoundTrip(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	ctx := context.Background()
+
+	headers := http.Header{"Content-Type": {"text/plain"}, "x-amz-meta-owner": {"alice"}}
+	putURL, err := store.PresignPut(ctx, "docs/hello world.txt", headers, time.Minute)
+	require.NoError(t, err)
+
+	resp := fsRequest(t, http.MethodPut, putURL, "hello", http.Header{"Content-Type": {"text/plain"}, "X-Amz-Meta-Owner": {"alice"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"5d41402abc4b2a76b9719d911017c592"`, resp.Header.Get("ETag"))
+
+	info, err := store.Stat(ctx, "docs/hello world.txt", "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, info.Size)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", info.ETag)
+
+	getURL, err := store.PresignGet(ctx, "docs/hello world.txt", time.Minute, url.Values{"response-content-type": {"application/octet-stream"}})
+	require.NoError(t, err)
+	resp = fsRequest(t, http.MethodGet, getURL, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+
+	keys, truncated, err := store.List(ctx, "docs/", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/hello world.txt"}, keys)
+	assert.False(t, truncated)
+
+	require.NoError(t, store.Remove(ctx, "docs/hello world.txt", ""))
+	_, err = store.Stat(ctx, "docs/hello world.txt", "")
+	assert.ErrorIs(t, err, errObjectNotFound)
+	assert.NoError(t, store.Remove(ctx, "docs/hello world.txt", ""))
+}
+
+func TestFSStorage_RejectsBadRequests(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	ctx := context.Background()
+
+	putURL, err := store.PresignPut(ctx, "a.txt", http.Header{"Content-Type": {"text/plain"}}, time.Minute)
+	require.NoError(t, err)
+
+	// Signed header missing or different.
+	resp := fsRequest(t, http.MethodPut, putURL, "x", http.Header{"Content-Type": {"text/html"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Wrong method for the signature.
+	resp = fsRequest(t, http.MethodDelete, putURL, "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Tampered query.
+	resp = fsRequest(t, http.MethodPut, putURL+"&extra=1", "x", http.Header{"Content-Type": {"text/plain"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Expired.
+	store.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expired, err := store.PresignGet(ctx, "a.txt", time.Minute, nil)
+	require.NoError(t, err)
+	store.now = time.Now
+	resp = fsRequest(t, http.MethodGet, expired, "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Signed Content-MD5 that does not match the body.
+	md5Headers := http.Header{"Content-Type": {"text/plain"}, "Content-MD5": {"XUFAKrxLKna5cZ2REBfFkg=="}}
+	putURL, err = store.PresignPut(ctx, "a.txt", md5Headers, time.Minute)
+	require.NoError(t, err)
+	resp = fsRequest(t, http.MethodPut, putURL, "not hello", md5Headers)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, err = store.Stat(ctx, "a.txt", "")
+	assert.ErrorIs(t, err, errObjectNotFound)
+}
+
+func TestFSStorage_Path(t *testing.T) {
+	store := &fsStorage{root: "/data", bucket: "uploads"}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x", "a//b", `a\b`, "./a"} {
+		_, err := store.path(key)
+		assert.Error(t, err, key)
+	}
+	p, err := store.path("a/b.txt")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(p, "b.txt"))
+}
+
+func TestFSStorage_ListPaging(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	ctx := context.Background()
+	for _, key := range []string{"p/c", "p/a", "p/b", "q/a"} {
+		u, err := store.PresignPut(ctx, key, nil, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, fsRequest(t, http.MethodPut, u, key, nil).StatusCode)
+	}
+
+	keys, truncated, err := store.List(ctx, "p/", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p/a", "p/b"}, keys)
+	assert.True(t, truncated)
+
+	keys, truncated, err = store.List(ctx, "p/", "p/b", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p/c"}, keys)
+	assert.False(t, truncated)
+}
+
+func TestPresignHandler_FSBackend(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	fsStore = store
+	signer = newSigner()
+	defer func() {
+		fsStore = nil
+		setupTestEnvironment()
+	}()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/confirm", confirmHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=note.txt&type=text/plain", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, strings.HasPrefix(resp.URL, store.baseURL+"/uploads/note.txt?"), resp.URL)
+	assert.Equal(t, store.baseURL+"/uploads/note.txt", resp.PublicURL)
+
+	upload := fsRequest(t, http.MethodPut, resp.URL, "hi", http.Header{"Content-Type": {"text/plain"}})
+	require.Equal(t, http.StatusOK, upload.StatusCode)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/confirm?filename=note.txt", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"size":2`)
+}
+
+func TestFSStorage_RangeProbe(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	ctx := context.Background()
+	u, err := store.PresignPut(ctx, "movie.mp4", nil, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, fsRequest(t, http.MethodPut, u, "0123456789", nil).StatusCode)
+
+	probe, err := store.PresignHeader(ctx, http.MethodHead, store.bucket, "movie.mp4", time.Minute, nil, http.Header{"Range": {RangeProbe}})
+	require.NoError(t, err)
+
+	resp := fsRequest(t, http.MethodHead, probe.String(), "", http.Header{"Range": {RangeProbe}})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	assert.Equal(t, "bytes 0-0/10", resp.Header.Get("Content-Range"))
+
+	// The Range header is signed, so the probe URL is no use without it.
+	resp = fsRequest(t, http.MethodHead, probe.String(), "", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
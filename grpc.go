@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mirago/miraio/proto/presignpb"
+	"github.com/mirago/miraio/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// presignServer implements presignpb.PresignServiceServer on top of the same
+// prepareUpload/Signer path as the HTTP handler.
+type presignServer struct {
+	presignpb.UnimplementedPresignServiceServer
+}
+
+// Presign mirrors GET /presign.
+func (presignServer) Presign(ctx context.Context, req *presignpb.PresignRequest) (*presignpb.PresignResponse, error) {
+	params := make(url.Values)
+	for k, v := range req.GetMetadata() {
+		params.Set(metaParamPrefix+k, v)
+	}
+	for k, v := range req.GetTags() {
+		params.Set(tagParamPrefix+k, v)
+	}
+
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:    req.GetFilename(),
+		ContentType: req.GetContentType(),
+		Params:      params,
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// The response does not list signed headers, so a client could not know
+	// to send MIRAIO_DEFAULT_CACHE_CONTROL and the upload would fail. gRPC
+	// uploads are stored without it; serveGRPC warns about this at startup.
+	reqParams.Del("Cache-Control")
+
+	if requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	expiry := uploadExpiryFor(key, reqParams.Get("Content-Type"))
+	scope := grpcScope(ctx)
+	b := pickScopedBackend(scope)
+	if scope != nil && !scope.allows(b.signer.Bucket, key) {
+		return nil, status.Error(codes.PermissionDenied, errOutOfScope.Error())
+	}
+	// pick skips backends without their bucket, so this only fails when
+	// none is left or the token is bound to a missing one.
+	if !bucketWatch.available(b) {
+		return nil, status.Error(codes.Unavailable, "Bucket missing: "+b.signer.Bucket)
+	}
+	if key, err = resolveCollision(key, existsOn(ctx, b)); err != nil {
+		if errors.Is(err, errKeyExists) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, grpcBackendError(err)
+	}
+	now := time.Now()
+	signed, public, err := b.signer.PutURLWithHeaders(ctx, key, http.Header(reqParams), expiry)
+	if err != nil {
+		return nil, grpcBackendError(err)
+	}
+	webhook.notifyPresign(http.MethodPut, req.GetFilename(), key, reqParams.Get("Content-Type"), peerIP(ctx))
+
+	resp := &presignpb.PresignResponse{
+		Url:           signed,
+		PublicUrl:     public,
+		ExpirySeconds: int64(expiry.Seconds()),
+		ExpiresAt:     formatExpiresAt(signed, now, expiry),
+		Key:           key,
+	}
+	if backends.multi() {
+		resp.Ref = b.ref(key)
+	}
+	return resp, nil
+}
+
+// grpcScopeKey is the context key holding the verified token's claims.
+type grpcScopeKey struct{}
+
+// grpcScope returns the scope of the call's token, or nil when the call is
+// not limited to one.
+func grpcScope(ctx context.Context) *scopeClaims {
+	s, _ := ctx.Value(grpcScopeKey{}).(*scopeClaims)
+	return s
+}
+
+// authenticateGRPC is the gRPC counterpart of jwtVerifier.middleware. With
+// JWT auth configured, calls must carry "authorization: Bearer <token>"
+// metadata with a valid token, whose scope Presign then enforces, or the
+// API key in "x-api-key" or the bearer metadata.
+func authenticateGRPC(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if jwtAuth == nil {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	token, _ := strings.CutPrefix(first("authorization"), "Bearer ")
+	if matchesAPIKey(first("x-api-key")) || matchesAPIKey(token) {
+		return handler(ctx, req)
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or missing token")
+	}
+	claims, err := jwtAuth.verify(ctx, token)
+	if err != nil {
+		utils.LogWarning("Rejected gRPC token from %s: %v", peerIP(ctx), err)
+		return nil, status.Error(codes.Unauthenticated, "Invalid or missing token")
+	}
+	return handler(context.WithValue(ctx, grpcScopeKey{}, claims), req)
+}
+
+// filterGRPC applies the MIRAIO_ALLOW_CIDRS and MIRAIO_DENY_CIDRS rules in
+// effect to the peer address. gRPC has no forwarded-for convention, so
+// MIRAIO_TRUST_PROXY does not apply. Peers without an IP address are
+// refused while a rule is configured, as over HTTP.
+func filterGRPC(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if f := currentConfig().ipFilter; f.enabled() {
+		addr, err := netip.ParseAddr(peerIP(ctx))
+		if err != nil || !f.allowed(addr) {
+			utils.LogDebug("Rejected gRPC call from %q", peerIP(ctx))
+			return nil, status.Error(codes.PermissionDenied, "Forbidden")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// limitGRPC is the gRPC counterpart of concurrency.middleware: calls share
+// the MIRAIO_MAX_CONCURRENCY slots with HTTP requests and fail with
+// ResourceExhausted when none is free.
+func limitGRPC(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	release, ok := concurrency.acquire()
+	if !ok {
+		return nil, status.Error(codes.ResourceExhausted, "Too many concurrent requests")
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+// peerIP returns the address of the gRPC client, without the port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// grpcBackendError maps a failed MinIO call to a gRPC status, following the
+// same transient/auth/permanent split as respondBackendError.
+func grpcBackendError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case isTransientError(err):
+		utils.LogError("Storage backend unavailable during gRPC Presign: %v", err)
+		return status.Error(codes.Unavailable, "Storage backend unavailable")
+	case isAuthError(err):
+		utils.LogError("MinIO rejected the server's credentials during gRPC Presign; check the access key, secret key and bucket policy: %v", err)
+		return status.Error(codes.Internal, "Storage authentication or configuration error")
+	default:
+		utils.LogError("Could not generate presigned URL: %v", err)
+		return status.Error(codes.Internal, "Could not generate presigned URL")
+	}
+}
+
+// newGRPCServer builds the gRPC server with all services registered.
+func newGRPCServer() *grpc.Server {
+	// As over HTTP, rejected callers never take a concurrency slot.
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(filterGRPC, authenticateGRPC, limitGRPC))
+	presignpb.RegisterPresignServiceServer(server, presignServer{})
+	return server
+}
+
+// serveGRPC listens on port and serves gRPC until the listener fails.
+func serveGRPC(port string) {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		utils.LogFatal("Error listening for gRPC on %s: %v", port, err)
+	}
+	utils.LogInfo("gRPC server running on %s", port)
+	if defaultCacheControl != "" {
+		utils.LogWarning("MIRAIO_DEFAULT_CACHE_CONTROL is not applied to gRPC uploads: the response cannot tell clients to send Cache-Control")
+	}
+	utils.LogFatal("Error serving gRPC: %v", newGRPCServer().Serve(lis))
+}
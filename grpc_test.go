@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirago/miraio/proto/presignpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestGRPCClient(t *testing.T) presignpb.PresignServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := newGRPCServer()
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return presignpb.NewPresignServiceClient(conn)
+}
+
+func TestGRPCPresign(t *testing.T) {
+	setupTestEnvironment()
+	client := newTestGRPCClient(t)
+
+	t.Run("Valid request", func(t *testing.T) {
+		resp, err := client.Presign(context.Background(), &presignpb.PresignRequest{
+			Filename:    "test.txt",
+			ContentType: "text/plain",
+			Metadata:    map[string]string{"owner": "alice"},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, resp.GetUrl(), "/test-bucket/test.txt?")
+		assert.Contains(t, resp.GetUrl(), "x-amz-meta-owner")
+		assert.Equal(t, "http://localhost:9000/test-bucket/test.txt", resp.GetPublicUrl())
+		expiresAt, err := time.Parse(time.RFC3339, resp.GetExpiresAt())
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Duration(resp.GetExpirySeconds())*time.Second), expiresAt, 2*time.Second)
+	})
+
+	t.Run("Default Cache-Control not signed", func(t *testing.T) {
+		defaultCacheControl = "public, max-age=3600"
+		defer func() { defaultCacheControl = "" }()
+
+		resp, err := client.Presign(context.Background(), &presignpb.PresignRequest{Filename: "test.txt", ContentType: "text/plain"})
+		require.NoError(t, err)
+		assert.NotContains(t, strings.ToLower(resp.GetUrl()), "cache-control")
+	})
+
+	t.Run("Missing content type", func(t *testing.T) {
+		_, err := client.Presign(context.Background(), &presignpb.PresignRequest{Filename: "test.txt"})
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestGRPCPresign_JWT(t *testing.T) {
+	setupTestEnvironment()
+	jwtAuth = newJWTVerifier(testJWTSecret, "")
+	apiKey = "secret-key"
+	defer func() { jwtAuth, apiKey = nil, "" }()
+	client := newTestGRPCClient(t)
+
+	album := signHS256(t, scopeClaims{Prefix: "album/", RegisteredClaims: expiresIn(time.Minute)})
+	for _, tc := range []struct {
+		name     string
+		md       []string
+		filename string
+		want     codes.Code
+	}{
+		{"No token", nil, "album/a.txt", codes.Unauthenticated},
+		{"Invalid token", []string{"authorization", "Bearer not-a-jwt"}, "album/a.txt", codes.Unauthenticated},
+		{"In scope", []string{"authorization", "Bearer " + album}, "album/a.txt", codes.OK},
+		{"Out of scope", []string{"authorization", "Bearer " + album}, "other/a.txt", codes.PermissionDenied},
+		{"API key", []string{"x-api-key", "secret-key"}, "other/a.txt", codes.OK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), tc.md...)
+			_, err := client.Presign(ctx, &presignpb.PresignRequest{Filename: tc.filename, ContentType: "text/plain"})
+			assert.Equal(t, tc.want, status.Code(err), err)
+		})
+	}
+}
+
+func TestGRPCPresign_ConcurrencyLimit(t *testing.T) {
+	setupTestEnvironment()
+	defer func(l *concurrencyLimiter) { concurrency = l }(concurrency)
+	concurrency = newConcurrencyLimiter(1)
+	client := newTestGRPCClient(t)
+	req := &presignpb.PresignRequest{Filename: "test.txt", ContentType: "text/plain"}
+
+	release, ok := concurrency.acquire()
+	require.True(t, ok)
+	_, err := client.Presign(context.Background(), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	release()
+	_, err = client.Presign(context.Background(), req)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, concurrency.inFlight.Load())
+}
+
+func TestGRPCPresign_BucketMissing(t *testing.T) {
+	setupTestEnvironment()
+	g := newBucketGuard(func(context.Context) ([]*backend, error) { return []*backend{backends.primary()}, nil })
+	g.checkOnce(context.Background())
+	saved := bucketWatch
+	bucketWatch = g
+	t.Cleanup(func() { bucketWatch = saved })
+	client := newTestGRPCClient(t)
+
+	_, err := client.Presign(context.Background(), &presignpb.PresignRequest{Filename: "test.txt", ContentType: "text/plain"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "test-bucket")
+}
+
+func TestFilterGRPC(t *testing.T) {
+	setupTestEnvironment()
+	deny, err := parseCIDRs("203.0.113.0/24")
+	require.NoError(t, err)
+	withConfig(t, func(cfg *reloadableConfig) { cfg.ipFilter = ipFilter{deny: deny} })
+
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(addr net.Addr) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+		_, err := filterGRPC(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		return err
+	}
+	assert.NoError(t, call(&net.TCPAddr{IP: net.ParseIP("198.51.100.7"), Port: 5555}))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 5555})))
+
+	// Through the server: bufconn peers have no IP address.
+	client := newTestGRPCClient(t)
+	_, err = client.Presign(context.Background(), &presignpb.PresignRequest{Filename: "test.txt", ContentType: "text/plain"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
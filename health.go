@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// ReadinessTimeout bounds the MinIO probe behind /readyz.
+const ReadinessTimeout = 2 * time.Second
+
+// DefaultHealthCacheTTL is how long a readiness result is reused when
+// MIRAIO_HEALTH_CACHE_MS is unset.
+const DefaultHealthCacheTTL = time.Second
+
+// readiness is the outcome of one MinIO probe.
+type readiness struct {
+	status    int
+	body      gin.H
+	checkedAt time.Time
+}
+
+// healthCache shares readiness probes between callers so that frequent
+// /readyz polling does not turn into one MinIO request per probe. A result
+// is served as-is for ttl; for another ttl it is still served while a
+// refresh runs in the background; after that callers wait for a fresh
+// probe. No caller ever sees a result older than twice the ttl.
+type healthCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	last       *readiness
+	refreshing bool
+	probe      func(ctx context.Context) readiness
+	now        func() time.Time
+}
+
+var health = newHealthCache(DefaultHealthCacheTTL, probeReadiness)
+
+func newHealthCache(ttl time.Duration, probe func(context.Context) readiness) *healthCache {
+	return &healthCache{ttl: ttl, probe: probe, now: time.Now}
+}
+
+// get returns a readiness result no older than twice the ttl.
+func (h *healthCache) get(ctx context.Context) readiness {
+	if h.ttl <= 0 {
+		return h.probe(ctx)
+	}
+
+	h.mu.Lock()
+	if h.last != nil {
+		age := h.now().Sub(h.last.checkedAt)
+		if age < h.ttl {
+			last := *h.last
+			h.mu.Unlock()
+			return last
+		}
+		if age < 2*h.ttl {
+			if !h.refreshing {
+				h.refreshing = true
+				go h.refresh()
+			}
+			last := *h.last
+			h.mu.Unlock()
+			return last
+		}
+	}
+	h.mu.Unlock()
+
+	result := h.probe(ctx)
+	h.store(result)
+	return result
+}
+
+func (h *healthCache) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), ReadinessTimeout)
+	defer cancel()
+	h.store(h.probe(ctx))
+}
+
+func (h *healthCache) store(result readiness) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.refreshing = false
+	if h.last == nil || !result.checkedAt.Before(h.last.checkedAt) {
+		h.last = &result
+	}
+}
+
+// backendSkipped is the /readyz status of a backend that bucketWatch found
+// without its bucket. It receives no uploads, so it is reported without
+// making the server unready.
+const backendSkipped = "Skipped: bucket missing"
+
+// probeReadiness checks that MinIO is reachable and the bucket exists, for
+// every configured backend. With several backends the body lists each
+// one's status.
+func probeReadiness(ctx context.Context) readiness {
+	ctx, cancel := context.WithTimeout(ctx, ReadinessTimeout)
+	defer cancel()
+
+	now := time.Now()
+	if fsStore != nil {
+		if err := fsStore.ready(); err != nil {
+			utils.LogWarning("Readiness check failed: %v", err)
+			return readiness{http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "Storage backend unavailable"}, now}
+		}
+		return readiness{http.StatusOK, gin.H{"status": "ok"}, now}
+	}
+
+	all := backends.all()
+	problems := make([]string, len(all))
+	var wg sync.WaitGroup
+	for i, b := range all {
+		if len(all) > 1 && !bucketWatch.available(b) {
+			problems[i] = backendSkipped
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			problems[i] = probeBackend(ctx, b)
+		}()
+	}
+	wg.Wait()
+
+	if len(all) == 1 {
+		if problems[0] != "" {
+			return readiness{http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": problems[0]}, now}
+		}
+		return readiness{http.StatusOK, gin.H{"status": "ok"}, now}
+	}
+	statuses := make(map[string]string, len(all))
+	failure, usable := "", false
+	for i, b := range all {
+		switch problems[i] {
+		case "":
+			statuses[b.name] = "ok"
+			usable = true
+		case backendSkipped:
+			statuses[b.name] = backendSkipped
+		default:
+			statuses[b.name] = problems[i]
+			if failure == "" {
+				failure = problems[i]
+			}
+		}
+	}
+	if failure == "" && !usable {
+		failure = "Bucket missing"
+	}
+	if failure != "" {
+		return readiness{http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": failure, "backends": statuses}, now}
+	}
+	return readiness{http.StatusOK, gin.H{"status": "ok", "backends": statuses}, now}
+}
+
+// probeBackend returns why b cannot serve requests, or "" when its bucket
+// is reachable.
+func probeBackend(ctx context.Context, b *backend) string {
+	exists, err := b.client.BucketExists(ctx, b.signer.Bucket)
+	if err != nil {
+		utils.LogWarning("Readiness check of backend %s failed: %v", b.name, err)
+		return "Storage backend unavailable"
+	}
+	if !exists {
+		utils.LogWarning("Readiness check of backend %s failed: bucket %s does not exist", b.name, b.signer.Bucket)
+		return "Bucket missing"
+	}
+	return ""
+}
+
+// livezHandler reports that the process is up. It never touches MinIO, so a
+// backend outage does not get the pod restarted.
+func livezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler reports whether the server can serve requests: MinIO must be
+// reachable and the bucket must exist. Results are shared through health.
+func readyzHandler(c *gin.Context) {
+	result := health.get(c.Request.Context())
+	c.JSON(result.status, result.body)
+}
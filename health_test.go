@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthEndpoints_BackendDown(t *testing.T) {
+	setupTestEnvironment()
+	defer setupTestEnvironment()
+	health.ttl = 0
+	defer func() { health.ttl = DefaultHealthCacheTTL }()
+
+	var err error
+	minioClient, err = minio.New("127.0.0.1:1", &minio.Options{
+		Creds:      credentials.NewStaticV4("minio", "minio123", ""),
+		Region:     minioRegion,
+		MaxRetries: 1,
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/livez", livezHandler)
+	router.GET("/readyz", readyzHandler)
+
+	req, err := http.NewRequest("GET", "/livez", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	req, err = http.NewRequest("GET", "/readyz", nil)
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "unavailable")
+}
+
+func TestHealthCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var probes atomic.Int32
+	up := true
+	refreshed := make(chan struct{}, 1)
+	cache := newHealthCache(time.Second, func(context.Context) readiness {
+		probes.Add(1)
+		defer func() {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		}()
+		if up {
+			return readiness{status: http.StatusOK, checkedAt: now}
+		}
+		return readiness{status: http.StatusServiceUnavailable, checkedAt: now}
+	})
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.Equal(t, http.StatusOK, cache.get(ctx).status)
+	<-refreshed
+	assert.Equal(t, http.StatusOK, cache.get(ctx).status)
+	assert.EqualValues(t, 1, probes.Load(), "fresh results are reused")
+
+	// Past the ttl the cached result is served while a refresh runs.
+	up = false
+	now = now.Add(1500 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, cache.get(ctx).status)
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale result was not refreshed")
+	}
+	require.Eventually(t, func() bool {
+		return cache.get(ctx).status == http.StatusServiceUnavailable
+	}, time.Second, 5*time.Millisecond)
+
+	// Past twice the ttl callers wait for a fresh probe.
+	up = true
+	now = now.Add(3 * time.Second)
+	assert.Equal(t, http.StatusOK, cache.get(ctx).status)
+}
+
+func TestHealthCache_Disabled(t *testing.T) {
+	var probes int
+	cache := newHealthCache(0, func(context.Context) readiness {
+		probes++
+		return readiness{status: http.StatusOK, checkedAt: time.Now()}
+	})
+	cache.get(context.Background())
+	cache.get(context.Background())
+	assert.Equal(t, 2, probes)
+}
+
+func TestProbeReadiness_MultipleBackends(t *testing.T) {
+	setupTestEnvironment()
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test-bucket/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	eu := testBackend("eu", 1)
+	withBackends(t, 1, eu)
+
+	result := probeReadiness(context.Background())
+	assert.Equal(t, http.StatusServiceUnavailable, result.status)
+	assert.Equal(t, "Bucket missing", result.body["error"])
+	assert.Equal(t, map[string]string{"default": "ok", "eu": "Bucket missing"}, result.body["backends"])
+
+	t.Run("Skipped by the bucket check", func(t *testing.T) {
+		g := newBucketGuard(func(context.Context) ([]*backend, error) { return []*backend{eu}, nil })
+		g.checkOnce(context.Background())
+		saved := bucketWatch
+		bucketWatch = g
+		t.Cleanup(func() { bucketWatch = saved })
+
+		result := probeReadiness(context.Background())
+		assert.Equal(t, http.StatusOK, result.status)
+		assert.Equal(t, map[string]string{"default": "ok", "eu": backendSkipped}, result.body["backends"])
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		client, err := minio.New("127.0.0.1:1", &minio.Options{
+			Creds:      credentials.NewStaticV4("minio", "minio123", ""),
+			Region:     minioRegion,
+			MaxRetries: 1,
+		})
+		require.NoError(t, err)
+		down := testBackend("us", 1)
+		down.client = client
+		withBackends(t, 1, down)
+
+		result := probeReadiness(context.Background())
+		assert.Equal(t, http.StatusServiceUnavailable, result.status)
+		assert.Equal(t, map[string]string{"default": "ok", "us": "Storage backend unavailable"}, result.body["backends"])
+	})
+}
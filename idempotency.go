@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader lets clients retry a presign request and get the
+// original response back instead of a new URL or key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses served from the cache.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyTTL is how long responses are kept when
+// MIRAIO_IDEMPOTENCY_TTL is unset.
+const DefaultIdempotencyTTL = 10 * time.Minute
+
+// IdempotencyCacheSize bounds the number of remembered keys; the least
+// recently used are dropped first.
+const IdempotencyCacheSize = 10000
+
+// MaxIdempotencyKeyLength rejects keys that are clearly not UUIDs or
+// similar tokens.
+const MaxIdempotencyKeyLength = 255
+
+// idempotency remembers responses by Idempotency-Key. Nil disables the
+// header (MIRAIO_IDEMPOTENCY_TTL=0).
+var idempotency = newIdempotencyCache(DefaultIdempotencyTTL, IdempotencyCacheSize)
+
+// idempotencyState is the outcome of idempotencyCache.begin.
+type idempotencyState int
+
+const (
+	// idempotencyNew: run the handler, then finish or abandon the key.
+	idempotencyNew idempotencyState = iota
+	// idempotencyReplay: answer with the cached response.
+	idempotencyReplay
+	// idempotencyInProgress: the first request is still running.
+	idempotencyInProgress
+	// idempotencyMismatch: the key was used for a different request.
+	idempotencyMismatch
+)
+
+// idempotencyEntry is one remembered key. Until done is set the first
+// request is still being handled.
+type idempotencyEntry struct {
+	key         string
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyCache is an LRU of responses keyed by Idempotency-Key.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration, size int) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// begin looks key up. For a new or expired key it records a pending entry
+// for fingerprint and returns idempotencyNew; for a finished one it returns
+// a copy of the entry to replay.
+func (c *idempotencyCache) begin(key, fingerprint string) (idempotencyEntry, idempotencyState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*idempotencyEntry)
+		switch {
+		case !now.Before(entry.expires):
+			c.order.Remove(el)
+			delete(c.entries, key)
+		case entry.fingerprint != fingerprint:
+			return idempotencyEntry{}, idempotencyMismatch
+		case !entry.done:
+			return idempotencyEntry{}, idempotencyInProgress
+		default:
+			c.order.MoveToFront(el)
+			return *entry, idempotencyReplay
+		}
+	}
+
+	entry := &idempotencyEntry{key: key, fingerprint: fingerprint, expires: now.Add(c.ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*idempotencyEntry).key)
+	}
+	return idempotencyEntry{}, idempotencyNew
+}
+
+// finish stores the response for a pending key. The TTL counts from here.
+func (c *idempotencyCache) finish(key string, status int, contentType string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	entry := el.Value.(*idempotencyEntry)
+	entry.done = true
+	entry.status = status
+	entry.contentType = contentType
+	entry.body = body
+	entry.expires = c.now().Add(c.ttl)
+}
+
+// abandon forgets a pending key, so a retry runs the handler again.
+func (c *idempotencyCache) abandon(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok && !el.Value.(*idempotencyEntry).done {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// sweep drops expired keys, finished or not; begin would treat them as new
+// anyway.
+func (c *idempotencyCache) sweep(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := 0
+	for key, el := range c.entries {
+		if !now.Before(el.Value.(*idempotencyEntry).expires) {
+			c.order.Remove(el)
+			delete(c.entries, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// captureWriter passes the response through while keeping a copy of the
+// body.
+type captureWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestPrincipal identifies the caller for requestFingerprint: the API
+// key, the subject and scope of a token, or no one. Replays run before the
+// handler's scope check, so a response is only replayed to a caller allowed
+// to see it.
+func requestPrincipal(c *gin.Context) string {
+	if validAPIKey(c) {
+		return "api-key"
+	}
+	if s := requestScope(c); s != nil {
+		return "token:" + s.Subject + "\x00" + s.Bucket + "\x00" + s.Prefix
+	}
+	return ""
+}
+
+// requestFingerprint hashes everything that shapes the response: caller,
+// method, path, query, body and the headers handlers read. The body is
+// restored for the handler.
+func requestFingerprint(c *gin.Context) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, requestPrincipal(c)+"\n")
+	io.WriteString(h, c.Request.Method+"\n"+c.Request.URL.Path+"\n"+c.Request.URL.Query().Encode()+"\n")
+	for _, name := range []string{SSECustomerKeyHeader, PublicBaseHeader} {
+		io.WriteString(h, c.GetHeader(name)+"\n")
+	}
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// idempotent replays the first successful response for a repeated
+// Idempotency-Key, so retried presign requests return the same key and URL
+// even when keys are generated server-side. Errors are not remembered, so a
+// failed request can be retried with the same key.
+func idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if idempotency == nil || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > MaxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key too long"})
+			return
+		}
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			respondBindError(c, err, "Could not read request body")
+			c.Abort()
+			return
+		}
+
+		entry, state := idempotency.begin(key, fingerprint)
+		switch state {
+		case idempotencyReplay:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(entry.status, entry.contentType, entry.body)
+			c.Abort()
+			return
+		case idempotencyInProgress:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			return
+		case idempotencyMismatch:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			return
+		}
+
+		w := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		finished := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if !finished {
+				idempotency.abandon(key)
+			}
+		}()
+
+		c.Next()
+
+		if status := w.Status(); status >= 200 && status < 300 {
+			idempotency.finish(key, status, w.Header().Get("Content-Type"), w.buf.Bytes())
+			finished = true
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idempotencyRouter serves a handler that returns a new key on every call,
+// like presigning with a {uuid} key template.
+func idempotencyRouter(t *testing.T, status int) (*gin.Engine, *atomic.Int64) {
+	t.Helper()
+	previous := idempotency
+	idempotency = newIdempotencyCache(time.Minute, 10)
+	t.Cleanup(func() { idempotency = previous })
+
+	var calls atomic.Int64
+	router := gin.New()
+	router.POST("/presign", idempotent(), func(c *gin.Context) {
+		n := calls.Add(1)
+		c.JSON(status, gin.H{"key": fmt.Sprintf("upload-%d", n)})
+	})
+	return router, &calls
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/presign", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotent_ReplaysResponse(t *testing.T) {
+	router, calls := idempotencyRouter(t, http.StatusOK)
+
+	first := postWithKey(router, "k1", `{"filename":"a.txt"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	retry := postWithKey(router, "k1", `{"filename":"a.txt"}`)
+	require.Equal(t, http.StatusOK, retry.Code)
+
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.EqualValues(t, 1, calls.Load())
+
+	other := postWithKey(router, "k2", `{"filename":"a.txt"}`)
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+	postWithKey(router, "", `{"filename":"a.txt"}`)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestIdempotent_DifferentRequestSameKey(t *testing.T) {
+	router, _ := idempotencyRouter(t, http.StatusOK)
+
+	require.Equal(t, http.StatusOK, postWithKey(router, "k1", `{"filename":"a.txt"}`).Code)
+	w := postWithKey(router, "k1", `{"filename":"b.txt"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestIdempotent_OtherCallerSameKey(t *testing.T) {
+	previous := idempotency
+	idempotency = newIdempotencyCache(time.Minute, 10)
+	t.Cleanup(func() { idempotency = previous })
+
+	var calls atomic.Int64
+	router := gin.New()
+	router.POST("/presign", newJWTVerifier(testJWTSecret, "").middleware(), idempotent(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"key": fmt.Sprintf("upload-%d", calls.Add(1))})
+	})
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/presign", strings.NewReader(`{"filename":"a.txt"}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	alice := signHS256(t, scopeClaims{Prefix: "alice/", RegisteredClaims: jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}})
+	mallory := signHS256(t, scopeClaims{Prefix: "mallory/", RegisteredClaims: jwt.RegisteredClaims{Subject: "mallory", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}})
+	require.Equal(t, http.StatusOK, post(alice).Code)
+	assert.Equal(t, http.StatusOK, post(alice).Code)
+	w := post(mallory)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotent_ErrorsAreNotCached(t *testing.T) {
+	router, calls := idempotencyRouter(t, http.StatusServiceUnavailable)
+
+	postWithKey(router, "k1", `{}`)
+	postWithKey(router, "k1", `{}`)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestIdempotencyCache_InProgressAndExpiry(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute, 10)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, state := cache.begin("k", "fp")
+	assert.Equal(t, idempotencyNew, state)
+	_, state = cache.begin("k", "fp")
+	assert.Equal(t, idempotencyInProgress, state)
+
+	cache.finish("k", http.StatusOK, "application/json", []byte(`{}`))
+	entry, state := cache.begin("k", "fp")
+	assert.Equal(t, idempotencyReplay, state)
+	assert.Equal(t, `{}`, string(entry.body))
+
+	now = now.Add(time.Minute)
+	_, state = cache.begin("k", "fp")
+	assert.Equal(t, idempotencyNew, state)
+
+	cache.abandon("k")
+	_, state = cache.begin("k", "other")
+	assert.Equal(t, idempotencyNew, state)
+}
+
+func TestIdempotencyCache_EvictsOldest(t *testing.T) {
+	cache := newIdempotencyCache(time.Minute, 2)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.begin(key, "fp")
+		cache.finish(key, http.StatusOK, "", nil)
+	}
+	_, state := cache.begin("a", "fp")
+	assert.Equal(t, idempotencyNew, state)
+	_, state = cache.begin("c", "fp")
+	assert.Equal(t, idempotencyReplay, state)
+}
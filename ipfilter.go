@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// ipFilter restricts which client addresses may use the service. Deny
+// entries win over allow entries; an empty allow list admits everyone not
+// denied.
+type ipFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// parseCIDRs reads a comma-separated list of CIDR blocks. Bare addresses
+// are taken as single-host blocks.
+func parseCIDRs(v string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// trustProxyFormat describes the MIRAIO_TRUST_PROXY value in errors.
+const trustProxyFormat = "list the proxy addresses or CIDR blocks, e.g. 10.0.0.0/8,192.0.2.7"
+
+// parseTrustProxy reads MIRAIO_TRUST_PROXY. "false" trusts no proxy, as
+// does an empty value. "true" is refused rather than taken to mean every
+// peer, since any client could then forge its address.
+func parseTrustProxy(v string) ([]netip.Prefix, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "false":
+		return nil, nil
+	case "true":
+		return nil, errors.New(`"true" would accept forwarded addresses from any client; ` + trustProxyFormat)
+	}
+	proxies, err := parseCIDRs(v)
+	if err != nil {
+		return nil, fmt.Errorf("%v; %s", err, trustProxyFormat)
+	}
+	return proxies, nil
+}
+
+// enabled reports whether any rule is configured.
+func (f ipFilter) enabled() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// allowed applies the deny list, then the allow list, to addr.
+func (f ipFilter) allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range f.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, p := range f.allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIPs rejects requests from addresses f does not allow with 403.
+// Requests whose client address cannot be determined, such as those over a
+// Unix socket, are rejected too.
+func filterIPs(f ipFilter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addr, err := netip.ParseAddr(c.ClientIP())
+		if err != nil || !f.allowed(addr) {
+			utils.LogDebug("Rejected request from %q to %s", c.ClientIP(), c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// filterConfiguredIPs applies the IP filter of the settings in effect, which
+// may change on reload.
+func filterConfiguredIPs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if f := currentConfig().ipFilter; f.enabled() {
+			filterIPs(f)(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// trustProxies controls whether X-Forwarded-For and X-Real-IP decide the
+// client address. Gin trusts them from any peer by default, which would let
+// clients spoof their address, so they are only read from peers in proxies
+// (MIRAIO_TRUST_PROXY). Gin walks X-Forwarded-For from the right and stops
+// at the first address that is not a trusted proxy, so entries a client
+// prepends itself are never reached.
+func trustProxies(router *gin.Engine, proxies []netip.Prefix) error {
+	if len(proxies) == 0 {
+		return router.SetTrustedProxies(nil)
+	}
+	cidrs := make([]string, len(proxies))
+	for i, p := range proxies {
+		cidrs[i] = p.String()
+	}
+	return router.SetTrustedProxies(cidrs)
+}
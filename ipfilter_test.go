@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := parseCIDRs(" 10.0.0.0/8, 192.168.1.7 ,2001:db8::/32,, ::1")
+	require.NoError(t, err)
+	var got []string
+	for _, p := range prefixes {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.7/32", "2001:db8::/32", "::1/128"}, got)
+
+	for _, v := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0/8"} {
+		_, err := parseCIDRs(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestParseTrustProxy(t *testing.T) {
+	for _, v := range []string{"", "false", "FALSE"} {
+		proxies, err := parseTrustProxy(v)
+		require.NoError(t, err, v)
+		assert.Empty(t, proxies, v)
+	}
+
+	proxies, err := parseTrustProxy("10.9.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.9.0.0/16")}, proxies)
+
+	for _, v := range []string{"true", "yes"} {
+		_, err := parseTrustProxy(v)
+		require.Error(t, err, v)
+		assert.Contains(t, err.Error(), "list the proxy addresses or CIDR blocks", v)
+	}
+}
+
+func TestFilterIPs(t *testing.T) {
+	allow, err := parseCIDRs("10.0.0.0/8,2001:db8::/32")
+	require.NoError(t, err)
+	deny, err := parseCIDRs("10.1.0.0/16,2001:db8:bad::/48")
+	require.NoError(t, err)
+
+	proxies, err := parseCIDRs("10.9.0.0/16")
+	require.NoError(t, err)
+	newRouter := func(trustProxy bool) *gin.Engine {
+		router := gin.New()
+		var trusted []netip.Prefix
+		if trustProxy {
+			trusted = proxies
+		}
+		require.NoError(t, trustProxies(router, trusted))
+		router.Use(filterIPs(ipFilter{allow: allow, deny: deny}))
+		router.GET("/livez", livezHandler)
+		return router
+	}
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		trustProxy bool
+		want       int
+	}{
+		{"IPv4 allowed", "10.2.3.4:5000", "", false, http.StatusOK},
+		{"IPv4 outside allow list", "192.0.2.1:5000", "", false, http.StatusForbidden},
+		{"IPv4 denied inside allowed range", "10.1.2.3:5000", "", false, http.StatusForbidden},
+		{"IPv4-mapped IPv6", "[::ffff:10.2.3.4]:5000", "", false, http.StatusOK},
+		{"IPv6 allowed", "[2001:db8:1::5]:5000", "", false, http.StatusOK},
+		{"IPv6 denied", "[2001:db8:bad::5]:5000", "", false, http.StatusForbidden},
+		{"IPv6 outside allow list", "[2001:db9::1]:5000", "", false, http.StatusForbidden},
+		{"Forwarded header ignored by default", "192.0.2.1:5000", "10.2.3.4", false, http.StatusForbidden},
+		{"Forwarded header cannot spoof", "10.2.3.4:5000", "10.1.2.3", false, http.StatusOK},
+		{"Forwarded header trusted", "10.9.0.1:5000", "10.2.3.4", true, http.StatusOK},
+		{"Forwarded denied when trusted", "10.9.0.1:5000", "10.1.2.3", true, http.StatusForbidden},
+		{"Forged header from untrusted peer", "192.0.2.1:5000", "10.2.3.4", true, http.StatusForbidden},
+		{"Forged entry before the proxy's", "10.9.0.1:5000", "10.2.3.4, 192.0.2.1", true, http.StatusForbidden},
+		{"Unknown client address", "@", "", false, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/livez", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			w := httptest.NewRecorder()
+			newRouter(tc.trustProxy).ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestIPFilter_DenyOnly(t *testing.T) {
+	deny, err := parseCIDRs("203.0.113.0/24")
+	require.NoError(t, err)
+	f := ipFilter{deny: deny}
+
+	router := gin.New()
+	router.Use(filterIPs(f))
+	router.GET("/livez", livezHandler)
+
+	for addr, want := range map[string]int{"203.0.113.9:1": http.StatusForbidden, "198.51.100.1:1": http.StatusOK} {
+		req := httptest.NewRequest("GET", "/livez", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, addr)
+	}
+}
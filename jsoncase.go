@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON field naming styles (MIRAIO_JSON_CASE).
+const (
+	JSONCaseCamel = "camel"
+	JSONCaseSnake = "snake"
+)
+
+// jsonCase selects the field names of JSON responses. Response structs carry
+// the camelCase name in their json tag and the snake_case name in a snake
+// tag; respondJSON picks one set at marshal time.
+var jsonCase = JSONCaseCamel
+
+// parseJSONCase reads MIRAIO_JSON_CASE.
+func parseJSONCase(v string) (string, error) {
+	switch strings.ToLower(v) {
+	case "", JSONCaseCamel:
+		return JSONCaseCamel, nil
+	case JSONCaseSnake:
+		return JSONCaseSnake, nil
+	default:
+		return "", fmt.Errorf("unsupported MIRAIO_JSON_CASE %q: use camel or snake", v)
+	}
+}
+
+// respondJSON writes v with the configured field naming. Map keys are data
+// (tags, headers, routes) and are never renamed.
+func respondJSON(c *gin.Context, status int, v any) {
+	if jsonCase == JSONCaseSnake {
+		v = snakeValue(reflect.ValueOf(v))
+	}
+	c.JSON(status, v)
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// snakeValue rebuilds v with every struct replaced by a map keyed by its
+// snake tags, falling back to the json tag for fields without one.
+func snakeValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return snakeValue(v.Elem())
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tag, ok := field.Tag.Lookup("snake")
+			if !ok {
+				tag = field.Tag.Get("json")
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if opts == "omitempty" && isEmptyValue(v.Field(i)) {
+				continue
+			}
+			out[name] = snakeValue(v.Field(i))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = snakeValue(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		out := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out[iter.Key().String()] = snakeValue(iter.Value())
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// isEmptyValue mirrors encoding/json's omitempty rule.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
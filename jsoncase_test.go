@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONCase(t *testing.T) {
+	for in, want := range map[string]string{"": JSONCaseCamel, "camel": JSONCaseCamel, "SNAKE": JSONCaseSnake} {
+		got, err := parseJSONCase(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseJSONCase("kebab")
+	assert.Error(t, err)
+}
+
+func TestSnakeValue(t *testing.T) {
+	page := prefixPage{
+		Objects:     []prefixObject{{Key: "a.txt", URL: "http://x/a.txt"}},
+		Expiry:      60,
+		ExpiresAt:   "2025-01-01T12:01:00Z",
+		IsTruncated: false,
+	}
+	assert.Equal(t, map[string]any{
+		"objects":      []any{map[string]any{"key": "a.txt", "url": "http://x/a.txt"}},
+		"expiry":       60,
+		"expires_at":   "2025-01-01T12:01:00Z",
+		"is_truncated": false,
+	}, snakeValue(reflect.ValueOf(page)), "omitempty fields are dropped")
+
+	tags := tagsResponse{Tags: map[string]string{"projectId": "42"}, VersionID: "v1"}
+	assert.Equal(t, map[string]any{
+		"tags":       map[string]any{"projectId": "42"},
+		"version_id": "v1",
+	}, snakeValue(reflect.ValueOf(tags)), "map keys are data and keep their case")
+}
+
+func TestRespondJSON_Casing(t *testing.T) {
+	setupTestEnvironment()
+	defer func() { jsonCase = JSONCaseCamel }()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/version", versionHandler)
+
+	get := func(path string) map[string]any {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	for _, tc := range []struct {
+		jsonCase string
+		presign  []string
+		version  string
+	}{
+		{JSONCaseCamel, []string{"url", "publicUrl", "contentType", "expiry", "expiresAt", "filename", "key"}, "buildTime"},
+		{JSONCaseSnake, []string{"url", "public_url", "content_type", "expiry", "expires_at", "filename", "key"}, "build_time"},
+	} {
+		jsonCase = tc.jsonCase
+
+		body := get("/presign?filename=a.txt&type=text/plain")
+		keys := make([]string, 0, len(body))
+		for k := range body {
+			keys = append(keys, k)
+		}
+		assert.ElementsMatch(t, tc.presign, keys, tc.jsonCase)
+
+		assert.Contains(t, get("/version"), tc.version, tc.jsonCase)
+	}
+}
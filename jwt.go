@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mirago/miraio/utils"
+	"golang.org/x/sync/singleflight"
+)
+
+// JWKSRefreshInterval is the minimum time between two fetches of the JWKS,
+// so tokens with unknown key IDs cannot make the server hammer the issuer.
+const JWKSRefreshInterval = time.Minute
+
+// JWKSFetchTimeout bounds one fetch of the JWKS.
+const JWKSFetchTimeout = 5 * time.Second
+
+// JWTLeeway is the clock difference with the token issuer tolerated when
+// checking exp and nbf.
+const JWTLeeway = 30 * time.Second
+
+// jwtScopeKey is the gin context key holding the verified token's claims.
+const jwtScopeKey = "jwtScope"
+
+// jwtAuth verifies the bearer tokens required by the presign endpoints
+// (MIRAIO_JWT_SECRET or MIRAIO_JWT_JWKS_URL). Nil disables JWT auth.
+var jwtAuth *jwtVerifier
+
+// errOutOfScope rejects keys and buckets the caller's token does not cover.
+var errOutOfScope = errors.New("Key is outside the token's scope")
+
+// scopeClaims are the claims MiraIO reads from a token. Prefix limits the
+// object keys the caller may presign, Bucket the bucket; either may be
+// empty.
+type scopeClaims struct {
+	Prefix string `json:"prefix,omitempty"`
+	Bucket string `json:"bucket,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// allows reports whether key in bucket lies within the scope. The prefix is
+// matched against the full object key, including MIRAIO_KEY_PREFIX.
+func (s *scopeClaims) allows(bucket, key string) bool {
+	return (s.Bucket == "" || s.Bucket == bucket) && strings.HasPrefix(key, s.Prefix)
+}
+
+// jwtVerifier checks token signatures: HS256 with a shared secret, RS256
+// with keys from a JWKS, or both.
+type jwtVerifier struct {
+	secret []byte
+	jwks   *jwksCache
+}
+
+// newJWTVerifier returns nil when neither a secret nor a JWKS URL is set.
+func newJWTVerifier(secret, jwksURL string) *jwtVerifier {
+	if secret == "" && jwksURL == "" {
+		return nil
+	}
+	v := &jwtVerifier{secret: []byte(secret)}
+	if jwksURL != "" {
+		v.jwks = newJWKSCache(jwksURL)
+	}
+	return v
+}
+
+// methods lists the accepted signing algorithms, so a token cannot pick an
+// algorithm the server is not configured for.
+func (v *jwtVerifier) methods() []string {
+	var methods []string
+	if len(v.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if v.jwks != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	return methods
+}
+
+// verify parses token and checks its signature and its exp and nbf claims.
+// Tokens without exp are refused, since they would never expire.
+func (v *jwtVerifier) verify(ctx context.Context, token string) (*scopeClaims, error) {
+	claims := new(scopeClaims)
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		if t.Method == jwt.SigningMethodHS256 {
+			return v.secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.jwks.key(ctx, kid)
+	}, jwt.WithValidMethods(v.methods()), jwt.WithLeeway(JWTLeeway), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// middleware requires a valid bearer token and stores its scope for
+// checkScope. Requests presenting MIRAIO_API_KEY pass unscoped.
+func (v *jwtVerifier) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if validAPIKey(c) {
+			c.Next()
+			return
+		}
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+			return
+		}
+		claims, err := v.verify(c.Request.Context(), token)
+		if err != nil {
+			utils.LogWarning("Rejected token from %s: %v", c.ClientIP(), err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+			return
+		}
+		c.Set(jwtScopeKey, claims)
+		c.Next()
+	}
+}
+
+// requestScope returns the scope of the request's token, or nil when the
+// request is not limited to one.
+func requestScope(c *gin.Context) *scopeClaims {
+	if v, ok := c.Get(jwtScopeKey); ok {
+		return v.(*scopeClaims)
+	}
+	return nil
+}
+
+// checkScope answers 403 and returns false when key on b lies outside the
+// request's token scope.
+func checkScope(c *gin.Context, b *backend, key string) bool {
+	if s := requestScope(c); s != nil && !s.allows(b.signer.Bucket, key) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errOutOfScope.Error()})
+		return false
+	}
+	return true
+}
+
+// pickBackend selects the backend for a new upload. A token scoped to a
+// bucket uploads to that bucket's backend; checkScope rejects the request
+// when none serves it.
+func pickBackend(c *gin.Context) *backend {
+	return pickScopedBackend(requestScope(c))
+}
+
+// pickScopedBackend is pickBackend for a scope s, which may be nil.
+func pickScopedBackend(s *scopeClaims) *backend {
+	if s != nil && s.Bucket != "" {
+		if b := backends.byBucket(s.Bucket); b != nil {
+			return b
+		}
+	}
+	return backends.pick()
+}
+
+// jwksCache holds the RSA keys published at a JWKS URL by key ID. Keys are
+// fetched on first use and again when a token names an unknown key, which
+// picks up key rotation at the issuer.
+type jwksCache struct {
+	url     string
+	client  *http.Client
+	fetches singleflight.Group
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func newJWKSCache(url string) *jwksCache {
+	return &jwksCache{url: url, client: &http.Client{Timeout: JWKSFetchTimeout}}
+}
+
+// key returns the key for kid. An empty kid is accepted when the JWKS holds
+// a single key. The fetch runs outside j.mu, so tokens with known keys are
+// not held up by a slow issuer, and misses during a fetch wait for it
+// instead of starting another.
+func (j *jwksCache) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if k := j.cached(kid); k != nil {
+		return k, nil
+	}
+	if _, err, _ := j.fetches.Do("jwks", func() (any, error) {
+		return nil, j.refresh(context.WithoutCancel(ctx))
+	}); err != nil {
+		return nil, err
+	}
+	if k := j.cached(kid); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (j *jwksCache) cached(kid string) *rsa.PublicKey {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.lookup(kid)
+}
+
+// lookup finds kid among the cached keys. The caller holds j.mu.
+func (j *jwksCache) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(j.keys) == 1 {
+		for _, k := range j.keys {
+			return k
+		}
+	}
+	return j.keys[kid]
+}
+
+// refresh fetches the JWKS and swaps in its keys, unless the last fetch
+// started less than JWKSRefreshInterval ago. Keys other than RSA signing
+// keys are skipped.
+func (j *jwksCache) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	if time.Since(j.fetched) < JWKSRefreshInterval {
+		j.mu.Unlock()
+		return nil
+	}
+	j.fetched = time.Now()
+	j.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			utils.LogWarning("Skipping malformed JWKS key %q", k.Kid)
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "jwt-test-secret"
+
+func signHS256(t *testing.T, claims scopeClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return token
+}
+
+func expiresIn(d time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(d))}
+}
+
+// serveJWKS publishes key under kid and counts the fetches.
+func serveJWKS(t *testing.T, kid string, key *rsa.PublicKey) (url string, fetches *atomic.Int32) {
+	t.Helper()
+	fetches = new(atomic.Int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","use":"sig","alg":"RS256","kid":"` + kid + `","n":"` +
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()) + `","e":"` +
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()) + `"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, fetches
+}
+
+func TestScopeClaimsAllows(t *testing.T) {
+	s := scopeClaims{Prefix: "album/", Bucket: "test-bucket"}
+	assert.True(t, s.allows("test-bucket", "album/a.txt"))
+	assert.False(t, s.allows("test-bucket", "other/a.txt"))
+	assert.False(t, s.allows("other-bucket", "album/a.txt"))
+	assert.True(t, (&scopeClaims{}).allows("any", "any/key"))
+}
+
+func TestJWTVerifier_HS256(t *testing.T) {
+	v := newJWTVerifier(testJWTSecret, "")
+	require.NotNil(t, v)
+	assert.Nil(t, newJWTVerifier("", ""))
+
+	claims, err := v.verify(context.Background(), signHS256(t, scopeClaims{Prefix: "album/", RegisteredClaims: expiresIn(time.Minute)}))
+	require.NoError(t, err)
+	assert.Equal(t, "album/", claims.Prefix)
+
+	_, err = v.verify(context.Background(), signHS256(t, scopeClaims{Prefix: "album/"}))
+	assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing, "tokens without exp are refused")
+
+	expired := signHS256(t, scopeClaims{RegisteredClaims: expiresIn(-time.Hour)})
+	_, err = v.verify(context.Background(), expired)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, scopeClaims{}).SignedString([]byte("other-secret"))
+	require.NoError(t, err)
+	_, err = v.verify(context.Background(), forged)
+	assert.Error(t, err)
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, scopeClaims{}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = v.verify(context.Background(), unsigned)
+	assert.Error(t, err)
+}
+
+func TestJWTVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	url, fetches := serveJWKS(t, "key-1", &key.PublicKey)
+	v := newJWTVerifier("", url)
+
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, scopeClaims{Bucket: "test-bucket", RegisteredClaims: expiresIn(time.Minute)})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	claims, err := v.verify(context.Background(), sign("key-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "test-bucket", claims.Bucket)
+	_, err = v.verify(context.Background(), sign("key-1"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, fetches.Load(), "known keys are cached")
+
+	// Unknown key IDs refetch at most once per JWKSRefreshInterval.
+	_, err = v.verify(context.Background(), sign("key-2"))
+	assert.Error(t, err)
+	assert.EqualValues(t, 1, fetches.Load())
+
+	// Without a secret, HS256 tokens are refused rather than checked
+	// against an empty key.
+	_, err = v.verify(context.Background(), signHS256(t, scopeClaims{RegisteredClaims: expiresIn(time.Minute)}))
+	assert.Error(t, err)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestJWKSCache_ConcurrentRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	url, fetches := serveJWKS(t, "key-2", &key.PublicKey)
+	started, release := make(chan struct{}), make(chan struct{})
+	j := newJWKSCache(url)
+	var once sync.Once
+	j.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		once.Do(func() { close(started) })
+		<-release
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	old := &key.PublicKey
+	j.keys = map[string]*rsa.PublicKey{"key-1": old}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k, err := j.key(context.Background(), "key-2")
+			assert.NoError(t, err)
+			assert.NotNil(t, k)
+		}()
+	}
+	<-started
+
+	// Known keys are served while the fetch is in flight.
+	k, err := j.key(context.Background(), "key-1")
+	require.NoError(t, err)
+	assert.Same(t, old, k)
+
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, fetches.Load(), "concurrent misses share one fetch")
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	apiKey = "secret-key"
+	defer func() { apiKey = "" }()
+
+	router := gin.New()
+	router.GET("/presign", newJWTVerifier(testJWTSecret, "").middleware(), func(c *gin.Context) {
+		if s := requestScope(c); s != nil {
+			c.String(http.StatusOK, s.Prefix)
+			return
+		}
+		c.String(http.StatusOK, "unscoped")
+	})
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		status int
+		body   string
+	}{
+		{"No token", "", "", http.StatusUnauthorized, ""},
+		{"Garbage", "Authorization", "Bearer not-a-jwt", http.StatusUnauthorized, ""},
+		{"Valid token", "Authorization", "Bearer " + signHS256(t, scopeClaims{Prefix: "album/", RegisteredClaims: expiresIn(time.Minute)}), http.StatusOK, "album/"},
+		{"No expiry", "Authorization", "Bearer " + signHS256(t, scopeClaims{Prefix: "album/"}), http.StatusUnauthorized, ""},
+		{"API key", "X-API-Key", "secret-key", http.StatusOK, "unscoped"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/presign", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+			if tc.body != "" {
+				assert.Equal(t, tc.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPresignHandler_JWTScope(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	auth := newJWTVerifier(testJWTSecret, "").middleware()
+	router.GET("/presign", auth, presignHandler)
+	router.GET("/presign-get", auth, presignGetHandler)
+	router.GET("/list", auth, listHandler)
+
+	album := signHS256(t, scopeClaims{Prefix: "album/", RegisteredClaims: expiresIn(time.Minute)})
+	otherBucket := signHS256(t, scopeClaims{Bucket: "other-bucket", RegisteredClaims: expiresIn(time.Minute)})
+	for _, tc := range []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"Upload in scope", "/presign?filename=album/a.txt&type=text/plain", album, http.StatusOK},
+		{"Upload out of scope", "/presign?filename=other/a.txt&type=text/plain", album, http.StatusForbidden},
+		{"Sibling of the prefix", "/presign?filename=album.txt&type=text/plain", album, http.StatusForbidden},
+		{"Download out of scope", "/presign-get?filename=other/a.txt", album, http.StatusForbidden},
+		{"List out of scope", "/list?prefix=other/", album, http.StatusForbidden},
+		{"Other bucket", "/presign?filename=a.txt&type=text/plain", otherBucket, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
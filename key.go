@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MaxKeyLength is the S3 limit on object key length, in bytes.
+const MaxKeyLength = 1024
+
+// keyPrefix is prepended to every object key (MIRAIO_KEY_PREFIX). It is
+// either empty or ends with a slash.
+var keyPrefix string
+
+// normalizeKeyPrefix ensures a non-empty prefix ends with exactly one slash
+// and carries no leading slash.
+func normalizeKeyPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// expandEnvStrict expands ${VAR} (and $VAR) references in s with os.Expand,
+// failing if any referenced variable is unset. Variables set to the empty
+// string expand to nothing.
+func expandEnvStrict(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("references unset environment variable(s) %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// sanitizeFilename strips leading slashes and rejects names that are empty,
+// contain control characters, or use "." / ".." path segments.
+func sanitizeFilename(filename string) (string, error) {
+	name := strings.TrimLeft(filename, "/")
+	if name == "" {
+		return "", fmt.Errorf("Invalid filename")
+	}
+	for _, r := range name {
+		if r < ' ' || r == 0x7f {
+			return "", fmt.Errorf("Invalid filename: control characters are not allowed")
+		}
+	}
+	for rest := name; rest != ""; {
+		var segment string
+		segment, rest, _ = strings.Cut(rest, "/")
+		if segment == "." || segment == ".." {
+			return "", fmt.Errorf("Invalid filename: relative path segments are not allowed")
+		}
+	}
+	return name, nil
+}
+
+// objectKey resolves a client-supplied filename to the object key used in
+// the bucket, applying sanitization and the deployment key prefix.
+func objectKey(filename string) (string, error) {
+	name, err := sanitizeFilename(filename)
+	if err != nil {
+		return "", err
+	}
+	// len counts bytes, which is what S3 limits, not runes.
+	key := keyPrefix + name
+	if maxKeyLength := currentConfig().maxKeyLength; len(key) > maxKeyLength {
+		return "", fmt.Errorf("filename too long (max %d)", maxKeyLength)
+	}
+	return key, nil
+}
+
+// parseMaxKeyLength reads MIRAIO_MAX_KEY_LENGTH, defaulting to MaxKeyLength.
+func parseMaxKeyLength(v string) (int, error) {
+	if v == "" {
+		return MaxKeyLength, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 || n > MaxKeyLength {
+		return 0, fmt.Errorf("MIRAIO_MAX_KEY_LENGTH must be between 1 and %d, got %q", MaxKeyLength, v)
+	}
+	return n, nil
+}
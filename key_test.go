@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeKeyPrefix(t *testing.T) {
+	assert.Equal(t, "", normalizeKeyPrefix(""))
+	assert.Equal(t, "", normalizeKeyPrefix("/"))
+	assert.Equal(t, "teamA/", normalizeKeyPrefix("teamA"))
+	assert.Equal(t, "teamA/", normalizeKeyPrefix("teamA/"))
+	assert.Equal(t, "teamA/uploads/", normalizeKeyPrefix("/teamA/uploads//"))
+}
+
+func TestObjectKey(t *testing.T) {
+	defer func() { keyPrefix = "" }()
+
+	t.Run("No prefix", func(t *testing.T) {
+		keyPrefix = ""
+		key, err := objectKey("photo.jpg")
+		require.NoError(t, err)
+		assert.Equal(t, "photo.jpg", key)
+	})
+
+	t.Run("With prefix", func(t *testing.T) {
+		keyPrefix = normalizeKeyPrefix("teamA")
+		key, err := objectKey("/photo.jpg")
+		require.NoError(t, err)
+		assert.Equal(t, "teamA/photo.jpg", key)
+	})
+
+	t.Run("Rejects traversal", func(t *testing.T) {
+		keyPrefix = "teamA/"
+		_, err := objectKey("../teamB/photo.jpg")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects control characters", func(t *testing.T) {
+		_, err := objectKey("photo\n.jpg")
+		assert.Error(t, err)
+	})
+
+	t.Run("Length includes prefix", func(t *testing.T) {
+		keyPrefix = "teamA/"
+		_, err := objectKey(strings.Repeat("a", MaxKeyLength-len(keyPrefix)))
+		assert.NoError(t, err)
+		_, err = objectKey(strings.Repeat("a", MaxKeyLength-len(keyPrefix)+1))
+		assert.Error(t, err)
+	})
+}
+
+func TestObjectKey_MaxLengthBytes(t *testing.T) {
+	keyPrefix = ""
+	withConfig(t, func(cfg *reloadableConfig) { cfg.maxKeyLength = 12 })
+
+	testCases := []struct {
+		name     string
+		filename string
+		valid    bool
+	}{
+		{"ASCII at limit", "abcdefgh.txt", true},
+		{"ASCII over limit", "abcdefghi.txt", false},
+		// Each emoji is 4 bytes: 2 emoji + ".txt" = 12 bytes but 6 runes.
+		{"Emoji at limit", "😀😀.txt", true},
+		{"Emoji over limit", "😀😀😀", true},
+		{"Emoji one byte over", "😀😀😀a", false},
+		// "é" is 2 bytes and "漢" 3 bytes: 2+3+3+4 = 12 bytes.
+		{"Mixed width at limit", "é漢abc.txt", true},
+		{"Mixed width over limit", "é漢abcd.txt", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := objectKey(tc.filename)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "filename too long (max 12)")
+			}
+		})
+	}
+}
+
+func TestParseMaxKeyLength(t *testing.T) {
+	n, err := parseMaxKeyLength("")
+	require.NoError(t, err)
+	assert.Equal(t, MaxKeyLength, n)
+
+	n, err = parseMaxKeyLength("255")
+	require.NoError(t, err)
+	assert.Equal(t, 255, n)
+
+	for _, v := range []string{"0", "-1", "abc", "2048"} {
+		_, err := parseMaxKeyLength(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestExpandEnvStrict(t *testing.T) {
+	t.Setenv("MIRAIO_TEST_REGION", "eu-west-1")
+	t.Setenv("MIRAIO_TEST_EMPTY", "")
+
+	got, err := expandEnvStrict("${MIRAIO_TEST_REGION}/uploads/")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1/uploads/", got)
+
+	got, err = expandEnvStrict("tenants/${MIRAIO_TEST_EMPTY}")
+	require.NoError(t, err)
+	assert.Equal(t, "tenants/", got)
+
+	got, err = expandEnvStrict("plain/")
+	require.NoError(t, err)
+	assert.Equal(t, "plain/", got)
+
+	_, err = expandEnvStrict("${MIRAIO_TEST_UNSET_A}/${MIRAIO_TEST_REGION}/${MIRAIO_TEST_UNSET_B}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MIRAIO_TEST_UNSET_A, MIRAIO_TEST_UNSET_B")
+}
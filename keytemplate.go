@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// keyTemplate lays out uploaded object keys (MIRAIO_KEY_TEMPLATE), e.g.
+// "uploads/{yyyy}/{mm}/{uuid}-{filename}". Empty means the filename is the
+// key.
+var keyTemplate string
+
+// keyPlaceholders expands each supported placeholder for a filename at a
+// point in time.
+var keyPlaceholders = map[string]func(filename string, now time.Time) string{
+	"yyyy":     func(_ string, now time.Time) string { return now.Format("2006") },
+	"mm":       func(_ string, now time.Time) string { return now.Format("01") },
+	"dd":       func(_ string, now time.Time) string { return now.Format("02") },
+	"uuid":     func(string, time.Time) string { return uuid.NewString() },
+	"filename": func(filename string, _ time.Time) string { return filename },
+	"ext": func(filename string, _ time.Time) string {
+		return strings.TrimPrefix(path.Ext(filename), ".")
+	},
+}
+
+// validateKeyTemplate rejects unknown placeholders and unbalanced braces so
+// a typo fails at startup instead of producing odd keys.
+func validateKeyTemplate(tmpl string) error {
+	_, err := expandKeyTemplate(tmpl, "", time.Time{})
+	return err
+}
+
+// expandKeyTemplate substitutes the placeholders of tmpl. Dates use UTC.
+func expandKeyTemplate(tmpl, filename string, now time.Time) (string, error) {
+	now = now.UTC()
+	var b strings.Builder
+	for rest := tmpl; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			b.WriteString(rest)
+			break
+		}
+		if rest[open] == '}' {
+			return "", fmt.Errorf("unmatched } in key template %q", tmpl)
+		}
+		b.WriteString(rest[:open])
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in key template %q", tmpl)
+		}
+		name := rest[open+1 : open+end]
+		expand, ok := keyPlaceholders[name]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder {%s} in key template %q", name, tmpl)
+		}
+		b.WriteString(expand(filename, now))
+		rest = rest[open+end+1:]
+	}
+	return b.String(), nil
+}
+
+// uploadKeyName applies keyTemplate to a sanitized upload filename.
+func uploadKeyName(filename string) (string, error) {
+	if keyTemplate == "" {
+		return filename, nil
+	}
+	return expandKeyTemplate(keyTemplate, filename, time.Now())
+}
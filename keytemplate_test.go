@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandKeyTemplate(t *testing.T) {
+	now := time.Date(2024, 3, 7, 23, 30, 0, 0, time.FixedZone("X", -2*3600))
+
+	key, err := expandKeyTemplate("uploads/{yyyy}/{mm}/{dd}/{filename}", "photo.jpg", now)
+	require.NoError(t, err)
+	assert.Equal(t, "uploads/2024/03/08/photo.jpg", key, "dates are in UTC")
+
+	key, err = expandKeyTemplate("{uuid}.{ext}", "archive.tar.gz", now)
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.gz$`, key)
+
+	key, err = expandKeyTemplate("static/{filename}", "a.txt", now)
+	require.NoError(t, err)
+	assert.Equal(t, "static/a.txt", key)
+}
+
+func TestValidateKeyTemplate(t *testing.T) {
+	assert.NoError(t, validateKeyTemplate("uploads/{yyyy}/{mm}/{dd}/{uuid}-{filename}.{ext}"))
+	assert.NoError(t, validateKeyTemplate("no-placeholders"))
+
+	for _, tmpl := range []string{"{year}/{filename}", "{filename", "filename}", "{}"} {
+		assert.Error(t, validateKeyTemplate(tmpl), tmpl)
+	}
+}
+
+func TestPrepareUpload_KeyTemplate(t *testing.T) {
+	setupTestEnvironment()
+	keyTemplate = "uploads/{uuid}-{filename}"
+	keyPrefix = "teamA/"
+	defer func() { keyTemplate, keyPrefix = "", "" }()
+
+	key, _, err := prepareUpload(uploadRequest{Filename: "/photo.jpg", ContentType: "image/jpeg"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^teamA/uploads/[0-9a-f-]{36}-photo\.jpg$`, key)
+
+	_, _, err = prepareUpload(uploadRequest{Filename: "../x", ContentType: "image/jpeg"})
+	assert.Error(t, err, "the filename is sanitized before expansion")
+}
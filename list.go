@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultListMaxKeys caps a /list page when MIRAIO_LIST_MAX_KEYS is unset.
+const DefaultListMaxKeys = 1000
+
+// listPage is one page of a /list response.
+type listPage struct {
+	Keys        []string `json:"keys"`
+	MaxKeys     int      `json:"maxKeys" snake:"max_keys"`
+	IsTruncated bool     `json:"isTruncated" snake:"is_truncated"`
+	NextMarker  string   `json:"nextMarker,omitempty" snake:"next_marker,omitempty"`
+}
+
+// listHandler returns object keys under an optional prefix, in key order.
+// maxKeys defaults to and is clamped at listMaxKeys; the response echoes the
+// value used. When more keys exist the response sets isTruncated and
+// nextMarker, which the client passes back as marker for the next page.
+func listHandler(c *gin.Context) {
+	fullPrefix := keyPrefix
+	if prefix := c.Query("prefix"); prefix != "" {
+		var err error
+		if fullPrefix, err = objectKey(prefix); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !checkScope(c, backends.all()[0], fullPrefix) {
+		return
+	}
+
+	listMaxKeys := currentConfig().listMaxKeys
+	maxKeys := listMaxKeys
+	if v := c.Query("maxKeys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maxKeys: must be a positive integer"})
+			return
+		}
+		maxKeys = min(n, listMaxKeys)
+	}
+
+	marker := ""
+	if v := c.Query("marker"); v != "" {
+		var err error
+		if marker, err = objectKey(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	keys, truncated, err := backends.all()[0].storage().List(ctx, fullPrefix, marker, maxKeys)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not list objects")
+		return
+	}
+
+	resp := listPage{
+		Keys:        make([]string, 0, len(keys)),
+		MaxKeys:     maxKeys,
+		IsTruncated: truncated,
+	}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, strings.TrimPrefix(key, keyPrefix))
+	}
+	if truncated {
+		resp.NextMarker = resp.Keys[len(resp.Keys)-1]
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// parseListMaxKeys reads MIRAIO_LIST_MAX_KEYS.
+func parseListMaxKeys(v string) (int, error) {
+	if v == "" {
+		return DefaultListMaxKeys, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("MIRAIO_LIST_MAX_KEYS must be a positive integer, got %q", v)
+	}
+	return n, nil
+}
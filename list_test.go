@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListMaxKeys(t *testing.T) {
+	n, err := parseListMaxKeys("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultListMaxKeys, n)
+
+	n, err = parseListMaxKeys("50")
+	require.NoError(t, err)
+	assert.Equal(t, 50, n)
+
+	for _, bad := range []string{"0", "-1", "many"} {
+		_, err := parseListMaxKeys(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	store, _ := newTestFSStorage(t)
+	fsStore = store
+	withConfig(t, func(cfg *reloadableConfig) { cfg.listMaxKeys = 2 })
+	defer func() { fsStore = nil }()
+
+	ctx := context.Background()
+	for _, key := range []string{"p/c", "p/a", "p/b", "q/a"} {
+		u, err := store.PresignPut(ctx, key, nil, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, fsRequest(t, http.MethodPut, u, key, nil).StatusCode)
+	}
+
+	router := gin.New()
+	router.GET("/list", listHandler)
+	list := func(query string) (int, listPage) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/list?"+query, nil))
+		var page listPage
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		}
+		return w.Code, page
+	}
+
+	t.Run("clamps maxKeys", func(t *testing.T) {
+		code, page := list("prefix=p/&maxKeys=500")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 2, page.MaxKeys)
+		assert.Equal(t, []string{"p/a", "p/b"}, page.Keys)
+		assert.True(t, page.IsTruncated)
+		assert.Equal(t, "p/b", page.NextMarker)
+	})
+
+	t.Run("continues from marker", func(t *testing.T) {
+		code, page := list("prefix=p/&marker=p/b")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"p/c"}, page.Keys)
+		assert.False(t, page.IsTruncated)
+		assert.Empty(t, page.NextMarker)
+	})
+
+	t.Run("walks every page", func(t *testing.T) {
+		var all []string
+		marker := ""
+		for {
+			code, page := list("maxKeys=1&marker=" + marker)
+			require.Equal(t, http.StatusOK, code)
+			all = append(all, page.Keys...)
+			if !page.IsTruncated {
+				break
+			}
+			marker = page.NextMarker
+		}
+		assert.Equal(t, []string{"p/a", "p/b", "p/c", "q/a"}, all)
+	})
+
+	t.Run("rejects invalid maxKeys", func(t *testing.T) {
+		for _, bad := range []string{"0", "-3", "ten"} {
+			code, _ := list("maxKeys=" + bad)
+			assert.Equal(t, http.StatusBadRequest, code, bad)
+		}
+	})
+}
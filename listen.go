@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// ShutdownTimeout bounds how long in-flight requests may run after a
+// termination signal.
+const ShutdownTimeout = 10 * time.Second
+
+// unixSocketPrefix marks a MIRAIO_LISTEN_ADDR that names a Unix socket.
+const unixSocketPrefix = "unix:"
+
+// resolveListenAddr turns MIRAIO_LISTEN_ADDR into a network and address for
+// net.Listen. An empty addr listens on all interfaces at port; a bare host
+// or a bare port is completed with the other half.
+func resolveListenAddr(addr, port string) (network, address string, err error) {
+	if path, ok := strings.CutPrefix(addr, unixSocketPrefix); ok {
+		if path == "" {
+			return "", "", errors.New("unix socket path is empty")
+		}
+		return "unix", path, nil
+	}
+
+	if _, err := strconv.Atoi(addr); err == nil {
+		addr = ":" + addr
+	}
+	switch {
+	case addr == "":
+		addr = "0.0.0.0:" + port
+	case strings.HasPrefix(addr, ":"):
+		addr = "0.0.0.0" + addr
+	case strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]"):
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), port)
+	case !strings.Contains(addr, ":"):
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	return "tcp", addr, nil
+}
+
+// listen opens the listener for network and address. A stale Unix socket
+// left behind by a crashed process is removed first.
+func listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(address); err != nil {
+				return nil, fmt.Errorf("removing stale socket %s: %w", address, err)
+			}
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// serve runs server on ln until SIGINT or SIGTERM, then shuts it down
+// gracefully. Closing the listener also unlinks a Unix socket.
+func serve(server *http.Server, ln net.Listener) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errCh := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			errCh <- server.ServeTLS(ln, "", "")
+		} else {
+			errCh <- server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-stop:
+		utils.LogInfo("Received %s, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveListenAddr(t *testing.T) {
+	testCases := []struct {
+		addr            string
+		expectedNetwork string
+		expectedAddress string
+	}{
+		{"", "tcp", "0.0.0.0:9080"},
+		{"8080", "tcp", "0.0.0.0:8080"},
+		{":8080", "tcp", "0.0.0.0:8080"},
+		{"127.0.0.1", "tcp", "127.0.0.1:9080"},
+		{"127.0.0.1:8080", "tcp", "127.0.0.1:8080"},
+		{"[::1]", "tcp", "[::1]:9080"},
+		{"[::1]:8080", "tcp", "[::1]:8080"},
+		{"unix:/run/miraio.sock", "unix", "/run/miraio.sock"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			network, address, err := resolveListenAddr(tc.addr, "9080")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNetwork, network)
+			assert.Equal(t, tc.expectedAddress, address)
+		})
+	}
+
+	for _, addr := range []string{"unix:", "::1"} {
+		_, _, err := resolveListenAddr(addr, "9080")
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "miraio.sock")
+
+	// A stale socket from a previous run must not block startup.
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	ln, err := listen("unix", path)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})}
+	go server.Serve(ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) { return net.Dial("unix", path) },
+	}}
+	resp, err := client.Get("http://unix/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "ok", string(body))
+
+	require.NoError(t, server.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket should be removed on shutdown")
+}
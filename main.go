@@ -2,26 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/mirago/miraio/pkg/presign"
 	"github.com/mirago/miraio/utils"
 )
 
 const (
-	DefaultPort = "9080"
+	DefaultPort      = "9080"
+	DefaultHTTPSPort = "9443"
+	DefaultExpiry    = time.Minute
 )
 
+// MinExpiry and MaxExpiry bound presigned URL lifetimes. S3 rejects
+// signatures valid for more than seven days, so a longer URL would only
+// fail when used.
+const (
+	MinExpiry = time.Second
+	MaxExpiry = 7 * 24 * time.Hour
+)
+
+// RangeProbe is the Range header signed into rangeProbe=true HEAD URLs.
+const RangeProbe = "bytes=0-0"
+
 var minioClient *minio.Client
+
+// signer presigns URLs for the configured bucket; handlers are thin adapters
+// over it.
+var signer *presign.Signer
 var bucketName string
 var publicURL string
+var requestTimeout time.Duration
+
+// minioRegion is the region used for signing and bucket creation. When empty
+// the client looks the bucket location up from the server.
+var minioRegion string
 
 func LoadConfig() {
 	env := os.Getenv("MIRAIO_ENV")
@@ -34,10 +61,11 @@ func LoadConfig() {
 		envFile = ".env"
 	}
 
+	// The logger is configured from the environment, so it is not initialized
+	// yet; utils falls back to stderr for this message.
 	err := godotenv.Load(envFile)
 	if err != nil {
-		utils.LogFatal("Error loading .env file: %v", err)
-		os.Exit(1)
+		utils.LogFatal("Error loading %s file: %v", envFile, err)
 	}
 }
 
@@ -45,56 +73,976 @@ func main() {
 	LoadConfig()
 
 	utils.InitLogger()
+	utils.ReopenOnHangup()
+	utils.LogInfo("MiraIO version %s (commit %s, built %s)", Version, Commit, BuildTime)
 
 	endpoint := os.Getenv("MIRAIO_MINIO_ENDPOINT")
-	accessKeyID := os.Getenv("MIRAIO_MINIO_ACCESS_KEY")
-	secretAccessKey := os.Getenv("MIRAIO_MINIO_SECRET_KEY")
+	accessKeyID, secretAccessKey, sessionToken, err := credentialEnv("MIRAIO_MINIO_")
+	if err != nil {
+		utils.LogFatal("Error reading MinIO credentials: %v", err)
+	}
 	useSSL := os.Getenv("MIRAIO_MINIO_USE_SSL") == "true"
 	bucketName = os.Getenv("MIRAIO_MINIO_BUCKET")
 	publicURL = os.Getenv("MIRAIO_MINIO_PUBLIC_URL")
+	urlStyle = os.Getenv("MIRAIO_URL_STYLE")
+	minioRegion = os.Getenv("MIRAIO_MINIO_REGION")
+	inferContentType = os.Getenv("MIRAIO_INFER_CONTENT_TYPE") == "true"
+	versioned = os.Getenv("MIRAIO_VERSIONED") == "true"
+	objectLockEnabled = os.Getenv("MIRAIO_OBJECT_LOCK") == "true"
+	createBucket = os.Getenv("MIRAIO_CREATE_BUCKET") == "true"
+	if path := os.Getenv("MIRAIO_BUCKET_POLICY_FILE"); path != "" {
+		tmpl, err := loadBucketPolicyTemplate(path)
+		if err != nil {
+			utils.LogFatal("%v", err)
+		}
+		bucketPolicyTemplate = tmpl
+		if !createBucket {
+			utils.LogWarning("MIRAIO_BUCKET_POLICY_FILE is only applied to buckets created with MIRAIO_CREATE_BUCKET=true")
+		}
+	}
+	if v := os.Getenv("MIRAIO_DEFAULT_CONTENT_TYPE"); v != "" {
+		defaultContentType = v
+	}
+	if v := os.Getenv("MIRAIO_DEFAULT_CACHE_CONTROL"); v != "" {
+		cc, err := parseCacheControl(v)
+		if err != nil {
+			utils.LogFatal("Invalid MIRAIO_DEFAULT_CACHE_CONTROL: %v", err)
+		}
+		defaultCacheControl = cc
+	}
+	classes, err := parseStorageClasses(os.Getenv("MIRAIO_STORAGE_CLASSES"))
+	if err != nil {
+		utils.LogFatal("Invalid MIRAIO_STORAGE_CLASSES: %v", err)
+	}
+	allowedStorageClasses = classes
+	apiKey = os.Getenv("MIRAIO_API_KEY")
+	jwksURL := os.Getenv("MIRAIO_JWT_JWKS_URL")
+	if jwksURL != "" {
+		if u, err := url.Parse(jwksURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			utils.LogFatal("Invalid MIRAIO_JWT_JWKS_URL %q: must be an http or https URL", jwksURL)
+		}
+	}
+	jwtAuth = newJWTVerifier(os.Getenv("MIRAIO_JWT_SECRET"), jwksURL)
+	keyTemplate = os.Getenv("MIRAIO_KEY_TEMPLATE")
 
-	var err error
-	minioClient, err = minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
-		Secure: useSSL,
-	})
+	prefix, err := expandEnvStrict(os.Getenv("MIRAIO_KEY_PREFIX"))
 	if err != nil {
-		utils.LogFatal("Error initializing MinIO client: %v", err)
-		os.Exit(1)
+		utils.LogFatal("Invalid MIRAIO_KEY_PREFIX: %v", err)
+	}
+	keyPrefix = normalizeKeyPrefix(prefix)
+	if keyPrefix != "" {
+		utils.LogInfo("Using key prefix %s", keyPrefix)
+	}
+
+	if storageBackend, err = parseStorageBackend(os.Getenv("MIRAIO_BACKEND")); err != nil {
+		utils.LogFatal("%v", err)
+	}
+	if v := os.Getenv("MIRAIO_MAX_RETRIES"); v != "" {
+		maxRetries, err = strconv.Atoi(v)
+		if err != nil || maxRetries < 0 {
+			utils.LogFatal("Invalid MIRAIO_MAX_RETRIES %q: must be a non-negative integer", v)
+		}
+	}
+	if v := os.Getenv("MIRAIO_PRESIGN_CACHE_SIZE"); v != "" {
+		presignCacheSize, err = strconv.Atoi(v)
+		if err != nil || presignCacheSize < 0 {
+			utils.LogFatal("Invalid MIRAIO_PRESIGN_CACHE_SIZE %q: must be a non-negative integer", v)
+		}
+	}
+	settingsFile = os.Getenv("MIRAIO_SETTINGS_FILE")
+	values, err := reloadableValues(os.Getenv, settingsFile)
+	if err != nil {
+		utils.LogFatal("Error reading MIRAIO_SETTINGS_FILE: %v", err)
+	}
+	cfg, err := parseReloadableConfig(values)
+	if err != nil {
+		utils.LogFatal("Invalid configuration: %v", err)
+	}
+	liveConfig.Store(cfg)
+	if settingsFile != "" {
+		reloadOnHangup()
+		utils.LogInfo("Reloading %s on SIGHUP", settingsFile)
+	}
+	if retentionMode, err = parseRetentionMode(os.Getenv("MIRAIO_RETENTION_MODE")); err != nil {
+		utils.LogFatal("Invalid object lock configuration: %v", err)
+	}
+	if maxBodyBytes, err = parseMaxBodyBytes(os.Getenv("MIRAIO_MAX_BODY_BYTES")); err != nil {
+		utils.LogFatal("Invalid body size configuration: %v", err)
+	}
+	if maxUploadBytes, err = parseMaxUploadBytes(os.Getenv("MIRAIO_MAX_UPLOAD_BYTES")); err != nil {
+		utils.LogFatal("Invalid upload size configuration: %v", err)
+	}
+	publicURL = strings.TrimRight(publicURL, "/")
+	var schemeMismatch bool
+	if publicURL, schemeMismatch = resolvePublicURLScheme(publicURL, useSSL); schemeMismatch {
+		utils.LogWarning("MIRAIO_MINIO_PUBLIC_URL %s does not match MIRAIO_MINIO_USE_SSL=%t; publicUrl links may mix http and https. Ignore this if TLS is terminated in front of MinIO.", publicURL, useSSL)
+	}
+	if err = validateKeyTemplate(keyTemplate); err != nil {
+		utils.LogFatal("Invalid MIRAIO_KEY_TEMPLATE: %v", err)
+	}
+	if err = validateURLStyle(); err != nil {
+		utils.LogFatal("Invalid public URL configuration: %v", err)
+	}
+	if v := os.Getenv("MIRAIO_USAGE_CACHE_TTL"); v != "" {
+		usage.ttl, err = time.ParseDuration(v)
+		if err != nil || usage.ttl < 0 {
+			utils.LogFatal("Invalid MIRAIO_USAGE_CACHE_TTL %q: must be a duration such as 5m", v)
+		}
+	}
+	janitor.register("usage cache", usage)
+	if v := os.Getenv("MIRAIO_MAX_CLOCK_SKEW"); v != "" {
+		maxClockSkew, err = time.ParseDuration(v)
+		if err != nil || maxClockSkew < 0 {
+			utils.LogFatal("Invalid MIRAIO_MAX_CLOCK_SKEW %q: must be a duration such as 30s", v)
+		}
+	}
+	if v := os.Getenv("MIRAIO_HEALTH_CACHE_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			utils.LogFatal("Invalid MIRAIO_HEALTH_CACHE_MS %q: must be a non-negative number of milliseconds", v)
+		}
+		health.ttl = time.Duration(ms) * time.Millisecond
+	}
+	if v := os.Getenv("MIRAIO_HANDLER_TIMEOUT"); v != "" {
+		handlerTimeout, err = time.ParseDuration(v)
+		if err != nil || handlerTimeout < 0 {
+			utils.LogFatal("Invalid MIRAIO_HANDLER_TIMEOUT %q: must be a duration such as 30s", v)
+		}
+	}
+	if v := os.Getenv("MIRAIO_IDEMPOTENCY_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			utils.LogFatal("Invalid MIRAIO_IDEMPOTENCY_TTL %q: must be a duration such as 10m", v)
+		}
+		if ttl == 0 {
+			idempotency = nil
+		} else {
+			idempotency.ttl = ttl
+		}
+	}
+	if idempotency != nil {
+		janitor.register("idempotency cache", idempotency)
+	}
+	sweepInterval := DefaultSweepInterval
+	if v := os.Getenv("MIRAIO_SWEEP_INTERVAL"); v != "" {
+		sweepInterval, err = time.ParseDuration(v)
+		if err != nil || sweepInterval < 0 {
+			utils.LogFatal("Invalid MIRAIO_SWEEP_INTERVAL %q: must be a duration such as 1m", v)
+		}
+	}
+	verifyBucket, err := parseVerifyBucket(os.Getenv("MIRAIO_VERIFY_BUCKET"))
+	if err != nil {
+		utils.LogFatal("%v", err)
+	}
+	if verifyBucket > 0 {
+		if storageBackend == StorageFS {
+			utils.LogWarning("MIRAIO_VERIFY_BUCKET is ignored with MIRAIO_BACKEND=fs")
+		} else {
+			bucketWatch = newBucketGuard(missingBuckets)
+		}
+	}
+	if v := os.Getenv("MIRAIO_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			utils.LogFatal("Invalid MIRAIO_MAX_CONCURRENCY %q: must be a non-negative integer", v)
+		}
+		concurrency = newConcurrencyLimiter(n)
+	}
+	if v := os.Getenv("MIRAIO_REQUEST_TIMEOUT"); v != "" {
+		requestTimeout, err = time.ParseDuration(v)
+		if err != nil || requestTimeout < 0 {
+			utils.LogFatal("Invalid MIRAIO_REQUEST_TIMEOUT %q: must be a duration such as 10s", v)
+		}
+	}
+
+	utils.LogInfo("%s", minioConfigLine(endpoint, accessKeyID, secretAccessKey, useSSL))
+
+	if collisionStrategy, err = parseCollisionStrategy(os.Getenv("MIRAIO_COLLISION_STRATEGY")); err != nil {
+		utils.LogFatal("%v", err)
+	}
+	if jsonCase, err = parseJSONCase(os.Getenv("MIRAIO_JSON_CASE")); err != nil {
+		utils.LogFatal("%v", err)
+	}
+	if signatureVersion, err = parseSignatureVersion(os.Getenv("MIRAIO_SIGNATURE_VERSION")); err != nil {
+		utils.LogFatal("%v", err)
+	}
+	if signatureVersion == SignatureV2 {
+		utils.LogWarning("MIRAIO_SIGNATURE_VERSION=v2 is deprecated; signature v2 is weaker than v4 and will be removed once no supported backend needs it")
+	}
+
+	if minioTransportTuning, err = parseTransportTuning(os.Getenv); err != nil {
+		utils.LogFatal("Invalid MinIO transport configuration: %v", err)
+	}
+	if minioTransport, err = loadMinIOTransport(); err != nil {
+		utils.LogFatal("Invalid MinIO TLS configuration: %v", err)
+	}
+	if os.Getenv("MIRAIO_MINIO_INSECURE_SKIP_VERIFY") == "true" {
+		utils.LogWarning("MIRAIO_MINIO_INSECURE_SKIP_VERIFY=true: MinIO's TLS certificate is NOT verified; connections can be intercepted. Use MIRAIO_MINIO_CA_CERT instead.")
+	}
+
+	var creds *credentials.Credentials
+	if storageBackend == StorageFS {
+		if objectLockEnabled {
+			utils.LogFatal("MIRAIO_OBJECT_LOCK is not supported with MIRAIO_BACKEND=fs")
+		}
+		if err := initFSStorage(); err != nil {
+			utils.LogFatal("Error initializing fs backend: %v", err)
+		}
+	} else {
+		creds = connectMinIO(endpoint, accessKeyID, secretAccessKey, sessionToken, useSSL)
+	}
+
+	if endpoint := os.Getenv("MIRAIO_OTEL_ENDPOINT"); endpoint != "" {
+		shutdownTracing, err := initTracing(context.Background(), endpoint)
+		if err != nil {
+			utils.LogFatal("Error initializing tracing: %v", err)
+		}
+		defer shutdownTracing(context.Background())
+		utils.LogInfo("Exporting traces to %s", endpoint)
+	}
+
+	signer = newSigner()
+	if names := os.Getenv("MIRAIO_BACKENDS"); names != "" {
+		if fsStore != nil {
+			utils.LogFatal("MIRAIO_BACKENDS is not supported with MIRAIO_BACKEND=fs")
+		}
+		if err := loadBackends(names); err != nil {
+			utils.LogFatal("Invalid backend configuration: %v", err)
+		}
+	}
+	if publicEndpoint := os.Getenv("MIRAIO_MINIO_PUBLIC_ENDPOINT"); publicEndpoint != "" && fsStore == nil {
+		region := minioRegion
+		if region == "" {
+			region = detectRegion(minioClient, bucketName)
+		}
+		presignClient, err := newPublicPresignClient(publicEndpoint, creds, useSSL, region)
+		if err != nil {
+			utils.LogFatal("Error initializing public endpoint client: %v", err)
+		}
+		signer.Client = withTracing(withPresignCache(withRetries(presignClient)))
+		postPolicyClient = presignClient
+	}
+
+	router := gin.New()
+	router.Use(gin.Logger(), countRequests(), recovery())
+	router.Use(rejectDuplicateParams())
+	trustedProxies, err := parseTrustProxy(os.Getenv("MIRAIO_TRUST_PROXY"))
+	if err != nil {
+		utils.LogFatal("Invalid MIRAIO_TRUST_PROXY: %v", err)
+	}
+	if err := trustProxies(router, trustedProxies); err != nil {
+		utils.LogFatal("Error configuring trusted proxies: %v", err)
+	}
+	if handlerTimeout > 0 {
+		router.Use(timeoutHandlers(handlerTimeout))
+	}
+	if authEnabled() {
+		router.Use(publicBaseOverride())
+	}
+	// With a settings file the lists may be set later, so the filter is
+	// installed even when they start out empty.
+	if filter := currentConfig().ipFilter; filter.enabled() || settingsFile != "" {
+		router.Use(filterConfiguredIPs())
+		utils.LogInfo("IP filtering enabled: %d allowed and %d denied ranges", len(filter.allow), len(filter.deny))
+	}
+	if tracer != nil {
+		router.Use(traceRequests())
+	}
+	accessLogWriter, err := openAccessLog(os.Getenv("MIRAIO_ACCESS_LOG"))
+	if err != nil {
+		utils.LogFatal("Error opening access log: %v", err)
+	}
+	if accessLogWriter != nil {
+		router.Use(accessLog(accessLogWriter))
+	}
+	if auditLog, err = openAuditLog(os.Getenv("MIRAIO_AUDIT_LOG")); err != nil {
+		utils.LogFatal("Error opening audit log: %v", err)
+	}
+	if auditLog != nil && !authEnabled() {
+		utils.LogWarning("MIRAIO_AUDIT_LOG is set but MIRAIO_API_KEY is not; the audited endpoints are disabled")
+	}
+	slowlogThreshold, err := parseSlowlogThreshold(os.Getenv("MIRAIO_SLOWLOG_MS"))
+	if err != nil {
+		utils.LogFatal("Invalid slow log configuration: %v", err)
+	}
+	if slowlogThreshold > 0 {
+		router.Use(slowlog(slowlogThreshold))
+	}
+	if os.Getenv("MIRAIO_ENABLE_GZIP") == "true" {
+		minBytes := DefaultGzipMinBytes
+		if v := os.Getenv("MIRAIO_GZIP_MIN_BYTES"); v != "" {
+			minBytes, err = strconv.Atoi(v)
+			if err != nil || minBytes < 0 {
+				utils.LogFatal("Invalid MIRAIO_GZIP_MIN_BYTES %q", v)
+			}
+		}
+		router.Use(gzipJSON(minBytes))
+	}
+	router.GET("/livez", livezHandler)
+	router.GET("/version", versionHandler)
+	router.GET("/readyz", readyzHandler)
+
+	// Routes that call MinIO share the concurrency limit and the bucket
+	// check; probes, /version and /stats stay responsive when either
+	// refuses requests.
+	limited := []gin.HandlerFunc{concurrency.middleware()}
+	if bucketWatch != nil {
+		limited = append(limited, bucketWatch.middleware())
+	}
+	// Token checks come first, so rejected callers never take a
+	// concurrency slot.
+	apiHandlers := limited
+	if jwtAuth != nil {
+		apiHandlers = append([]gin.HandlerFunc{jwtAuth.middleware()}, limited...)
+	}
+	api := router.Group("/", apiHandlers...)
+	api.GET("/presign", idempotent(), presignHandler)
+	api.POST("/presign", limitBody(), idempotent(), presignPostHandler)
+	api.GET("/presign-head", presignHeadHandler)
+	// HEAD lets clients follow a redirect=true response with the verb the
+	// URL was signed for.
+	api.HEAD("/presign-head", presignHeadHandler)
+	api.GET("/presign-get", presignGetHandler)
+	api.GET("/presign-prefix", presignPrefixHandler)
+	api.GET("/list", listHandler)
+	api.GET("/upload-bundle", idempotent(), uploadBundleHandler)
+	if fsStore == nil {
+		api.GET("/presign-post", idempotent(), presignPostPolicyHandler)
+		if defaultCacheControl != "" {
+			utils.LogWarning("MIRAIO_DEFAULT_CACHE_CONTROL is not applied to /presign-post uploads: POST policies cannot carry Cache-Control")
+		}
+		// Proxied uploads use the MinIO client; in fs mode clients reach
+		// the store through this server anyway.
+		api.PUT("/upload/*filename", audited("upload"), proxyUploadHandler)
+	}
+	api.GET("/confirm", confirmHandler)
+	if fsStore != nil {
+		for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete} {
+			router.Handle(method, FSRoutePrefix+"/:bucket/*key", fsStore.serve)
+		}
+	}
+	if os.Getenv("MIRAIO_SERVE_DEMO") == "true" {
+		router.GET("/", demoHandler)
+		utils.LogInfo("Serving the upload test page at /")
+	}
+
+	if authEnabled() {
+		admin := router.Group("/", requireAPIKey(), limitBody())
+		admin.GET("/stats", statsHandler)
+		admin.POST("/admin/reload", audited("reload"), reloadHandler)
+		admin = admin.Group("/", limited...)
+		admin.DELETE("/objects/*filename", audited("delete"), deleteObjectHandler)
+		// The remaining endpoints rely on MinIO features the fs backend
+		// does not have.
+		if fsStore == nil {
+			admin.POST("/copy", audited("copy"), copyObjectHandler)
+			admin.POST("/presign-compose", audited("compose"), composeHandler)
+			put := objectSubresources{"tags": {"tag", putObjectTagsHandler}}
+			if objectLockEnabled {
+				put["legal-hold"] = objectSubresource{"legal-hold", putLegalHoldHandler}
+			}
+			admin.PUT("/objects/*filename", put.handlers()...)
+			admin.GET("/objects/*filename", objectSubresources{"tags": {"", getObjectTagsHandler}}.handlers()...)
+			admin.GET("/usage", usageHandler)
+			admin.POST("/admin/purge", audited("purge"), purgeHandler)
+			admin.GET("/multipart", listMultipartHandler)
+			admin.DELETE("/multipart", audited("abort-multipart"), abortMultipartHandler)
+		}
+	} else {
+		router.GET("/stats", statsHandler)
+		utils.LogInfo("MIRAIO_API_KEY not set; object management endpoints are disabled")
+	}
+	if fsStore != nil {
+		utils.LogInfo("MIRAIO_BACKEND=fs: copy, compose, tags, usage, purge and multipart endpoints are disabled")
 	}
 
-	router := gin.Default()
-	router.GET("/presign", presignHandler)
+	if webhookURL := os.Getenv("MIRAIO_WEBHOOK_URL"); webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || u.Host == "" {
+			utils.LogFatal("Invalid MIRAIO_WEBHOOK_URL: must be an absolute URL")
+		}
+		webhook = newWebhookNotifier(webhookURL)
+		// Only the host is logged; webhook URLs often embed a token.
+		utils.LogInfo("Sending presign events to webhook at %s", u.Host)
+	}
+
+	if grpcPort := os.Getenv("MIRAIO_GRPC_PORT"); grpcPort != "" {
+		go serveGRPC(grpcPort)
+	}
 
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		utils.LogFatal("Error loading TLS configuration: %v", err)
+	}
 	port := os.Getenv("MIRAIO_PORT")
 	if port == "" {
 		port = DefaultPort
 	}
-	utils.LogInfo("Server running on %s", port)
-	utils.LogFatal("Error starting server: %v", router.Run(":"+port))
+	if tlsConfig != nil {
+		port = os.Getenv("MIRAIO_HTTPS_PORT")
+		if port == "" {
+			port = DefaultHTTPSPort
+		}
+	}
+
+	network, address, err := resolveListenAddr(os.Getenv("MIRAIO_LISTEN_ADDR"), port)
+	if err != nil {
+		utils.LogFatal("Invalid MIRAIO_LISTEN_ADDR: %v", err)
+	}
+	logEffectiveConfig(effectiveConfig{
+		endpoint:  endpoint,
+		accessKey: accessKeyID,
+		secretKey: secretAccessKey,
+		token:     sessionToken,
+		useSSL:    useSSL,
+		listen:    network + " " + address,
+		tls:       tlsConfig != nil,
+		grpcPort:  os.Getenv("MIRAIO_GRPC_PORT"),
+	})
+	ln, err := listen(network, address)
+	if err != nil {
+		utils.LogFatal("Error listening on %s %s: %v", network, address, err)
+	}
+
+	server := &http.Server{
+		Handler:   router,
+		TLSConfig: tlsConfig,
+	}
+	if tlsConfig != nil {
+		utils.LogInfo("Server running with TLS on %s %s", network, address)
+	} else {
+		utils.LogInfo("Server running on %s %s", network, address)
+	}
+	if sweepInterval > 0 {
+		stopSweeper := janitor.start(sweepInterval)
+		defer stopSweeper()
+	}
+	if bucketWatch != nil {
+		stopBucketWatch := bucketWatch.start(verifyBucket)
+		defer stopBucketWatch()
+	}
+	if err := serve(server, ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		utils.LogFatal("Error starting server: %v", err)
+	}
+}
+
+// connectMinIO creates minioClient and runs the startup checks that need
+// it, exiting on failure. It returns the credentials for clients created
+// later.
+func connectMinIO(endpoint, accessKeyID, secretAccessKey, sessionToken string, useSSL bool) *credentials.Credentials {
+	creds, err := loadCredentials(endpoint, useSSL, accessKeyID, secretAccessKey, sessionToken)
+	if err != nil {
+		utils.LogFatal("Error configuring MinIO credentials: %v", err)
+	}
+	minioClient, err = minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    useSSL,
+		Region:    minioRegion,
+		Transport: minioTransport,
+	})
+	if err != nil {
+		utils.LogFatal("Error initializing MinIO client: %v", err)
+	}
+	if minioRegion != "" {
+		utils.LogInfo("Using MinIO region %s", minioRegion)
+	} else {
+		utils.LogInfo("MIRAIO_MINIO_REGION not set; region will be detected from the bucket location")
+	}
+
+	if os.Getenv("MIRAIO_SKIP_STARTUP_CHECK") == "true" {
+		utils.LogWarning("MIRAIO_SKIP_STARTUP_CHECK=true; not verifying MinIO connectivity")
+	} else {
+		if err := checkBackend(endpoint, useSSL); err != nil {
+			utils.LogFatal("Startup check failed: %v", err)
+		}
+		if maxClockSkew > 0 {
+			checkClockSkew(endpoint, useSSL)
+		}
+	}
+
+	if objectLockEnabled {
+		if err := checkObjectLock(); err != nil {
+			utils.LogFatal("Object lock check failed: %v", err)
+		}
+	}
+
+	if os.Getenv("MIRAIO_ENSURE_PUBLIC_READ") == "true" {
+		if err := ensurePublicRead(); err != nil {
+			utils.LogFatal("Error ensuring public-read policy: %v", err)
+		}
+	}
+	return creds
+}
+
+// formatExpiresAt is the expiresAt of signed, a URL requested at now for
+// expiry; see urlExpiresAt.
+func formatExpiresAt(signed string, now time.Time, expiry time.Duration) string {
+	return urlExpiresAt(signed, now, expiry).UTC().Format(time.RFC3339)
+}
+
+// urlExpiresAt returns when signed stops working. The presign cache may
+// return a URL signed earlier than now, so the deadline is read from the
+// URL's X-Amz-Date and X-Amz-Expires, or X-Miraio-Expires for the fs
+// backend. Failing that it is now plus expiry, with now taken before
+// signing, so the reported deadline is never later than the real one.
+func urlExpiresAt(signed string, now time.Time, expiry time.Duration) time.Time {
+	_, query, _ := strings.Cut(signed, "?")
+	if v := rawQueryValue(query, fsExpiresParam); v != "" {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(unix, 0)
+		}
+	}
+	date, err := time.Parse(amzDateFormat, rawQueryValue(query, "X-Amz-Date"))
+	if err != nil {
+		return now.Add(expiry)
+	}
+	seconds, err := strconv.Atoi(rawQueryValue(query, "X-Amz-Expires"))
+	if err != nil {
+		return now.Add(expiry)
+	}
+	return date.Add(time.Duration(seconds) * time.Second)
+}
+
+// amzDateFormat is the layout of X-Amz-Date.
+const amzDateFormat = "20060102T150405Z"
+
+// rawQueryValue returns the first value of name in query without decoding
+// it, which is enough for the digits and letters of the signing parameters.
+func rawQueryValue(query, name string) string {
+	for query != "" {
+		var pair string
+		pair, query, _ = strings.Cut(query, "&")
+		if k, v, _ := strings.Cut(pair, "="); k == name {
+			return v
+		}
+	}
+	return ""
+}
+
+// presignUploadResponse is the response of GET and POST /presign.
+type presignUploadResponse struct {
+	URL                  string `json:"url"`
+	PublicURL            string `json:"publicUrl" snake:"public_url"`
+	ContentType          string `json:"contentType" snake:"content_type"`
+	Expiry               int    `json:"expiry"`
+	ExpiresAt            string `json:"expiresAt" snake:"expires_at"`
+	Filename             string `json:"filename"`
+	Key                  string `json:"key"`
+	Backend              string `json:"backend,omitempty"`
+	Ref                  string `json:"ref,omitempty"`
+	ContentMD5           string `json:"contentMd5,omitempty" snake:"content_md5,omitempty"`
+	CacheControl         string `json:"cacheControl,omitempty" snake:"cache_control,omitempty"`
+	StorageClass         string `json:"storageClass,omitempty" snake:"storage_class,omitempty"`
+	SSECustomerAlgorithm string `json:"sseCustomerAlgorithm,omitempty" snake:"sse_customer_algorithm,omitempty"`
+	SSECustomerKeyMD5    string `json:"sseCustomerKeyMd5,omitempty" snake:"sse_customer_key_md5,omitempty"`
+}
+
+// presignURLResponse is the response of the download-side presign endpoints.
+type presignURLResponse struct {
+	URL       string `json:"url"`
+	PublicURL string `json:"publicUrl" snake:"public_url"`
+	VersionID string `json:"versionId,omitempty" snake:"version_id,omitempty"`
+	ExpiresAt string `json:"expiresAt" snake:"expires_at"`
+	// Headers must be sent with the request for the signature to match.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// uploadRequest describes a presigned upload independently of the transport
+// it arrived on.
+type uploadRequest struct {
+	Filename    string
+	ContentType string
+	// Params carries meta.<key> and tag.<key> parameters.
+	Params url.Values
+	// SSECustomerKey is a base64 SSE-C key the upload is encrypted with.
+	SSECustomerKey string
+	// Proxied is set for uploads this server sends to MinIO itself.
+	Proxied bool
+}
+
+// prepareUpload validates req and resolves the object key and the headers
+// that will be signed into the upload URL. Errors are client errors.
+func prepareUpload(req uploadRequest) (string, url.Values, error) {
+	req.ContentType = resolveContentType(req.Filename, req.ContentType)
+	if req.Filename == "" || req.ContentType == "" {
+		return "", nil, errors.New("Missing filename or type")
+	}
+
+	name, err := sanitizeFilename(req.Filename)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkExtension(currentConfig().allowedExtensions, name); err != nil {
+		return "", nil, err
+	}
+	if name, err = uploadKeyName(name); err != nil {
+		return "", nil, err
+	}
+	key, err := objectKey(name)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkPrefixType(key, req.ContentType); err != nil {
+		return "", nil, err
+	}
+
+	reqParams := make(url.Values)
+	reqParams.Set("Content-Type", req.ContentType)
+	if err := addUploadHeaders(req.Params, reqParams); err != nil {
+		return "", nil, err
+	}
+	if err := addContentMD5(req.Params, reqParams); err != nil {
+		return "", nil, err
+	}
+	if err := addCacheControl(req.Params, reqParams); err != nil {
+		return "", nil, err
+	}
+	if err := addStorageClass(req.Params, reqParams); err != nil {
+		return "", nil, err
+	}
+	if req.Params.Has(sseCustomerKeyParam) {
+		return "", nil, errSSEKeyInQuery
+	}
+	if err := addSSECustomerKey(req.SSECustomerKey, reqParams); err != nil {
+		return "", nil, err
+	}
+	if err := addRetentionHeaders(req.Params, reqParams, req.Proxied); err != nil {
+		return "", nil, err
+	}
+	return key, reqParams, nil
+}
+
+// newSigner builds a Signer from the current client and bucket settings. In
+// fs mode it signs with the filesystem store, always path-style.
+func newSigner() *presign.Signer {
+	if fsStore != nil {
+		return presign.New(withTracing(fsStore), fsStore.bucket, fsStore.baseURL)
+	}
+	s := presign.New(withTracing(withPresignCache(withRetries(minioClient))), bucketName, publicURL)
+	s.URLStyle = urlStyle
+	return s
 }
 
+// presignHandler issues a presigned URL for the verb named by the method
+// parameter (default PUT, for backwards compatibility).
 func presignHandler(c *gin.Context) {
-	filename := c.Query("filename")
-	contentType := c.Query("type")
+	switch strings.ToUpper(c.DefaultQuery("method", http.MethodPut)) {
+	case http.MethodPut:
+		presignPutHandler(c)
+	case http.MethodGet:
+		presignGetHandler(c)
+	case http.MethodHead:
+		presignHeadHandler(c)
+	case http.MethodDelete:
+		// A delete URL destroys data, so only API-key holders may mint one.
+		if !authEnabled() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported method: DELETE requires API-key auth"})
+			return
+		}
+		requireAPIKey()(c)
+		if c.IsAborted() {
+			return
+		}
+		presignDeleteHandler(c)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported method: use PUT, GET, HEAD or DELETE"})
+	}
+}
+
+// presignPutHandler returns a presigned upload URL.
+func presignPutHandler(c *gin.Context) {
+	if rejectRedirect(c) {
+		return
+	}
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:       c.Query("filename"),
+		ContentType:    c.Query("type"),
+		Params:         c.Request.URL.Query(),
+		SSECustomerKey: c.GetHeader(SSECustomerKeyHeader),
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+	b := pickBackend(c)
+	if !checkScope(c, b, key) {
+		return
+	}
+
+	// validate=true runs the checks above without signing anything.
+	if c.Query("validate") == "true" {
+		c.JSON(http.StatusOK, gin.H{"valid": true})
+		return
+	}
+
+	signUpload(c, b, c.Query("filename"), key, reqParams, uploadExpiryFor(key, reqParams.Get("Content-Type")))
+}
+
+// signUpload presigns the upload prepared by prepareUpload on b and writes
+// the response shared by the GET and POST forms of /presign.
+func signUpload(c *gin.Context, b *backend, filename, key string, reqParams url.Values, expiry time.Duration) {
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now()
+	key, err := resolveCollision(key, existsOn(ctx, b))
+	if err != nil {
+		respondCollisionError(c, ctx, err)
+		return
+	}
+	signed, err := b.storage().PresignPut(ctx, key, http.Header(reqParams), expiry)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
+		return
+	}
+	public := b.signer.ObjectURL(key)
+	webhook.notifyPresign(http.MethodPut, filename, key, reqParams.Get("Content-Type"), c.ClientIP())
+
+	resp := presignUploadResponse{
+		URL:                  signed,
+		PublicURL:            requestPublicURL(c, b.signer, key, public),
+		ContentType:          reqParams.Get("Content-Type"),
+		Expiry:               int(expiry.Seconds()),
+		ExpiresAt:            formatExpiresAt(signed, now, expiry),
+		Filename:             filename,
+		Key:                  key,
+		ContentMD5:           reqParams.Get("Content-MD5"),
+		CacheControl:         reqParams.Get("Cache-Control"),
+		StorageClass:         reqParams.Get(storageClassHeader),
+		SSECustomerAlgorithm: reqParams.Get(sseCustomerAlgorithmHeader),
+		SSECustomerKeyMD5:    reqParams.Get(sseCustomerKeyMD5Header),
+	}
+	if backends.multi() {
+		resp.Backend = b.name
+		resp.Ref = b.ref(key)
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// presignHeadHandler returns a presigned HEAD URL so clients can check that
+// an object exists and read its headers without downloading it. With
+// rangeProbe=true the URL is signed for a one-byte Range request, whose
+// answer shows whether the object can be streamed in byte ranges.
+func presignHeadHandler(c *gin.Context) {
+	b, key, expiry, ok := objectParams(c)
+	if !ok {
+		return
+	}
+
+	var headers http.Header
+	if c.Query("rangeProbe") == "true" {
+		// A redirect cannot make the client add the Range header.
+		if wantsRedirect(c) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rangeProbe cannot be combined with redirect"})
+			return
+		}
+		headers = http.Header{"Range": {RangeProbe}}
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now()
+	signed, public, err := b.signer.HeadURLWithHeaders(ctx, key, headers, expiry)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
+		return
+	}
+	webhook.notifyPresign(http.MethodHead, c.Query("filename"), key, "", c.ClientIP())
+	if wantsRedirect(c) {
+		respondRedirect(c, signed)
+		return
+	}
+
+	resp := presignURLResponse{
+		URL:       signed,
+		PublicURL: requestPublicURL(c, b.signer, key, public),
+		ExpiresAt: formatExpiresAt(signed, now, expiry),
+	}
+	if headers != nil {
+		resp.Headers = map[string]string{"Range": RangeProbe}
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
 
-	if filename == "" || contentType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing filename or type"})
+// presignGetHandler returns a presigned download URL. The optional
+// filename-override and type-override parameters make MinIO answer with the
+// given Content-Disposition and Content-Type regardless of what was stored.
+// With versionId the URL fetches exactly that version, which must exist.
+func presignGetHandler(c *gin.Context) {
+	b, key, expiry, ok := objectParams(c)
+	if !ok {
 		return
 	}
 
 	reqParams := make(url.Values)
-	reqParams.Set("Content-Type", contentType)
+	versionID := c.Query("versionId")
+	if versionID != "" {
+		reqParams.Set("versionId", versionID)
+	}
+	if name := c.Query("filename-override"); name != "" {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name})
+		if disposition == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename-override"})
+			return
+		}
+		reqParams.Set("response-content-disposition", disposition)
+	}
+	if contentType := c.Query("type-override"); contentType != "" {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type-override"})
+			return
+		}
+		reqParams.Set("response-content-type", contentType)
+	}
+	addDownloadDisposition(reqParams, key, c.Query("type-override"))
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	// A URL for a missing version would only fail once the client uses it,
+	// so check up front.
+	if versionID != "" {
+		err := withRetry(ctx, "stat "+key, func() error {
+			_, err := b.storage().Stat(ctx, key, versionID)
+			return err
+		})
+		if err != nil {
+			if isNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Object version not found"})
+				return
+			}
+			respondBackendError(c, ctx, err, "Could not generate presigned URL")
+			return
+		}
+	}
+
+	now := time.Now()
+	signed, err := b.storage().PresignGet(ctx, key, expiry, reqParams)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
+		return
+	}
+	webhook.notifyPresign(http.MethodGet, c.Query("filename"), key, "", c.ClientIP())
+	if wantsRedirect(c) {
+		respondRedirect(c, signed)
+		return
+	}
+
+	respondJSON(c, http.StatusOK, presignURLResponse{
+		URL:       signed,
+		PublicURL: requestPublicURL(c, b.signer, key, b.signer.ObjectURL(key)),
+		VersionID: versionID,
+		ExpiresAt: formatExpiresAt(signed, now, expiry),
+	})
+}
 
-	presignedURL, err := minioClient.PresignedPutObject(context.Background(), bucketName, filename, time.Minute)
+// presignDeleteHandler returns a presigned DELETE URL.
+func presignDeleteHandler(c *gin.Context) {
+	if rejectRedirect(c) {
+		return
+	}
+	b, key, expiry, ok := objectParams(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	now := time.Now()
+	signed, public, err := b.signer.DeleteURL(ctx, key, expiry)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate presigned URL"})
+		respondBackendError(c, ctx, err, "Could not generate presigned URL")
 		return
 	}
+	webhook.notifyPresign(http.MethodDelete, c.Query("filename"), key, "", c.ClientIP())
 
-	publicFileURL := fmt.Sprintf("%s/%s/%s", publicURL, bucketName, filename)
-	c.JSON(http.StatusOK, gin.H{
-		"url":       presignedURL.String(),
-		"publicUrl": publicFileURL,
+	respondJSON(c, http.StatusOK, presignURLResponse{
+		URL:       signed,
+		PublicURL: requestPublicURL(c, b.signer, key, public),
+		ExpiresAt: formatExpiresAt(signed, now, expiry),
 	})
 }
+
+// objectParams reads the filename and expiry parameters shared by the
+// download-side presign endpoints. The filename may be a backend reference
+// returned by an upload. On invalid input it writes a 400, and for keys
+// outside the token scope a 403, and returns ok=false.
+func objectParams(c *gin.Context) (b *backend, key string, expiry time.Duration, ok bool) {
+	filename := c.Query("filename")
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing filename"})
+		return nil, "", 0, false
+	}
+
+	b, name := backends.resolve(filename)
+	key, err := objectKey(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", 0, false
+	}
+	if !checkScope(c, b, key) {
+		return nil, "", 0, false
+	}
+
+	expiry, err = parseExpiry(c.Query("expiry"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", 0, false
+	}
+	return b, key, expiry, true
+}
+
+// parseExpiry reads an expiry in seconds, returning DefaultExpiry when unset.
+// It must lie within MinExpiry and MaxExpiry.
+func parseExpiry(v string) (time.Duration, error) {
+	if v == "" {
+		return DefaultExpiry, nil
+	}
+	seconds, err := strconv.Atoi(v)
+	if err != nil || !validExpirySeconds(seconds) {
+		return 0, fmt.Errorf("Invalid expiry: must be between %d and %d seconds", int(MinExpiry.Seconds()), int(MaxExpiry.Seconds()))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// validExpirySeconds reports whether seconds is within MinExpiry and
+// MaxExpiry.
+func validExpirySeconds(seconds int) bool {
+	return seconds >= int(MinExpiry.Seconds()) && seconds <= int(MaxExpiry.Seconds())
+}
+
+// requestContext derives the context for backend calls from the incoming
+// request, bounded by MIRAIO_REQUEST_TIMEOUT when configured.
+func requestContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	if requestTimeout > 0 {
+		return context.WithTimeout(c.Request.Context(), requestTimeout)
+	}
+	return context.WithCancel(c.Request.Context())
+}
+
+// logContextAbort records why a backend call was abandoned, distinguishing
+// the handler and request timeouts from the client going away.
+func logContextAbort(ctx context.Context, c *gin.Context, err error) {
+	if reason := contextAbortReason(ctx, c); reason != "" {
+		utils.LogWarning("Request %s %s %s: %v", c.Request.Method, c.Request.URL.Path, reason, err)
+	}
+}
+
+// contextAbortReason explains why ctx, derived from the request by
+// requestContext, is done, or returns "" while it is not. The handler
+// deadline is checked first: it also surfaces as DeadlineExceeded in ctx.
+func contextAbortReason(ctx context.Context, c *gin.Context) string {
+	switch {
+	case ctx.Err() == nil:
+		return ""
+	case handlerTimedOut(c):
+		return fmt.Sprintf("exceeded the handler timeout of %s", handlerTimeout)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Sprintf("timed out after %s", requestTimeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return "cancelled by client"
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	metaParamPrefix = "meta."
+	tagParamPrefix  = "tag."
+)
+
+// addUploadHeaders folds repeated meta.<key>=<value> and tag.<key>=<value>
+// query parameters into reqParams as x-amz-meta-* and X-Amz-Tagging headers,
+// so they become part of the signed upload request. Tags are held to the
+// same S3 limits as the tagging endpoint, which S3 would otherwise only
+// enforce when the upload arrives.
+func addUploadHeaders(query url.Values, reqParams url.Values) error {
+	tagMap := make(map[string]string)
+	for param, values := range query {
+		switch {
+		case strings.HasPrefix(param, metaParamPrefix):
+			key := strings.TrimPrefix(param, metaParamPrefix)
+			if !validMetadataKey(key) {
+				return fmt.Errorf("Invalid metadata key %q", key)
+			}
+			for _, v := range values {
+				if !validHeaderValue(v) {
+					return fmt.Errorf("Invalid value for metadata key %q", key)
+				}
+			}
+			reqParams.Set("x-amz-meta-"+strings.ToLower(key), strings.Join(values, ","))
+		case strings.HasPrefix(param, tagParamPrefix):
+			tagMap[strings.TrimPrefix(param, tagParamPrefix)] = values[0]
+		}
+	}
+	if len(tagMap) == 0 {
+		return nil
+	}
+	if err := validateTags(tagMap); err != nil {
+		return err
+	}
+	tags := make(url.Values, len(tagMap))
+	for k, v := range tagMap {
+		tags.Set(k, v)
+	}
+	reqParams.Set("X-Amz-Tagging", tags.Encode())
+	return nil
+}
+
+// validMetadataKey reports whether key can follow the x-amz-meta- prefix in
+// an HTTP header name. Only letters, digits and hyphens are accepted since
+// underscores are dropped or rewritten by many proxies.
+func validMetadataKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether v is printable US-ASCII, which is all S3
+// guarantees to preserve in user metadata.
+func validHeaderValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] < ' ' || v[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// MultipartPageSize caps how many incomplete uploads GET /multipart returns
+// per call, which is also the S3 maximum.
+const MultipartPageSize = 1000
+
+// multipartUpload is one in-progress multipart upload.
+type multipartUpload struct {
+	Key       string `json:"key"`
+	UploadID  string `json:"uploadId" snake:"upload_id"`
+	Initiated string `json:"initiated"`
+}
+
+// multipartPage is the body of GET /multipart. When IsTruncated is set, the
+// next page is fetched by passing NextKeyMarker and NextUploadIDMarker back
+// as keyMarker and uploadIdMarker.
+type multipartPage struct {
+	Uploads            []multipartUpload `json:"uploads"`
+	IsTruncated        bool              `json:"isTruncated" snake:"is_truncated"`
+	NextKeyMarker      string            `json:"nextKeyMarker,omitempty" snake:"next_key_marker,omitempty"`
+	NextUploadIDMarker string            `json:"nextUploadIdMarker,omitempty" snake:"next_upload_id_marker,omitempty"`
+}
+
+// listMultipartHandler lists multipart uploads that were started but never
+// completed or aborted. Their parts use storage without being visible as
+// objects, so cleanup jobs page through them and abort stale ones.
+func listMultipartHandler(c *gin.Context) {
+	prefix := keyPrefix
+	if v := c.Query("prefix"); v != "" {
+		var err error
+		if prefix, err = objectKey(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	keyMarker := ""
+	if v := c.Query("keyMarker"); v != "" {
+		var err error
+		if keyMarker, err = objectKey(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	limit := MultipartPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > MultipartPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: must be between 1 and " + strconv.Itoa(MultipartPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	core := minio.Core{Client: minioClient}
+	var result minio.ListMultipartUploadsResult
+	err := withRetry(ctx, "list multipart uploads", func() error {
+		var err error
+		result, err = core.ListMultipartUploads(ctx, bucketName, prefix, keyMarker, c.Query("uploadIdMarker"), "", limit)
+		return err
+	})
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not list multipart uploads")
+		return
+	}
+
+	page := multipartPage{Uploads: make([]multipartUpload, 0, len(result.Uploads)), IsTruncated: result.IsTruncated}
+	for _, u := range result.Uploads {
+		page.Uploads = append(page.Uploads, multipartUpload{
+			Key:       strings.TrimPrefix(u.Key, keyPrefix),
+			UploadID:  u.UploadID,
+			Initiated: u.Initiated.UTC().Format(time.RFC3339),
+		})
+	}
+	if result.IsTruncated {
+		page.NextKeyMarker = strings.TrimPrefix(result.NextKeyMarker, keyPrefix)
+		page.NextUploadIDMarker = result.NextUploadIDMarker
+	}
+	respondJSON(c, http.StatusOK, page)
+}
+
+// abortMultipartHandler aborts one multipart upload, freeing its parts.
+func abortMultipartHandler(c *gin.Context) {
+	uploadID := c.Query("uploadId")
+	if c.Query("key") == "" || uploadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing key or uploadId"})
+		return
+	}
+	key, err := objectKey(c.Query("key"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	auditKey(c, key)
+	auditDetail(c, "uploadId="+uploadID)
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	core := minio.Core{Client: minioClient}
+	err = withRetry(ctx, "abort multipart "+key, func() error {
+		return core.AbortMultipartUpload(ctx, bucketName, key, uploadID)
+	})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Multipart upload not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not abort multipart upload")
+		return
+	}
+
+	utils.LogInfo("Aborted multipart upload %s of %s", uploadID, key)
+	c.Status(http.StatusNoContent)
+}
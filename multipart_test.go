@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func multipartRouter() *gin.Engine {
+	router := gin.New()
+	router.GET("/multipart", listMultipartHandler)
+	router.DELETE("/multipart", abortMultipartHandler)
+	return router
+}
+
+func TestMultipartHandlers_Validation(t *testing.T) {
+	setupTestEnvironment()
+	router := multipartRouter()
+
+	for _, target := range []string{
+		"GET /multipart?limit=0",
+		"GET /multipart?limit=1001",
+		"GET /multipart?prefix=../x",
+		"DELETE /multipart?key=a.txt",
+		"DELETE /multipart?uploadId=abc",
+		"DELETE /multipart?key=../a.txt&uploadId=abc",
+	} {
+		method, path, _ := strings.Cut(target, " ")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestIntegrationMultipart(t *testing.T) {
+	client := integrationClient(t)
+	router := multipartRouter()
+	ctx := context.Background()
+
+	core := minio.Core{Client: client}
+	uploadID, err := core.NewMultipartUpload(ctx, bucketName, "multipart-test.bin", minio.PutObjectOptions{})
+	require.NoError(t, err)
+	defer core.AbortMultipartUpload(ctx, bucketName, "multipart-test.bin", uploadID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/multipart?prefix=multipart-test", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var page multipartPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	require.Len(t, page.Uploads, 1)
+	assert.Equal(t, "multipart-test.bin", page.Uploads[0].Key)
+	assert.Equal(t, uploadID, page.Uploads[0].UploadID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/multipart?key=multipart-test.bin&uploadId="+uploadID, nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/multipart?key=multipart-test.bin&uploadId="+uploadID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// objectLockEnabled turns on retainUntil and the legal-hold endpoint
+// (MIRAIO_OBJECT_LOCK). The bucket must have been created with object lock.
+var objectLockEnabled bool
+
+// retentionMode is the object-lock mode signed into uploads with a
+// retainUntil (MIRAIO_RETENTION_MODE), GOVERNANCE by default.
+var retentionMode = string(minio.Governance)
+
+// parseRetentionMode validates MIRAIO_RETENTION_MODE.
+func parseRetentionMode(v string) (string, error) {
+	if v == "" {
+		return string(minio.Governance), nil
+	}
+	mode := minio.RetentionMode(strings.ToUpper(v))
+	if !mode.IsValid() {
+		return "", fmt.Errorf("unsupported MIRAIO_RETENTION_MODE %q: use GOVERNANCE or COMPLIANCE", v)
+	}
+	return string(mode), nil
+}
+
+// errRetentionWithoutMD5 rejects retainUntil on presigned uploads without
+// contentMd5: S3 refuses object-lock PUTs that lack Content-MD5, so the
+// signed URL could never be used.
+var errRetentionWithoutMD5 = errors.New("retainUntil requires contentMd5")
+
+// addRetentionHeaders signs object-lock retention into the upload when the
+// retainUntil parameter (an RFC 3339 timestamp) is present. Unless proxied,
+// where PutObject computes it, Content-MD5 must already be in reqParams.
+func addRetentionHeaders(query url.Values, reqParams url.Values, proxied bool) error {
+	v := query.Get("retainUntil")
+	if v == "" {
+		return nil
+	}
+	if !objectLockEnabled {
+		return errors.New("retainUntil is not supported: object lock is not enabled")
+	}
+	until, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return errors.New("Invalid retainUntil: must be an RFC 3339 timestamp")
+	}
+	if !until.After(time.Now()) {
+		return errors.New("Invalid retainUntil: must be in the future")
+	}
+	if !proxied && !reqParams.Has("Content-MD5") {
+		return errRetentionWithoutMD5
+	}
+	reqParams.Set("X-Amz-Object-Lock-Mode", retentionMode)
+	reqParams.Set("X-Amz-Object-Lock-Retain-Until-Date", until.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// checkObjectLock verifies at startup that the bucket supports object lock.
+func checkObjectLock() error {
+	ctx, cancel := context.WithTimeout(context.Background(), StartupCheckTimeout)
+	defer cancel()
+
+	status, _, _, _, err := minioClient.GetObjectLockConfig(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("reading object lock configuration of bucket %s: %w", bucketName, err)
+	}
+	if status != "Enabled" {
+		return fmt.Errorf("bucket %s does not have object lock enabled", bucketName)
+	}
+	utils.LogInfo("Object lock enabled on bucket %s; retention mode %s", bucketName, retentionMode)
+	return nil
+}
+
+// legalHoldRequest is the body of PUT /objects/:filename/legal-hold.
+type legalHoldRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// legalHoldResponse reports the legal hold now set on an object.
+type legalHoldResponse struct {
+	Key       string `json:"key"`
+	Status    string `json:"status"`
+	VersionID string `json:"versionId,omitempty" snake:"version_id,omitempty"`
+}
+
+// putLegalHoldHandler places or lifts a legal hold on an object.
+func putLegalHoldHandler(c *gin.Context) {
+	b, key, ok := objectTarget(c, objectFilename(c))
+	if !ok {
+		return
+	}
+
+	auditKey(c, key)
+
+	var req legalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err, `Body must be {"status": "ON"} or {"status": "OFF"}`)
+		return
+	}
+	status := minio.LegalHoldStatus(strings.ToUpper(req.Status))
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `Invalid status: use "ON" or "OFF"`})
+		return
+	}
+
+	auditDetail(c, "status="+string(status))
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	versionID := c.Query("versionId")
+	err := withRetry(ctx, "legal hold "+key, func() error {
+		return b.client.PutObjectLegalHold(ctx, b.signer.Bucket, key, minio.PutObjectLegalHoldOptions{
+			VersionID: versionID,
+			Status:    &status,
+		})
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not set legal hold")
+		return
+	}
+
+	utils.LogInfo("Set legal hold %s on object %s", status, key)
+	resp := legalHoldResponse{Key: strings.TrimPrefix(key, keyPrefix), Status: string(status)}
+	if versioned {
+		resp.VersionID = versionID
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRetentionHeaders(t *testing.T) {
+	defer func() { objectLockEnabled, retentionMode = false, "GOVERNANCE" }()
+	until := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	t.Run("Disabled", func(t *testing.T) {
+		objectLockEnabled = false
+		err := addRetentionHeaders(url.Values{"retainUntil": {until.Format(time.RFC3339)}}, url.Values{}, false)
+		assert.Error(t, err)
+
+		assert.NoError(t, addRetentionHeaders(url.Values{}, url.Values{}, false))
+	})
+
+	t.Run("Signs retention", func(t *testing.T) {
+		objectLockEnabled = true
+		retentionMode = "COMPLIANCE"
+		reqParams := url.Values{"Content-MD5": {"1B2M2Y8AsgTpgAmY7PhCfg=="}}
+		require.NoError(t, addRetentionHeaders(url.Values{"retainUntil": {until.Format(time.RFC3339)}}, reqParams, false))
+		assert.Equal(t, "COMPLIANCE", reqParams.Get("X-Amz-Object-Lock-Mode"))
+		assert.Equal(t, until.Format(time.RFC3339), reqParams.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	})
+
+	t.Run("Requires Content-MD5", func(t *testing.T) {
+		objectLockEnabled = true
+		query := url.Values{"retainUntil": {until.Format(time.RFC3339)}}
+		assert.ErrorIs(t, addRetentionHeaders(query, url.Values{}, false), errRetentionWithoutMD5)
+		assert.NoError(t, addRetentionHeaders(query, url.Values{}, true), "proxied uploads compute it")
+	})
+
+	t.Run("Invalid dates", func(t *testing.T) {
+		objectLockEnabled = true
+		past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		for _, v := range []string{"tomorrow", "2030-01-01", past} {
+			assert.Error(t, addRetentionHeaders(url.Values{"retainUntil": {v}}, url.Values{}, false), v)
+		}
+	})
+}
+
+func TestParseRetentionMode(t *testing.T) {
+	mode, err := parseRetentionMode("")
+	require.NoError(t, err)
+	assert.Equal(t, "GOVERNANCE", mode)
+
+	mode, err = parseRetentionMode("compliance")
+	require.NoError(t, err)
+	assert.Equal(t, "COMPLIANCE", mode)
+
+	_, err = parseRetentionMode("forever")
+	assert.Error(t, err)
+}
+
+func TestPutLegalHoldHandler_InvalidBody(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.PUT("/objects/:filename/legal-hold", putLegalHoldHandler)
+
+	for _, body := range []string{``, `{}`, `{"status":"MAYBE"}`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PUT", "/objects/a.txt/legal-hold", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestPutLegalHoldHandler_NestedKey(t *testing.T) {
+	setupTestEnvironment()
+	var paths []string
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+	})
+
+	router := gin.New()
+	router.PUT("/objects/*filename", objectSubresources{"legal-hold": {"legal-hold", putLegalHoldHandler}}.handlers()...)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/objects/uploads/2026/cat.jpg/legal-hold", strings.NewReader(`{"status":"ON"}`)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"key":"uploads/2026/cat.jpg","status":"ON"}`, w.Body.String())
+	assert.Equal(t, []string{"PUT /test-bucket/uploads/2026/cat.jpg?legal-hold="}, paths)
+}
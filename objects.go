@@ -0,0 +1,356 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/mirago/miraio/utils"
+)
+
+// versioned exposes object version IDs in responses (MIRAIO_VERSIONED).
+var versioned bool
+
+// isNotFound reports whether err is a MinIO "object or version missing" error,
+// or errObjectNotFound from another Storage.
+func isNotFound(err error) bool {
+	if errors.Is(err, errObjectNotFound) {
+		return true
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchVersion":
+		return true
+	}
+	return false
+}
+
+// objectFilename returns the filename of an /objects/*filename route. The
+// catch-all keeps the slashes of nested keys, and its leading slash is
+// dropped.
+func objectFilename(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("filename"), "/")
+}
+
+// objectSubresourceContext holds the objectSubresource a request addresses.
+const objectSubresourceContext = "miraio.object.subresource"
+
+// objectSubresource is an endpoint at /objects/<filename>/<name>. Operation
+// names its audit record; it is empty for reads, which are not audited.
+type objectSubresource struct {
+	operation string
+	handler   gin.HandlerFunc
+}
+
+// objectSubresources are the sub-resources served for one method on
+// /objects/*filename, by name. gin cannot route a fixed suffix after a
+// catch-all, so the last path segment picks the sub-resource and the rest
+// is the filename: /objects/a/tags/tags addresses the tags of "a/tags".
+type objectSubresources map[string]objectSubresource
+
+// handlers returns the route handlers: resolving the sub-resource, auditing
+// it and running it.
+func (s objectSubresources) handlers() []gin.HandlerFunc {
+	return []gin.HandlerFunc{s.resolve, auditSubresource, runSubresource}
+}
+
+// resolve splits the sub-resource off the filename parameter, answering 404
+// for names not served on this method.
+func (s objectSubresources) resolve(c *gin.Context) {
+	filename := objectFilename(c)
+	i := strings.LastIndexByte(filename, '/')
+	sub, ok := s[filename[i+1:]]
+	if i <= 0 || !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	for j := range c.Params {
+		if c.Params[j].Key == "filename" {
+			c.Params[j].Value = filename[:i]
+		}
+	}
+	c.Set(objectSubresourceContext, sub)
+}
+
+func auditSubresource(c *gin.Context) {
+	if sub := c.MustGet(objectSubresourceContext).(objectSubresource); sub.operation != "" {
+		auditRequest(c, sub.operation)
+		return
+	}
+	c.Next()
+}
+
+func runSubresource(c *gin.Context) {
+	c.MustGet(objectSubresourceContext).(objectSubresource).handler(c)
+}
+
+// objectTarget resolves filename, which may be a backend reference returned
+// by an upload, to its backend and object key. On invalid input it writes a
+// 400 and returns ok=false.
+func objectTarget(c *gin.Context, filename string) (b *backend, key string, ok bool) {
+	b, name := backends.resolve(filename)
+	key, err := objectKey(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return b, key, true
+}
+
+// deleteObjectHandler removes an object using the server's own credentials.
+// S3 deletes are idempotent, so the object is stat'ed first to report 404
+// for keys that do not exist.
+func deleteObjectHandler(c *gin.Context) {
+	b, key, ok := objectTarget(c, objectFilename(c))
+	if !ok {
+		return
+	}
+	auditKey(c, key)
+	versionID := c.Query("versionId")
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	store := b.storage()
+	err := withRetry(ctx, "stat "+key, func() error {
+		_, err := store.Stat(ctx, key, versionID)
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not delete object")
+		return
+	}
+
+	err = withRetry(ctx, "remove "+key, func() error {
+		return store.Remove(ctx, key, versionID)
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not delete object")
+		return
+	}
+
+	utils.LogInfo("Deleted object %s (version %q)", key, versionID)
+	c.Status(http.StatusNoContent)
+}
+
+// copyRequest is the input to copyObjectHandler, accepted as JSON or as
+// query/form parameters.
+type copyRequest struct {
+	Source       string `json:"source" form:"source" binding:"required"`
+	Destination  string `json:"destination" form:"destination" binding:"required"`
+	DeleteSource bool   `json:"deleteSource" form:"deleteSource"`
+}
+
+// copyResponse is the response of copyObjectHandler. Error is only set when
+// a move copied the object but left the source behind.
+type copyResponse struct {
+	Error     string `json:"error,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+	PublicURL string `json:"publicUrl" snake:"public_url"`
+	VersionID string `json:"versionId,omitempty" snake:"version_id,omitempty"`
+}
+
+// tagsResponse carries an object's tags. Tag keys are returned as stored.
+type tagsResponse struct {
+	Tags      map[string]string `json:"tags"`
+	VersionID string            `json:"versionId,omitempty" snake:"version_id,omitempty"`
+}
+
+// copyObjectHandler copies an object server-side, optionally removing the
+// source afterwards to emulate a move. No object data passes through this
+// process.
+func copyObjectHandler(c *gin.Context) {
+	var req copyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		respondBindError(c, err, "Missing source or destination")
+		return
+	}
+
+	b, srcKey, ok := objectTarget(c, req.Source)
+	if !ok {
+		return
+	}
+	dst, dstKey, ok := objectTarget(c, req.Destination)
+	if !ok {
+		return
+	}
+	auditKey(c, srcKey)
+	auditTarget(c, dstKey)
+	if req.DeleteSource {
+		auditDetail(c, "move")
+	}
+	if dst.name != b.name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and destination must be on the same backend"})
+		return
+	}
+	if srcKey == dstKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and destination must differ"})
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	var info minio.UploadInfo
+	err := withRetry(ctx, "copy "+srcKey, func() error {
+		var err error
+		info, err = b.client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: b.signer.Bucket, Object: dstKey},
+			minio.CopySrcOptions{Bucket: b.signer.Bucket, Object: srcKey},
+		)
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not copy object")
+		return
+	}
+	utils.LogInfo("Copied object %s to %s", srcKey, dstKey)
+
+	if req.DeleteSource {
+		err = withRetry(ctx, "remove "+srcKey, func() error {
+			return b.client.RemoveObject(ctx, b.signer.Bucket, srcKey, minio.RemoveObjectOptions{})
+		})
+		if err != nil {
+			// The copy succeeded, so report the partial move rather than
+			// a plain failure.
+			utils.LogError("Copied %s to %s but could not remove source: %v", srcKey, dstKey, err)
+			respondJSON(c, http.StatusInternalServerError, copyResponse{
+				Error:     "Object copied but source could not be removed",
+				PublicURL: requestPublicURL(c, b.signer, dstKey, b.signer.ObjectURL(dstKey)),
+			})
+			return
+		}
+		utils.LogInfo("Removed source object %s after move", srcKey)
+	}
+
+	resp := copyResponse{Key: dstKey, PublicURL: requestPublicURL(c, b.signer, dstKey, b.signer.ObjectURL(dstKey))}
+	if backends.multi() {
+		resp.Ref = b.ref(dstKey)
+	}
+	if versioned {
+		resp.VersionID = info.VersionID
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// S3 object tagging limits.
+const (
+	MaxObjectTags     = 10
+	MaxTagKeyLength   = 128
+	MaxTagValueLength = 256
+)
+
+// validateTags checks a tag set against the S3 limits on count and key/value
+// length (in characters).
+func validateTags(tagMap map[string]string) error {
+	if len(tagMap) > MaxObjectTags {
+		return fmt.Errorf("Too many tags (max %d)", MaxObjectTags)
+	}
+	for k, v := range tagMap {
+		if k == "" || utf8.RuneCountInString(k) > MaxTagKeyLength {
+			return fmt.Errorf("Tag key %q must be 1-%d characters", k, MaxTagKeyLength)
+		}
+		if utf8.RuneCountInString(v) > MaxTagValueLength {
+			return fmt.Errorf("Tag value for %q exceeds %d characters", k, MaxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// putObjectTagsHandler replaces an object's tag set with the JSON map in the
+// request body.
+func putObjectTagsHandler(c *gin.Context) {
+	b, key, ok := objectTarget(c, objectFilename(c))
+	if !ok {
+		return
+	}
+	auditKey(c, key)
+
+	var tagMap map[string]string
+	if err := c.ShouldBindJSON(&tagMap); err != nil {
+		respondBindError(c, err, "Body must be a JSON object of string tags")
+		return
+	}
+	if err := validateTags(tagMap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	objectTags, err := tags.NewTags(tagMap, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	versionID := c.Query("versionId")
+	err = withRetry(ctx, "tag "+key, func() error {
+		return b.client.PutObjectTagging(ctx, b.signer.Bucket, key, objectTags, minio.PutObjectTaggingOptions{VersionID: versionID})
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not tag object")
+		return
+	}
+
+	utils.LogInfo("Set %d tags on object %s", len(tagMap), key)
+	resp := tagsResponse{Tags: tagMap}
+	if versioned {
+		resp.VersionID = versionID
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// getObjectTagsHandler returns an object's tags as a JSON map.
+func getObjectTagsHandler(c *gin.Context) {
+	b, key, ok := objectTarget(c, objectFilename(c))
+	if !ok {
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	versionID := c.Query("versionId")
+	var objectTags *tags.Tags
+	err := withRetry(ctx, "get tags "+key, func() error {
+		var err error
+		objectTags, err = b.client.GetObjectTagging(ctx, b.signer.Bucket, key, minio.GetObjectTaggingOptions{VersionID: versionID})
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not read object tags")
+		return
+	}
+
+	resp := tagsResponse{Tags: objectTags.ToMap()}
+	if versioned {
+		resp.VersionID = versionID
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyObjectHandler_Validation(t *testing.T) {
+	setupTestEnvironment()
+
+	withBackends(t, 1, testBackend("eu", 1))
+	router := gin.New()
+	router.POST("/copy", copyObjectHandler)
+
+	testCases := []struct {
+		name          string
+		body          string
+		expectedError string
+	}{
+		{"Missing destination", `{"source":"a.txt"}`, "Missing source or destination"},
+		{"Malformed JSON", `{"source":`, "Missing source or destination"},
+		{"Same key", `{"source":"a.txt","destination":"/a.txt"}`, "Source and destination must differ"},
+		{"Traversal", `{"source":"a.txt","destination":"../b.txt"}`, "Invalid filename"},
+		{"Other backend", `{"source":"a.txt","destination":"eu:b.txt"}`, "Source and destination must be on the same backend"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/copy", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), tc.expectedError)
+		})
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	assert.NoError(t, validateTags(map[string]string{"project": "demo"}))
+	assert.NoError(t, validateTags(map[string]string{strings.Repeat("k", MaxTagKeyLength): strings.Repeat("ü", MaxTagValueLength)}))
+
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxObjectTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	assert.Error(t, validateTags(tooMany))
+	assert.Error(t, validateTags(map[string]string{"": "v"}))
+	assert.Error(t, validateTags(map[string]string{strings.Repeat("k", MaxTagKeyLength+1): "v"}))
+	assert.Error(t, validateTags(map[string]string{"k": strings.Repeat("v", MaxTagValueLength+1)}))
+}
+
+func TestPutObjectTagsHandler_Validation(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.PUT("/objects/:filename/tags", putObjectTagsHandler)
+
+	for name, body := range map[string]string{
+		"Not an object":  `["a"]`,
+		"Non-string tag": `{"a": 1}`,
+		"Long value":     `{"a": "` + strings.Repeat("v", MaxTagValueLength+1) + `"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/objects/a.txt/tags", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		})
+	}
+}
+
+func TestDeleteObjectHandler_NestedKey(t *testing.T) {
+	setupTestEnvironment()
+	var paths []string
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+			w.Header().Set("ETag", `"9b2cf535f27731c974343645a3985328"`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	router := gin.New()
+	router.DELETE("/objects/*filename", deleteObjectHandler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/objects/uploads/2026/cat.jpg", nil))
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Equal(t, []string{"HEAD /test-bucket/uploads/2026/cat.jpg", "DELETE /test-bucket/uploads/2026/cat.jpg"}, paths)
+}
+
+func TestDeleteObjectHandler_BackendRef(t *testing.T) {
+	setupTestEnvironment()
+	var paths []string
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+			w.Header().Set("ETag", `"9b2cf535f27731c974343645a3985328"`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	withBackends(t, 1, testBackend("eu", 1))
+
+	router := gin.New()
+	router.DELETE("/objects/*filename", deleteObjectHandler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/objects/eu:photos/cat.jpg", nil))
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Equal(t, []string{"HEAD /eu-bucket/photos/cat.jpg", "DELETE /eu-bucket/photos/cat.jpg"}, paths)
+}
+
+func TestObjectSubresources(t *testing.T) {
+	setupTestEnvironment()
+	records := useAuditLog(t)
+	var paths []string
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`<Tagging><TagSet><Tag><Key>project</Key><Value>demo</Value></Tag></TagSet></Tagging>`))
+		}
+	})
+
+	router := gin.New()
+	router.PUT("/objects/*filename", objectSubresources{"tags": {"tag", putObjectTagsHandler}}.handlers()...)
+	router.GET("/objects/*filename", objectSubresources{"tags": {"", getObjectTagsHandler}}.handlers()...)
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Nested key", func(t *testing.T) {
+		paths = nil
+		w := send(http.MethodPut, "/objects/uploads/2026/cat.jpg/tags", `{"project":"demo"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = send(http.MethodGet, "/objects/uploads/2026/cat.jpg/tags", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"tags":{"project":"demo"}}`, w.Body.String())
+		assert.Equal(t, []string{
+			"PUT /test-bucket/uploads/2026/cat.jpg?tagging=",
+			"GET /test-bucket/uploads/2026/cat.jpg?tagging=",
+		}, paths)
+	})
+
+	t.Run("Key ending in a sub-resource name", func(t *testing.T) {
+		paths = nil
+		w := send(http.MethodGet, "/objects/a/tags/tags", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, []string{"GET /test-bucket/a/tags?tagging="}, paths)
+	})
+
+	t.Run("Unknown sub-resource", func(t *testing.T) {
+		for _, path := range []string{"/objects/a.txt/acl", "/objects/a.txt", "/objects/tags"} {
+			assert.Equal(t, http.StatusNotFound, send(http.MethodGet, path, "").Code, path)
+		}
+	})
+
+	t.Run("Audited", func(t *testing.T) {
+		var ops []string
+		for _, rec := range records() {
+			ops = append(ops, rec.Operation+" "+rec.Key)
+		}
+		assert.Equal(t, []string{"tag uploads/2026/cat.jpg"}, ops)
+	})
+}
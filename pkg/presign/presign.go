@@ -0,0 +1,150 @@
+// Package presign generates presigned MinIO/S3 URLs and the matching public
+// object URLs. It has no dependency on the HTTP layer, so it can be embedded
+// in other Go services.
+package presign
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Public URL styles.
+const (
+	// StylePath addresses objects as <public URL>/<bucket>/<key>.
+	StylePath = "path"
+	// StyleVHost addresses objects as <scheme>://<bucket>.<host>/<key>.
+	StyleVHost = "vhost"
+)
+
+// Client is the subset of *minio.Client needed for signing.
+type Client interface {
+	PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error)
+}
+
+// Signer presigns requests against a single bucket.
+type Signer struct {
+	Client Client
+	Bucket string
+	// PublicURL is the base URL clients use to read objects.
+	PublicURL string
+	// URLStyle is StylePath (the default when empty) or StyleVHost.
+	URLStyle string
+	// HTTPClient performs requests made by Upload; nil means
+	// http.DefaultClient.
+	HTTPClient *http.Client
+
+	// base is PublicURL as parsed by New, which saves ObjectURL parsing it
+	// on every call. It is only used while PublicURL still equals baseRaw.
+	base    *url.URL
+	baseRaw string
+}
+
+// New returns a path-style Signer for bucket.
+func New(client Client, bucket, publicURL string) *Signer {
+	s := &Signer{
+		Client:    client,
+		Bucket:    bucket,
+		PublicURL: publicURL,
+		URLStyle:  StylePath,
+		baseRaw:   publicURL,
+	}
+	s.base, _ = url.Parse(publicURL)
+	return s
+}
+
+// PutURL presigns an upload of key with the given content type, which the
+// client must send as its Content-Type header.
+func (s *Signer) PutURL(ctx context.Context, key, contentType string, expiry time.Duration) (signed, public string, err error) {
+	headers := make(http.Header)
+	headers.Set("Content-Type", contentType)
+	return s.PutURLWithHeaders(ctx, key, headers, expiry)
+}
+
+// PutURLWithHeaders presigns an upload of key with headers included in the
+// signature, so the client must send each of them verbatim.
+func (s *Signer) PutURLWithHeaders(ctx context.Context, key string, headers http.Header, expiry time.Duration) (signed, public string, err error) {
+	return s.sign(ctx, http.MethodPut, key, expiry, nil, headers)
+}
+
+// GetURL presigns a download of key. reqParams are added to the signed query
+// string, e.g. versionId or response-content-type.
+func (s *Signer) GetURL(ctx context.Context, key string, expiry time.Duration, reqParams url.Values) (signed, public string, err error) {
+	return s.sign(ctx, http.MethodGet, key, expiry, reqParams, nil)
+}
+
+// HeadURL presigns a HEAD request for key.
+func (s *Signer) HeadURL(ctx context.Context, key string, expiry time.Duration, reqParams url.Values) (signed, public string, err error) {
+	return s.sign(ctx, http.MethodHead, key, expiry, reqParams, nil)
+}
+
+// HeadURLWithHeaders presigns a HEAD request for key with headers included
+// in the signature, e.g. a Range probe.
+func (s *Signer) HeadURLWithHeaders(ctx context.Context, key string, headers http.Header, expiry time.Duration) (signed, public string, err error) {
+	return s.sign(ctx, http.MethodHead, key, expiry, nil, headers)
+}
+
+// DeleteURL presigns a DELETE request for key.
+func (s *Signer) DeleteURL(ctx context.Context, key string, expiry time.Duration) (signed, public string, err error) {
+	return s.sign(ctx, http.MethodDelete, key, expiry, nil, nil)
+}
+
+func (s *Signer) sign(ctx context.Context, method, key string, expiry time.Duration, reqParams url.Values, headers http.Header) (string, string, error) {
+	u, err := s.Client.PresignHeader(ctx, method, s.Bucket, key, expiry, reqParams, headers)
+	if err != nil {
+		return "", "", err
+	}
+	return u.String(), s.ObjectURL(key), nil
+}
+
+// ObjectURL returns the public (unsigned) URL of key. The key is
+// percent-encoded per path segment, so "my file.jpg" becomes
+// "my%20file.jpg". Slashes where the public URL, bucket and key meet are
+// collapsed, so a PublicURL with a trailing slash does not produce "//".
+func (s *Signer) ObjectURL(key string) string {
+	base := s.base
+	if base == nil || s.baseRaw != s.PublicURL {
+		var err error
+		if base, err = url.Parse(s.PublicURL); err != nil {
+			return joinURLPath(s.PublicURL, s.Bucket, (&url.URL{Path: key}).EscapedPath())
+		}
+	}
+	u := *base
+	if s.URLStyle == StyleVHost && u.Host != "" {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = joinURLPath(u.Path, key)
+	} else {
+		u.Path = joinURLPath(u.Path, s.Bucket, key)
+	}
+	// Path holds the unescaped form; clearing RawPath makes String encode it.
+	u.RawPath = ""
+	return u.String()
+}
+
+// joinURLPath joins base and elems with single slashes. Unlike path.Join it
+// leaves the inside of each element alone, since object keys may contain
+// repeated slashes or dots that are significant.
+func joinURLPath(base string, elems ...string) string {
+	base = strings.TrimRight(base, "/")
+	n := len(base)
+	for _, elem := range elems {
+		n += 1 + len(elem)
+	}
+	var b strings.Builder
+	b.Grow(n)
+	b.WriteString(base)
+	for _, elem := range elems {
+		b.WriteByte('/')
+		b.WriteString(strings.TrimLeft(elem, "/"))
+	}
+	return b.String()
+}
+
+// VHostCompatible reports whether bucket can be used as a subdomain. Dotted
+// bucket names break wildcard TLS certificates, so they are only allowed
+// over plain http.
+func VHostCompatible(scheme, bucket string) bool {
+	return scheme != "https" || !strings.Contains(bucket, ".")
+}
@@ -0,0 +1,166 @@
+package presign
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient records the last presign call and returns a fixed URL.
+type fakeClient struct {
+	method    string
+	bucket    string
+	object    string
+	expires   time.Duration
+	reqParams url.Values
+	headers   http.Header
+	err       error
+	// base overrides the scheme and host of returned URLs.
+	base string
+}
+
+func (f *fakeClient) PresignHeader(_ context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	f.method, f.bucket, f.object, f.expires = method, bucketName, objectName, expires
+	f.reqParams, f.headers = reqParams, extraHeaders
+	if f.err != nil {
+		return nil, f.err
+	}
+	u := &url.URL{Scheme: "http", Host: "minio:9000", Path: "/" + bucketName + "/" + objectName, RawQuery: "X-Amz-Signature=sig"}
+	if f.base != "" {
+		base, _ := url.Parse(f.base)
+		u.Scheme, u.Host = base.Scheme, base.Host
+	}
+	return u, nil
+}
+
+func TestPutURL(t *testing.T) {
+	client := &fakeClient{}
+	s := New(client, "uploads", "https://cdn.example.com")
+
+	signed, public, err := s.PutURL(context.Background(), "a/photo.jpg", "image/jpeg", 2*time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://minio:9000/uploads/a/photo.jpg?X-Amz-Signature=sig", signed)
+	assert.Equal(t, "https://cdn.example.com/uploads/a/photo.jpg", public)
+	assert.Equal(t, http.MethodPut, client.method)
+	assert.Equal(t, "uploads", client.bucket)
+	assert.Equal(t, 2*time.Minute, client.expires)
+	assert.Equal(t, "image/jpeg", client.headers.Get("Content-Type"))
+}
+
+func TestGetHeadDeleteURL(t *testing.T) {
+	client := &fakeClient{}
+	s := New(client, "uploads", "https://cdn.example.com")
+	ctx := context.Background()
+
+	params := url.Values{"versionId": {"v1"}}
+	_, _, err := s.GetURL(ctx, "a.txt", time.Minute, params)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodGet, client.method)
+	assert.Equal(t, params, client.reqParams)
+	assert.Nil(t, client.headers)
+
+	_, _, err = s.HeadURL(ctx, "a.txt", time.Minute, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodHead, client.method)
+
+	_, _, err = s.HeadURLWithHeaders(ctx, "a.txt", http.Header{"Range": {"bytes=0-0"}}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodHead, client.method)
+	assert.Equal(t, "bytes=0-0", client.headers.Get("Range"))
+
+	_, _, err = s.DeleteURL(ctx, "a.txt", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, client.method)
+}
+
+func TestSignError(t *testing.T) {
+	s := New(&fakeClient{err: errors.New("boom")}, "uploads", "https://cdn.example.com")
+
+	signed, public, err := s.PutURL(context.Background(), "a.txt", "text/plain", time.Minute)
+	assert.EqualError(t, err, "boom")
+	assert.Empty(t, signed)
+	assert.Empty(t, public)
+}
+
+func TestObjectURL(t *testing.T) {
+	s := New(nil, "uploads", "https://cdn.example.com")
+	assert.Equal(t, "https://cdn.example.com/uploads/a.jpg", s.ObjectURL("a.jpg"))
+
+	s.URLStyle = StyleVHost
+	assert.Equal(t, "https://uploads.cdn.example.com/a.jpg", s.ObjectURL("a.jpg"))
+	assert.Equal(t, "https://uploads.cdn.example.com/b.jpg", s.ObjectURL("b.jpg"), "the parsed base is not modified")
+
+	// PublicURL may be changed after New.
+	s.PublicURL = "http://other.example.com"
+	assert.Equal(t, "http://uploads.other.example.com/a.jpg", s.ObjectURL("a.jpg"))
+}
+
+func TestObjectURL_Slashes(t *testing.T) {
+	testCases := []struct {
+		publicURL   string
+		style       string
+		key         string
+		expectedURL string
+	}{
+		{"https://cdn.example.com/", StylePath, "a.jpg", "https://cdn.example.com/uploads/a.jpg"},
+		{"https://cdn.example.com//", StylePath, "a.jpg", "https://cdn.example.com/uploads/a.jpg"},
+		{"https://cdn.example.com/assets", StylePath, "a.jpg", "https://cdn.example.com/assets/uploads/a.jpg"},
+		{"https://cdn.example.com/assets/", StylePath, "a.jpg", "https://cdn.example.com/assets/uploads/a.jpg"},
+		{"https://cdn.example.com/", StyleVHost, "a.jpg", "https://uploads.cdn.example.com/a.jpg"},
+		{"https://cdn.example.com/assets/", StyleVHost, "a.jpg", "https://uploads.cdn.example.com/assets/a.jpg"},
+		{"https://cdn.example.com", StylePath, "dir//a.jpg", "https://cdn.example.com/uploads/dir//a.jpg"},
+	}
+
+	for _, tc := range testCases {
+		s := New(nil, "uploads", tc.publicURL)
+		s.URLStyle = tc.style
+		assert.Equal(t, tc.expectedURL, s.ObjectURL(tc.key), "%s %s", tc.publicURL, tc.style)
+	}
+}
+
+func TestObjectURL_Escaping(t *testing.T) {
+	testCases := []struct {
+		key         string
+		expectedURL string
+	}{
+		{"my file.jpg", "https://cdn.example.com/uploads/my%20file.jpg"},
+		{"dir/my file.jpg", "https://cdn.example.com/uploads/dir/my%20file.jpg"},
+		{"café/日本.png", "https://cdn.example.com/uploads/caf%C3%A9/%E6%97%A5%E6%9C%AC.png"},
+		{"what?#100%.txt", "https://cdn.example.com/uploads/what%3F%23100%25.txt"},
+	}
+
+	for _, tc := range testCases {
+		for _, style := range []string{StylePath, StyleVHost} {
+			s := New(nil, "uploads", "https://cdn.example.com")
+			s.URLStyle = style
+			public := s.ObjectURL(tc.key)
+			if style == StylePath {
+				assert.Equal(t, tc.expectedURL, public)
+			}
+
+			// The URL must parse back to the original key.
+			u, err := url.Parse(public)
+			require.NoError(t, err)
+			assert.Empty(t, u.RawQuery, public)
+			assert.Empty(t, u.Fragment, public)
+			expectedPath := "/uploads/" + tc.key
+			if style == StyleVHost {
+				expectedPath = "/" + tc.key
+			}
+			assert.Equal(t, expectedPath, u.Path, public)
+		}
+	}
+}
+
+func TestVHostCompatible(t *testing.T) {
+	assert.True(t, VHostCompatible("https", "uploads"))
+	assert.True(t, VHostCompatible("http", "my.bucket"))
+	assert.False(t, VHostCompatible("https", "my.bucket"))
+}
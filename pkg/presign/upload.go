@@ -0,0 +1,63 @@
+package presign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UploadExpiry is the lifetime of the URL presigned by Upload. It only has
+// to outlast the start of the request, but is generous so slow proxies and
+// retries inside the HTTP client do not hit an expired signature.
+const UploadExpiry = 15 * time.Minute
+
+// maxErrorBody caps how much of a failed upload's response is quoted in the
+// returned error.
+const maxErrorBody = 512
+
+// Upload stores size bytes read from r at key by presigning a PUT and
+// performing it with HTTPClient (http.DefaultClient when nil). The reader is
+// streamed, never buffered in full. S3 requires the length up front, so
+// size must not be negative.
+func (s *Signer) Upload(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		return errors.New("presign: upload size must be known")
+	}
+
+	signed, _, err := s.PutURL(ctx, key, contentType, UploadExpiry)
+	if err != nil {
+		return err
+	}
+
+	// NopCloser hides any Len/Seek methods from net/http so that the body is
+	// streamed with the explicit ContentLength below.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, signed, io.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("presign: upload of %s failed: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
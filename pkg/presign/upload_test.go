@@ -0,0 +1,58 @@
+package presign
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload(t *testing.T) {
+	var gotMethod, gotPath, gotType, gotBody string
+	var gotLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		gotLength = r.ContentLength
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	client := &fakeClient{base: server.URL}
+	s := New(client, "uploads", "https://cdn.example.com")
+	s.HTTPClient = server.Client()
+
+	// strings.Reader has Len, which must not stop the body from streaming.
+	err := s.Upload(context.Background(), "a/notes.txt", strings.NewReader("hello"), 5, "text/plain")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPut, gotMethod)
+	assert.Equal(t, "/uploads/a/notes.txt", gotPath)
+	assert.Equal(t, "text/plain", gotType)
+	assert.Equal(t, int64(5), gotLength)
+	assert.Equal(t, "hello", gotBody)
+	assert.Equal(t, UploadExpiry, client.expires)
+	assert.Equal(t, "text/plain", client.headers.Get("Content-Type"))
+}
+
+func TestUpload_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+	}))
+	defer server.Close()
+
+	s := New(&fakeClient{base: server.URL}, "uploads", "https://cdn.example.com")
+	err := s.Upload(context.Background(), "a.txt", strings.NewReader("x"), 1, "text/plain")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+
+	err = s.Upload(context.Background(), "a.txt", strings.NewReader("x"), -1, "text/plain")
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// BucketPlaceholder is replaced with the bucket name in the policy template.
+const BucketPlaceholder = "{bucket}"
+
+// bucketPolicyTemplate is the policy applied to buckets created at startup
+// (MIRAIO_BUCKET_POLICY_FILE). Empty means created buckets keep the
+// server's default policy.
+var bucketPolicyTemplate string
+
+// bucketPolicy is the subset of an S3 bucket policy document needed to add
+// a statement without disturbing the others.
+type bucketPolicy struct {
+	Version   string            `json:"Version"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// policyStatement is a single statement. Principal, Action and Resource may
+// be a string or a list in S3 policies, so they are decoded loosely.
+type policyStatement struct {
+	Effect    string      `json:"Effect"`
+	Principal interface{} `json:"Principal"`
+	Action    interface{} `json:"Action"`
+	Resource  interface{} `json:"Resource"`
+}
+
+// publicReadResource is the ARN covering every object this deployment
+// writes: the whole bucket, or only the key prefix when one is configured.
+func publicReadResource() string {
+	return fmt.Sprintf("arn:aws:s3:::%s/%s*", bucketName, keyPrefix)
+}
+
+// addPublicReadStatement returns existing with an anonymous s3:GetObject
+// statement for resource added. changed is false when an equivalent
+// statement is already present.
+func addPublicReadStatement(existing, resource string) (updated string, changed bool, err error) {
+	policy := bucketPolicy{Version: "2012-10-17"}
+	if existing != "" {
+		if err := json.Unmarshal([]byte(existing), &policy); err != nil {
+			return "", false, fmt.Errorf("parsing existing bucket policy: %w", err)
+		}
+	}
+
+	for _, raw := range policy.Statement {
+		var st policyStatement
+		if err := json.Unmarshal(raw, &st); err != nil {
+			continue
+		}
+		if st.Effect == "Allow" && anonymousPrincipal(st.Principal) &&
+			containsString(st.Action, "s3:GetObject") && containsString(st.Resource, resource) {
+			return existing, false, nil
+		}
+	}
+
+	statement, err := json.Marshal(map[string]interface{}{
+		"Effect":    "Allow",
+		"Principal": map[string][]string{"AWS": {"*"}},
+		"Action":    []string{"s3:GetObject"},
+		"Resource":  []string{resource},
+	})
+	if err != nil {
+		return "", false, err
+	}
+	policy.Statement = append(policy.Statement, statement)
+
+	out, err := json.Marshal(policy)
+	if err != nil {
+		return "", false, err
+	}
+	return string(out), true, nil
+}
+
+// anonymousPrincipal reports whether p is "*" or {"AWS": "*"/["*"]}.
+func anonymousPrincipal(p interface{}) bool {
+	switch v := p.(type) {
+	case string:
+		return v == "*"
+	case map[string]interface{}:
+		return containsString(v["AWS"], "*")
+	}
+	return false
+}
+
+// containsString reports whether v, a JSON string or list of strings,
+// contains s.
+func containsString(v interface{}, s string) bool {
+	switch v := v.(type) {
+	case string:
+		return v == s
+	case []interface{}:
+		for _, item := range v {
+			if item == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ensurePublicRead makes the bucket's objects anonymously readable so that
+// publicUrl links work. It is idempotent: an existing equivalent statement
+// is left alone.
+func ensurePublicRead() error {
+	ctx, cancel := context.WithTimeout(context.Background(), StartupCheckTimeout)
+	defer cancel()
+
+	existing, err := minioClient.GetBucketPolicy(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("reading policy of bucket %s: %w", bucketName, err)
+	}
+
+	resource := publicReadResource()
+	updated, changed, err := addPublicReadStatement(existing, resource)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		utils.LogInfo("Public-read policy for %s already present", resource)
+		return nil
+	}
+
+	if err := minioClient.SetBucketPolicy(ctx, bucketName, updated); err != nil {
+		return fmt.Errorf("applying public-read policy to bucket %s: %w", bucketName, err)
+	}
+	utils.LogInfo("Applied public-read policy for %s", resource)
+	return nil
+}
+
+// loadBucketPolicyTemplate reads a policy template from path and checks that
+// it renders to a valid policy, so a broken file fails at startup instead
+// of when a bucket is created.
+func loadBucketPolicyTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading MIRAIO_BUCKET_POLICY_FILE: %w", err)
+	}
+	tmpl := string(data)
+	if _, err := renderBucketPolicy(tmpl, "bucket"); err != nil {
+		return "", fmt.Errorf("MIRAIO_BUCKET_POLICY_FILE %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// renderBucketPolicy substitutes bucket for every {bucket} in tmpl and
+// returns the result if it is a policy document with at least one
+// statement.
+func renderBucketPolicy(tmpl, bucket string) (string, error) {
+	rendered := strings.ReplaceAll(tmpl, BucketPlaceholder, bucket)
+	var policy bucketPolicy
+	if err := json.Unmarshal([]byte(rendered), &policy); err != nil {
+		return "", fmt.Errorf("invalid policy JSON: %w", err)
+	}
+	if len(policy.Statement) == 0 {
+		return "", fmt.Errorf("policy has no statements")
+	}
+	return rendered, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPublicReadStatement(t *testing.T) {
+	resource := "arn:aws:s3:::uploads/*"
+
+	t.Run("Empty policy", func(t *testing.T) {
+		updated, changed, err := addPublicReadStatement("", resource)
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		var policy bucketPolicy
+		require.NoError(t, json.Unmarshal([]byte(updated), &policy))
+		assert.Equal(t, "2012-10-17", policy.Version)
+		require.Len(t, policy.Statement, 1)
+		assert.Contains(t, string(policy.Statement[0]), "s3:GetObject")
+	})
+
+	t.Run("Idempotent", func(t *testing.T) {
+		first, _, err := addPublicReadStatement("", resource)
+		require.NoError(t, err)
+
+		second, changed, err := addPublicReadStatement(first, resource)
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("Recognizes string forms", func(t *testing.T) {
+		existing := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::uploads/*"}]}`
+		_, changed, err := addPublicReadStatement(existing, resource)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("Keeps other statements", func(t *testing.T) {
+		existing := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:ListBucket"],"Resource":["arn:aws:s3:::uploads"]}]}`
+		updated, changed, err := addPublicReadStatement(existing, resource)
+		require.NoError(t, err)
+		assert.True(t, changed)
+
+		var policy bucketPolicy
+		require.NoError(t, json.Unmarshal([]byte(updated), &policy))
+		require.Len(t, policy.Statement, 2)
+		assert.Contains(t, string(policy.Statement[0]), "s3:ListBucket")
+	})
+
+	t.Run("Invalid existing policy", func(t *testing.T) {
+		_, _, err := addPublicReadStatement("{", resource)
+		assert.Error(t, err)
+	})
+}
+
+func TestPublicReadResource(t *testing.T) {
+	setupTestEnvironment()
+	defer func() { keyPrefix = "" }()
+
+	assert.Equal(t, "arn:aws:s3:::test-bucket/*", publicReadResource())
+	keyPrefix = "teamA/"
+	assert.Equal(t, "arn:aws:s3:::test-bucket/teamA/*", publicReadResource())
+}
+
+func TestRenderBucketPolicy(t *testing.T) {
+	tmpl := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":"*","Action":"s3:*","Resource":["arn:aws:s3:::{bucket}","arn:aws:s3:::{bucket}/*"],"Condition":{"Bool":{"aws:SecureTransport":"false"}}}]}`
+
+	policy, err := renderBucketPolicy(tmpl, "uploads")
+	require.NoError(t, err)
+	assert.NotContains(t, policy, BucketPlaceholder)
+	assert.Contains(t, policy, `"arn:aws:s3:::uploads"`)
+	assert.Contains(t, policy, `"arn:aws:s3:::uploads/*"`)
+
+	_, err = renderBucketPolicy(`{"Version":"2012-10-17","Statement":[`, "uploads")
+	assert.ErrorContains(t, err, "invalid policy JSON")
+
+	_, err = renderBucketPolicy(`{"Version":"2012-10-17","Statement":[]}`, "uploads")
+	assert.ErrorContains(t, err, "no statements")
+}
+
+func TestLoadBucketPolicyTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::{bucket}/public/*"]}]}`), 0o600))
+	tmpl, err := loadBucketPolicyTemplate(valid)
+	require.NoError(t, err)
+	assert.Contains(t, tmpl, BucketPlaceholder)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`not json`), 0o600))
+	_, err = loadBucketPolicyTemplate(invalid)
+	assert.ErrorContains(t, err, "invalid.json")
+
+	_, err = loadBucketPolicyTemplate(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+// PostFileField is the form field that carries the file in a POST upload.
+// S3 ignores every field after it, so it must come last.
+const PostFileField = "file"
+
+// postPolicyClient signs POST policies for MIRAIO_MINIO_PUBLIC_ENDPOINT when
+// set; nil means minioClient.
+var postPolicyClient *minio.Client
+
+// postPolicyUnsupported are /presign parameters a POST policy cannot carry.
+// minio-go only builds policy conditions for the key, type, size and
+// metadata, so these are refused rather than silently left out of the
+// upload.
+var postPolicyUnsupported = []string{
+	contentMD5Param, cacheControlParam, storageClassParam, sseCustomerKeyParam, "retainUntil",
+}
+
+// presignPostResponse is a ready-to-submit browser form: POST to URL a
+// multipart/form-data body with every entry of Fields, then the file in
+// FileField.
+type presignPostResponse struct {
+	URL       string            `json:"url"`
+	Fields    map[string]string `json:"fields"`
+	FileField string            `json:"fileField" snake:"file_field"`
+	Key       string            `json:"key"`
+	PublicURL string            `json:"publicUrl" snake:"public_url"`
+	Expiry    int               `json:"expiry"`
+	ExpiresAt string            `json:"expiresAt" snake:"expires_at"`
+	Backend   string            `json:"backend,omitempty"`
+	Ref       string            `json:"ref,omitempty"`
+}
+
+// presignPostPolicyHandler signs a POST policy for an upload form. It takes
+// the filename, type, meta.* and expiry parameters of /presign, plus
+// maxSize to cap the upload in bytes. The policy pins the key and
+// Content-Type, so the returned fields must be sent unchanged. Like
+// /presign, it spreads uploads over the configured backends.
+//
+// Parameters in postPolicyUnsupported, tag.* and an SSE-C key header are
+// refused. MIRAIO_DEFAULT_CACHE_CONTROL does not apply either; main warns
+// about that at startup.
+func presignPostPolicyHandler(c *gin.Context) {
+	query := c.Request.URL.Query()
+	for _, param := range postPolicyUnsupported {
+		if query.Has(param) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " is not supported for POST uploads"})
+			return
+		}
+	}
+	if c.GetHeader(SSECustomerKeyHeader) != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SSE-C keys are not supported for POST uploads"})
+		return
+	}
+	params := make(url.Values)
+	for param, values := range query {
+		if strings.HasPrefix(param, tagParamPrefix) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag.* parameters are not supported for POST uploads"})
+			return
+		}
+		if strings.HasPrefix(param, metaParamPrefix) {
+			params[param] = values
+		}
+	}
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:    c.Query("filename"),
+		ContentType: c.Query("type"),
+		Params:      params,
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	expiry := uploadExpiryFor(key, reqParams.Get("Content-Type"))
+	if v := c.Query("expiry"); v != "" {
+		if expiry, err = parseExpiry(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkPrefixExpiry(key, expiry); err != nil {
+			respondUploadError(c, err)
+			return
+		}
+	}
+	var maxSize int64
+	if v := c.Query("maxSize"); v != "" {
+		if maxSize, err = strconv.ParseInt(v, 10, 64); err != nil || maxSize <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maxSize: must be a positive number of bytes"})
+			return
+		}
+	}
+
+	b := pickBackend(c)
+	if !checkScope(c, b, key) {
+		return
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	if key, err = resolveCollision(key, existsOn(ctx, b)); err != nil {
+		respondCollisionError(c, ctx, err)
+		return
+	}
+
+	// Taken before signing so the reported deadline is never later than the
+	// real one.
+	expiresAt := time.Now().Add(expiry).UTC()
+	policy := minio.NewPostPolicy()
+	// These setters only fail on empty values, which prepareUpload rules
+	// out.
+	policy.SetBucket(b.signer.Bucket)
+	policy.SetKey(key)
+	policy.SetExpires(expiresAt)
+	policy.SetContentType(reqParams.Get("Content-Type"))
+	if maxSize > 0 {
+		policy.SetContentLengthRange(0, maxSize)
+	}
+	for header, values := range reqParams {
+		if name, ok := strings.CutPrefix(strings.ToLower(header), "x-amz-meta-"); ok {
+			if err := policy.SetUserMetadata(name, values[0]); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid value for metadata key " + strconv.Quote(name)})
+				return
+			}
+		}
+	}
+
+	// Not retried: signing completes the policy in place, and it only
+	// reaches the network when the bucket region is not configured.
+	signed, fields, err := b.policyClient().PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not generate POST policy")
+		return
+	}
+	webhook.notifyPresign(http.MethodPost, c.Query("filename"), key, reqParams.Get("Content-Type"), c.ClientIP())
+
+	resp := presignPostResponse{
+		URL:       signed.String(),
+		Fields:    fields,
+		FileField: PostFileField,
+		Key:       key,
+		PublicURL: requestPublicURL(c, b.signer, key, b.signer.ObjectURL(key)),
+		Expiry:    int(expiry.Seconds()),
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}
+	if backends.multi() {
+		resp.Backend = b.name
+		resp.Ref = b.ref(key)
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestPostPolicy calls /presign-post with query and decodes a successful
+// response.
+func requestPostPolicy(t *testing.T, query string) (*httptest.ResponseRecorder, presignPostResponse) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/presign-post", presignPostPolicyHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-post?"+query, nil))
+	var resp presignPostResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+// postForm builds the multipart body a browser form would send: every
+// field, then the file last.
+func postForm(t *testing.T, resp presignPostResponse, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range resp.Fields {
+		require.NoError(t, form.WriteField(name, value))
+	}
+	file, err := form.CreateFormFile(resp.FileField, "upload")
+	require.NoError(t, err)
+	_, err = file.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	return &body, form.FormDataContentType()
+}
+
+func TestPresignPostPolicyHandler(t *testing.T) {
+	setupTestEnvironment()
+
+	before := time.Now()
+	w, resp := requestPostPolicy(t, "filename=cat.jpg&type=image/jpeg&meta.owner=ann&maxSize=1000&expiry=300")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, "http://localhost:9000/test-bucket/", resp.URL)
+	assert.Equal(t, "cat.jpg", resp.Key)
+	assert.Equal(t, PostFileField, resp.FileField)
+	assert.Equal(t, "http://localhost:9000/test-bucket/cat.jpg", resp.PublicURL)
+	assert.Equal(t, 300, resp.Expiry)
+	expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, before.Add(300*time.Second), expiresAt, 2*time.Second)
+
+	for _, name := range []string{"bucket", "key", "Content-Type", "x-amz-meta-owner", "policy", "x-amz-algorithm", "x-amz-credential", "x-amz-date", "x-amz-signature"} {
+		assert.NotEmpty(t, resp.Fields[name], name)
+	}
+	assert.Equal(t, "cat.jpg", resp.Fields["key"])
+	assert.Equal(t, "image/jpeg", resp.Fields["Content-Type"])
+	assert.Equal(t, "ann", resp.Fields["x-amz-meta-owner"])
+
+	raw, err := base64.StdEncoding.DecodeString(resp.Fields["policy"])
+	require.NoError(t, err)
+	var policy struct {
+		Expiration string
+		Conditions []any
+	}
+	require.NoError(t, json.Unmarshal(raw, &policy))
+	assert.Contains(t, policy.Conditions, []any{"content-length-range", float64(0), float64(1000)})
+	assert.Contains(t, policy.Conditions, []any{"eq", "$key", "cat.jpg"})
+}
+
+func TestPresignPostPolicyHandler_Invalid(t *testing.T) {
+	setupTestEnvironment()
+
+	for query, want := range map[string]int{
+		"type=image/jpeg":                                                    http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&tag.x=1":                             http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&cacheControl=no-cache":               http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&contentMd5=1B2M2Y8AsgTpgAmY7PhCfg==": http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&storageClass=STANDARD":               http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&retainUntil=2099-01-01T00:00:00Z":    http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&sseCustomerKey=x":                    http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&maxSize=0":                           http.StatusBadRequest,
+		"filename=a.jpg&type=image/jpeg&expiry=-1":                           http.StatusBadRequest,
+	} {
+		w, _ := requestPostPolicy(t, query)
+		assert.Equal(t, want, w.Code, query)
+	}
+}
+
+func TestPresignPostPolicyHandler_SSECustomerKey(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign-post", presignPostPolicyHandler)
+	req := httptest.NewRequest("GET", "/presign-post?filename=a.jpg&type=image/jpeg", nil)
+	req.Header.Set(SSECustomerKeyHeader, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "SSE-C keys are not supported for POST uploads")
+}
+
+func TestPresignPostPolicyHandler_MultipleBackends(t *testing.T) {
+	setupTestEnvironment()
+	withBackends(t, 0, testBackend("eu", 1))
+
+	w, resp := requestPostPolicy(t, "filename=cat.jpg&type=image/jpeg")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "eu-bucket", resp.Fields["bucket"])
+	assert.Equal(t, "http://localhost:9000/eu-bucket/", resp.URL)
+	assert.Equal(t, "http://eu.example.com/eu-bucket/cat.jpg", resp.PublicURL)
+	assert.Equal(t, "eu", resp.Backend)
+	assert.Equal(t, "eu:cat.jpg", resp.Ref)
+}
+
+func TestIntegrationPresignPostPolicyUpload(t *testing.T) {
+	client := integrationClient(t)
+	ctx := context.Background()
+
+	w, resp := requestPostPolicy(t, "filename=post-policy-test.txt&type=text/plain&meta.owner=ann&maxSize=100")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	defer client.RemoveObject(ctx, bucketName, resp.Key, minio.RemoveObjectOptions{})
+
+	body, contentType := postForm(t, resp, []byte("hello from a form"))
+	upload, err := http.Post(resp.URL, contentType, body)
+	require.NoError(t, err)
+	defer upload.Body.Close()
+	require.Equal(t, http.StatusNoContent, upload.StatusCode)
+
+	info, err := client.StatObject(ctx, bucketName, resp.Key, minio.StatObjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("hello from a form")), info.Size)
+	assert.Equal(t, "text/plain", info.ContentType)
+	assert.Equal(t, "ann", info.UserMetadata["Owner"])
+
+	// maxSize is enforced by MinIO.
+	body, contentType = postForm(t, resp, bytes.Repeat([]byte("x"), 101))
+	tooLarge, err := http.Post(resp.URL, contentType, body)
+	require.NoError(t, err)
+	defer tooLarge.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, tooLarge.StatusCode)
+}
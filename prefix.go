@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxPrefixObjects caps how many URLs /presign-prefix returns per call.
+const DefaultMaxPrefixObjects = 100
+
+// prefixObject is one entry of a /presign-prefix response.
+type prefixObject struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+}
+
+// prefixPage is one page of a /presign-prefix response.
+type prefixPage struct {
+	Objects        []prefixObject `json:"objects"`
+	Expiry         int            `json:"expiry"`
+	ExpiresAt      string         `json:"expiresAt" snake:"expires_at"`
+	IsTruncated    bool           `json:"isTruncated" snake:"is_truncated"`
+	NextStartAfter string         `json:"nextStartAfter,omitempty" snake:"next_start_after,omitempty"`
+}
+
+// presignPrefixHandler returns presigned download URLs for the objects under
+// a prefix. Results are capped at MIRAIO_MAX_PREFIX_OBJECTS; when more exist the
+// response sets isTruncated and nextStartAfter, which the client passes back
+// as startAfter to fetch the next page.
+func presignPrefixHandler(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing prefix"})
+		return
+	}
+	fullPrefix, err := objectKey(prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkScope(c, backends.all()[0], fullPrefix) {
+		return
+	}
+
+	expiry, err := parseExpiry(c.Query("expiry"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startAfter := ""
+	if v := c.Query("startAfter"); v != "" {
+		if startAfter, err = objectKey(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	store := backends.all()[0].storage()
+	keys, truncated, err := store.List(ctx, fullPrefix, startAfter, currentConfig().maxPrefixObjects)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not list objects")
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(expiry)
+	objects := make([]prefixObject, 0, len(keys))
+	for _, key := range keys {
+		reqParams := make(url.Values)
+		addDownloadDisposition(reqParams, key, "")
+		signed, err := store.PresignGet(ctx, key, expiry, reqParams)
+		if err != nil {
+			respondBackendError(c, ctx, err, "Could not generate presigned URL")
+			return
+		}
+		if t := urlExpiresAt(signed, now, expiry); t.Before(expiresAt) {
+			expiresAt = t
+		}
+		objects = append(objects, prefixObject{Key: strings.TrimPrefix(key, keyPrefix), URL: signed})
+	}
+
+	webhook.notifyPrefix(prefix, fullPrefix, len(objects), c.ClientIP())
+
+	resp := prefixPage{
+		Objects:     objects,
+		Expiry:      int(expiry.Seconds()),
+		ExpiresAt:   expiresAt.UTC().Format(time.RFC3339),
+		IsTruncated: truncated,
+	}
+	if truncated {
+		resp.NextStartAfter = objects[len(objects)-1].Key
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// parseMaxPrefixObjects reads MIRAIO_MAX_PREFIX_OBJECTS.
+func parseMaxPrefixObjects(v string) (int, error) {
+	if v == "" {
+		return DefaultMaxPrefixObjects, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("MIRAIO_MAX_PREFIX_OBJECTS must be a positive integer, got %q", v)
+	}
+	return n, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type prefixResponse struct {
+	Objects        []prefixObject `json:"objects"`
+	IsTruncated    bool           `json:"isTruncated"`
+	NextStartAfter string         `json:"nextStartAfter"`
+}
+
+func TestPresignPrefixHandler_Validation(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign-prefix", presignPrefixHandler)
+
+	for query, expected := range map[string]string{
+		"":                            "Missing prefix",
+		"?prefix=../":                 "Invalid filename",
+		"?prefix=album1/&expiry=zero": "Invalid expiry",
+	} {
+		req, err := http.NewRequest("GET", "/presign-prefix"+query, nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+		assert.Contains(t, recorder.Body.String(), expected, query)
+	}
+}
+
+func TestParseMaxPrefixObjects(t *testing.T) {
+	n, err := parseMaxPrefixObjects("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxPrefixObjects, n)
+
+	_, err = parseMaxPrefixObjects("0")
+	assert.Error(t, err)
+}
+
+func TestIntegrationPresignPrefixPaging(t *testing.T) {
+	client := integrationClient(t)
+	withConfig(t, func(cfg *reloadableConfig) { cfg.maxPrefixObjects = 2 })
+
+	ctx := context.Background()
+	keys := []string{"album-test/1.jpg", "album-test/2.jpg", "album-test/3.jpg"}
+	for _, key := range keys {
+		_, err := client.PutObject(ctx, bucketName, key, strings.NewReader("x"), 1, minio.PutObjectOptions{})
+		require.NoError(t, err)
+		defer client.RemoveObject(ctx, bucketName, key, minio.RemoveObjectOptions{})
+	}
+
+	router := gin.New()
+	router.GET("/presign-prefix", presignPrefixHandler)
+
+	fetch := func(query string) prefixResponse {
+		req, err := http.NewRequest("GET", "/presign-prefix?prefix=album-test/"+query, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var resp prefixResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		return resp
+	}
+
+	first := fetch("")
+	require.Len(t, first.Objects, 2)
+	assert.True(t, first.IsTruncated)
+	assert.Equal(t, keys[1], first.NextStartAfter)
+
+	second := fetch("&startAfter=" + first.NextStartAfter)
+	require.Len(t, second.Objects, 1)
+	assert.False(t, second.IsTruncated)
+	assert.Equal(t, keys[2], second.Objects[0].Key)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errTypeNotAllowed rejects uploads whose content type the matching prefix
+// policy does not allow; HTTP handlers answer it with 415.
+var errTypeNotAllowed = errors.New("Content type not allowed")
+
+// errExpiryNotAllowed rejects upload expiries longer than the matching
+// prefix policy allows; HTTP handlers answer it with 403.
+var errExpiryNotAllowed = errors.New("Expiry not allowed")
+
+// prefixPolicy constrains uploads whose object key starts with prefix.
+type prefixPolicy struct {
+	prefix string
+	// types are MIME patterns as in MIRAIO_EXPIRY_BY_TYPE; "*/*" allows
+	// any type.
+	types []string
+	// expiry is the lifetime of upload URLs and the longest a client may
+	// ask for. Zero keeps the global defaults.
+	expiry time.Duration
+}
+
+// prefixPolicies is ordered longest prefix first, so the first match is the
+// most specific one.
+type prefixPolicies []prefixPolicy
+
+// parsePrefixPolicies reads MIRAIO_PREFIX_POLICIES, a comma-separated list
+// of prefix=types:seconds entries where types are |-separated MIME
+// patterns, e.g. "avatars/=image/*:120,exports/=*/*:3600". The :seconds
+// part may be left out.
+func parsePrefixPolicies(v string) (prefixPolicies, error) {
+	var policies prefixPolicies
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, rule, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid entry %q: want prefix=types:seconds", entry)
+		}
+		if slices.ContainsFunc(policies, func(p prefixPolicy) bool { return p.prefix == prefix }) {
+			return nil, fmt.Errorf("duplicate prefix %q", prefix)
+		}
+
+		types, seconds, hasExpiry := strings.Cut(rule, ":")
+		p := prefixPolicy{prefix: prefix}
+		for _, pattern := range strings.Split(types, "|") {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if !validMIMEPattern(pattern) {
+				return nil, fmt.Errorf("invalid type %q in %q", pattern, entry)
+			}
+			p.types = append(p.types, pattern)
+		}
+		if hasExpiry {
+			n, err := strconv.Atoi(strings.TrimSpace(seconds))
+			if err != nil || !validExpirySeconds(n) {
+				return nil, fmt.Errorf("invalid expiry in %q: must be between %d and %d seconds", entry, int(MinExpiry.Seconds()), int(MaxExpiry.Seconds()))
+			}
+			p.expiry = time.Duration(n) * time.Second
+		}
+		policies = append(policies, p)
+	}
+	sort.SliceStable(policies, func(i, j int) bool { return len(policies[i].prefix) > len(policies[j].prefix) })
+	return policies, nil
+}
+
+// match returns the policy with the longest prefix of key, or nil. Keys
+// include MIRAIO_KEY_PREFIX.
+func (ps prefixPolicies) match(key string) *prefixPolicy {
+	for i := range ps {
+		if strings.HasPrefix(key, ps[i].prefix) {
+			return &ps[i]
+		}
+	}
+	return nil
+}
+
+// mediaTypePatterns lists the patterns contentType matches, most specific
+// first: the exact type, type/* and */*.
+func mediaTypePatterns(contentType string) []string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+	typ, _, _ := strings.Cut(mediaType, "/")
+	return []string{mediaType, typ + "/*", "*/*"}
+}
+
+// checkPrefixType returns errTypeNotAllowed when the policy matching key
+// does not allow contentType.
+func checkPrefixType(key, contentType string) error {
+	p := currentConfig().prefixPolicies.match(key)
+	if p == nil {
+		return nil
+	}
+	for _, pattern := range mediaTypePatterns(contentType) {
+		if slices.Contains(p.types, pattern) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w under %s: use %s", errTypeNotAllowed, p.prefix, strings.Join(p.types, ", "))
+}
+
+// checkPrefixExpiry returns errExpiryNotAllowed when expiry is longer than
+// the policy matching key allows.
+func checkPrefixExpiry(key string, expiry time.Duration) error {
+	if p := currentConfig().prefixPolicies.match(key); p != nil && p.expiry > 0 && expiry > p.expiry {
+		return fmt.Errorf("%w under %s: at most %d seconds", errExpiryNotAllowed, p.prefix, int(p.expiry.Seconds()))
+	}
+	return nil
+}
+
+// uploadExpiryFor returns the lifetime of an upload URL for key: the
+// expiry of the matching prefix policy, or else uploadExpiry.
+func uploadExpiryFor(key, contentType string) time.Duration {
+	if p := currentConfig().prefixPolicies.match(key); p != nil && p.expiry > 0 {
+		return p.expiry
+	}
+	return uploadExpiry(contentType)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrefixPolicies(t *testing.T) {
+	policies, err := parsePrefixPolicies("exports/=*/*:3600, avatars/=image/png|image/jpeg:120, avatars/team/=image/*")
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+	assert.Equal(t, "avatars/team/", policies[0].prefix, "longest prefix first")
+	assert.Zero(t, policies[0].expiry)
+	avatars := policies.match("avatars/a.png")
+	require.NotNil(t, avatars)
+	assert.Equal(t, []string{"image/png", "image/jpeg"}, avatars.types)
+	assert.Equal(t, 120*time.Second, avatars.expiry)
+
+	for _, bad := range []string{
+		"avatars/",
+		"=image/*:120",
+		"avatars/=image:120",
+		"avatars/=image/*:0",
+		"avatars/=image/*:604801",
+		"avatars/=image/*,avatars/=*/*",
+	} {
+		_, err := parsePrefixPolicies(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPrefixPolicyChecks(t *testing.T) {
+	withConfig(t, func(cfg *reloadableConfig) {
+		cfg.prefixPolicies, _ = parsePrefixPolicies("avatars/=image/*:120,avatars/raw/=*/*,exports/=*/*:3600")
+		cfg.expiryByType = map[string]time.Duration{"text/*": 30 * time.Second}
+	})
+
+	assert.NoError(t, checkPrefixType("avatars/a.png", "image/png"))
+	assert.ErrorIs(t, checkPrefixType("avatars/a.txt", "text/plain"), errTypeNotAllowed)
+	assert.NoError(t, checkPrefixType("avatars/raw/a.txt", "text/plain"), "the longer prefix wins")
+	assert.NoError(t, checkPrefixType("other/a.txt", "text/plain"))
+
+	assert.Equal(t, 120*time.Second, uploadExpiryFor("avatars/a.png", "image/png"))
+	assert.Equal(t, 30*time.Second, uploadExpiryFor("avatars/raw/a.txt", "text/plain"), "no policy expiry falls back to the type")
+	assert.Equal(t, DefaultExpiry, uploadExpiryFor("other/a.bin", "application/octet-stream"))
+
+	assert.NoError(t, checkPrefixExpiry("avatars/a.png", 60*time.Second))
+	assert.ErrorIs(t, checkPrefixExpiry("avatars/a.png", 600*time.Second), errExpiryNotAllowed)
+	assert.NoError(t, checkPrefixExpiry("other/a.png", MaxExpiry))
+}
+
+func TestPresignHandlers_PrefixPolicy(t *testing.T) {
+	setupTestEnvironment()
+	withConfig(t, func(cfg *reloadableConfig) {
+		cfg.prefixPolicies, _ = parsePrefixPolicies("avatars/=image/*:120,exports/=*/*:3600")
+	})
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.POST("/presign", presignPostHandler)
+	router.GET("/upload-bundle", uploadBundleHandler)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		expiry string
+	}{
+		{"Allowed type", "GET", "/presign?filename=avatars/a.png&type=image/png", "", http.StatusOK, `"expiry":120`},
+		{"Type not allowed", "GET", "/presign?filename=avatars/a.pdf&type=application/pdf", "", http.StatusUnsupportedMediaType, ""},
+		{"Any type", "GET", "/presign?filename=exports/a.csv&type=text/csv", "", http.StatusOK, `"expiry":3600`},
+		{"No policy", "GET", "/presign?filename=docs/a.pdf&type=application/pdf", "", http.StatusOK, `"expiry":60`},
+		{"Expiry within policy", "POST", "/presign", `{"filename":"avatars/a.png","type":"image/png","expiry":60}`, http.StatusOK, `"expiry":60`},
+		{"Expiry beyond policy", "POST", "/presign", `{"filename":"avatars/a.png","type":"image/png","expiry":600}`, http.StatusForbidden, ""},
+		{"Bundle expiry beyond policy", "GET", "/upload-bundle?filename=avatars/a.png&type=image/png&expiry=600", "", http.StatusForbidden, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.expiry != "" {
+				assert.Contains(t, w.Body.String(), tc.expiry)
+			}
+		})
+	}
+}
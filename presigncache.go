@@ -0,0 +1,156 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirago/miraio/pkg/presign"
+)
+
+// PresignCacheReuseFraction is the share of a URL's lifetime during which a
+// cached copy may be handed out. Later requests get a fresh signature, so a
+// client never receives a URL with much less time left than it asked for.
+const PresignCacheReuseFraction = 0.1
+
+// presignCacheSize is the number of presigned URLs kept for reuse
+// (MIRAIO_PRESIGN_CACHE_SIZE). Zero disables the cache.
+var presignCacheSize int
+
+// presignCache is a fixed-size LRU of presigned URLs.
+type presignCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type presignCacheEntry struct {
+	key      string
+	url      url.URL
+	reuseTil time.Time
+}
+
+func newPresignCache(size int) *presignCache {
+	return &presignCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached URL for key while it may still be reused.
+func (c *presignCache) get(key string) (*url.URL, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*presignCacheEntry)
+	if !c.now().Before(entry.reuseTil) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	u := entry.url
+	return &u, true
+}
+
+// add stores u, signed now for expires, evicting the least recently used
+// entry when full.
+func (c *presignCache) add(key string, u *url.URL, expires time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &presignCacheEntry{
+		key:      key,
+		url:      *u,
+		reuseTil: c.now().Add(time.Duration(float64(expires) * PresignCacheReuseFraction)),
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*presignCacheEntry).key)
+	}
+}
+
+// sweep drops entries that may no longer be reused.
+func (c *presignCache) sweep(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := 0
+	for key, el := range c.entries {
+		if !now.Before(el.Value.(*presignCacheEntry).reuseTil) {
+			c.order.Remove(el)
+			delete(c.entries, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// presignCacheKey identifies a signature: everything that is signed must be
+// part of the key, including headers such as Content-Type and metadata.
+func presignCacheKey(method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\x00%s\x00%s\x00%s\x00%s", method, bucketName, objectName, strconv.FormatInt(int64(expires), 10), reqParams.Encode())
+
+	names := make([]string, 0, len(extraHeaders))
+	for name := range extraHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\x00%s=%s", strings.ToLower(name), strings.Join(extraHeaders[name], ","))
+	}
+	return b.String()
+}
+
+// cacheClient serves repeated presign calls from a presignCache.
+type cacheClient struct {
+	presign.Client
+	cache *presignCache
+}
+
+func (c cacheClient) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	key := presignCacheKey(method, bucketName, objectName, expires, reqParams, extraHeaders)
+	if u, ok := c.cache.get(key); ok {
+		return u, nil
+	}
+	u, err := c.Client.PresignHeader(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.add(key, u, expires)
+	return u, nil
+}
+
+// withPresignCache wraps c with an LRU of presigned URLs when the cache is
+// enabled.
+func withPresignCache(c presign.Client) presign.Client {
+	if presignCacheSize == 0 {
+		return c
+	}
+	cache := newPresignCache(presignCacheSize)
+	janitor.register("presign cache", cache)
+	return cacheClient{Client: c, cache: cache}
+}
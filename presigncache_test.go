@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClient returns a distinct URL for every call.
+type countingClient struct {
+	calls int
+}
+
+func (c *countingClient) PresignHeader(_ context.Context, method, bucketName, objectName string, _ time.Duration, _ url.Values, _ http.Header) (*url.URL, error) {
+	c.calls++
+	return &url.URL{Scheme: "http", Host: "minio:9000", Path: "/" + bucketName + "/" + objectName, RawQuery: "sig=" + strconv.Itoa(c.calls)}, nil
+}
+
+func TestPresignCache(t *testing.T) {
+	backend := &countingClient{}
+	cache := newPresignCache(2)
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+	client := cacheClient{Client: backend, cache: cache}
+	ctx := context.Background()
+	jpeg := http.Header{"Content-Type": {"image/jpeg"}}
+
+	first, err := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+	require.NoError(t, err)
+	second, err := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), second.String())
+	assert.Equal(t, 1, backend.calls)
+
+	t.Run("Signed inputs are part of the key", func(t *testing.T) {
+		calls := backend.calls
+		client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, http.Header{"Content-Type": {"image/png"}})
+		client.PresignHeader(ctx, http.MethodGet, "b", "a.jpg", time.Minute, nil, nil)
+		assert.Equal(t, calls+2, backend.calls)
+	})
+
+	t.Run("Evicts least recently used", func(t *testing.T) {
+		calls := backend.calls
+		// The PNG and GET entries above pushed the JPEG PUT out.
+		client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+		assert.Equal(t, calls+1, backend.calls)
+	})
+
+	t.Run("Stale entries are not served", func(t *testing.T) {
+		cached, _ := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+		calls := backend.calls
+
+		now = now.Add(time.Minute / 10)
+		fresh, err := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, backend.calls)
+		assert.NotEqual(t, cached.String(), fresh.String())
+	})
+
+	t.Run("Returned URLs are copies", func(t *testing.T) {
+		u, _ := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+		u.Host = "changed"
+		again, _ := client.PresignHeader(ctx, http.MethodPut, "b", "a.jpg", time.Minute, nil, jpeg)
+		assert.Equal(t, "minio:9000", again.Host)
+	})
+}
+
+func TestWithPresignCache_Disabled(t *testing.T) {
+	presignCacheSize = 0
+	backend := &countingClient{}
+	assert.Equal(t, backend, withPresignCache(backend))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// presignJSONRequest is the body of POST /presign. Expiry is in seconds; when
+// zero the per-type default applies, otherwise it may be at most MaxExpiry.
+// Bucket selects one of the configured backends by bucket name; when empty
+// uploads are spread as for GET.
+// ContentMD5 is the base64 MD5 digest the upload body must match,
+// CacheControl the Cache-Control stored with the object, StorageClass its
+// storage class, and SSECustomerKey a base64 SSE-C key the upload is
+// encrypted with.
+type presignJSONRequest struct {
+	Filename       string            `json:"filename" binding:"required"`
+	Type           string            `json:"type"`
+	Expiry         int               `json:"expiry" binding:"gte=0"`
+	Meta           map[string]string `json:"meta"`
+	Bucket         string            `json:"bucket"`
+	ContentMD5     string            `json:"contentMd5"`
+	CacheControl   string            `json:"cacheControl"`
+	StorageClass   string            `json:"storageClass"`
+	SSECustomerKey string            `json:"sseCustomerKey"`
+}
+
+// presignPostHandler is the JSON-body form of GET /presign, for clients that
+// would rather not encode metadata maps into a query string.
+func presignPostHandler(c *gin.Context) {
+	var req presignJSONRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if fields := fieldErrors(err); fields != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "fields": fields})
+			return
+		}
+		respondBindError(c, err, "Malformed JSON body")
+		return
+	}
+	if !checkBodyExpiry(c, req.Expiry) {
+		return
+	}
+
+	b := pickBackend(c)
+	if req.Bucket != "" {
+		if b = backends.byBucket(req.Bucket); b == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "fields": map[string]string{"bucket": "unknown bucket"}})
+			return
+		}
+	}
+
+	params := make(url.Values)
+	for k, v := range req.Meta {
+		params.Set(metaParamPrefix+k, v)
+	}
+	if req.ContentMD5 != "" {
+		params.Set(contentMD5Param, req.ContentMD5)
+	}
+	if req.CacheControl != "" {
+		params.Set(cacheControlParam, req.CacheControl)
+	}
+	if req.StorageClass != "" {
+		params.Set(storageClassParam, req.StorageClass)
+	}
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:       req.Filename,
+		ContentType:    req.Type,
+		Params:         params,
+		SSECustomerKey: req.SSECustomerKey,
+	})
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+	if !checkScope(c, b, key) {
+		return
+	}
+
+	expiry := uploadExpiryFor(key, reqParams.Get("Content-Type"))
+	if req.Expiry > 0 {
+		expiry = time.Duration(req.Expiry) * time.Second
+		if err := checkPrefixExpiry(key, expiry); err != nil {
+			respondUploadError(c, err)
+			return
+		}
+	}
+	signUpload(c, b, req.Filename, key, reqParams, expiry)
+}
+
+// jsonKind names the JSON type a Go field expects.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Map:
+		return "an object of strings"
+	default:
+		return "a " + t.String()
+	}
+}
+
+// checkBodyExpiry rejects a JSON body expiry above MaxExpiry with the same
+// field error shape as a binding failure. Zero selects the default and is
+// let through; negative values are caught by the gte binding.
+func checkBodyExpiry(c *gin.Context, seconds int) bool {
+	if seconds == 0 || validExpirySeconds(seconds) {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "fields": map[string]string{
+		"expiry": fmt.Sprintf("must be at most %d", int(MaxExpiry.Seconds())),
+	}})
+	return false
+}
+
+// fieldErrors maps a binding failure onto the JSON fields at fault, or
+// returns nil when the body could not be parsed at all.
+func fieldErrors(err error) map[string]string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: "must be " + jsonKind(typeErr.Type)}
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return nil
+	}
+	fields := make(map[string]string, len(invalid))
+	for _, fe := range invalid {
+		name := strings.ToLower(fe.Field())
+		switch fe.Tag() {
+		case "required":
+			fields[name] = "required"
+		case "gte":
+			fields[name] = "must be at least " + fe.Param()
+		default:
+			fields[name] = "invalid"
+		}
+	}
+	return fields
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresignPostHandler(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.POST("/presign", limitBody(), presignPostHandler)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/presign", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Presigns upload", func(t *testing.T) {
+		w := post(`{"filename":"photo.jpg","type":"image/jpeg","expiry":120,"meta":{"owner":"alice"},"bucket":"test-bucket"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Contains(t, resp["url"], "photo.jpg")
+		assert.Contains(t, resp["url"], "x-amz-meta-owner")
+		assert.Equal(t, "image/jpeg", resp["contentType"])
+		assert.EqualValues(t, 120, resp["expiry"])
+		assert.Equal(t, "http://localhost:9000/test-bucket/photo.jpg", resp["publicUrl"])
+	})
+
+	t.Run("Field errors", func(t *testing.T) {
+		for body, fields := range map[string]map[string]any{
+			`{"type":"text/plain"}`:                   {"filename": "required"},
+			`{"filename":"a.txt","expiry":"soon"}`:    {"expiry": "must be an integer"},
+			`{"filename":"a.txt","expiry":-5}`:        {"expiry": "must be at least 0"},
+			`{"filename":"a.txt","expiry":604801}`:    {"expiry": "must be at most 604800"},
+			`{"filename":"a.txt","meta":["x"]}`:       {"meta": "must be an object of strings"},
+			`{"filename":"a.txt","bucket":"unknown"}`: {"bucket": "unknown bucket"},
+		} {
+			w := post(body)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+			var resp map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, fields, resp["fields"], body)
+		}
+	})
+
+	t.Run("Malformed JSON", func(t *testing.T) {
+		w := post(`{"filename":`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Malformed JSON body")
+	})
+
+	t.Run("Shares GET validation", func(t *testing.T) {
+		w := post(`{"filename":"../etc/passwd","type":"text/plain"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,269 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: presignpb/presign.proto
+
+package presignpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PresignRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the file to upload.
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// MIME type of the file; must be sent as Content-Type on upload.
+	ContentType string `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// User metadata stored as x-amz-meta-<key>.
+	Metadata map[string]string `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Object tags applied at upload time.
+	Tags          map[string]string `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresignRequest) Reset() {
+	*x = PresignRequest{}
+	mi := &file_presignpb_presign_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresignRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresignRequest) ProtoMessage() {}
+
+func (x *PresignRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_presignpb_presign_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresignRequest.ProtoReflect.Descriptor instead.
+func (*PresignRequest) Descriptor() ([]byte, []int) {
+	return file_presignpb_presign_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PresignRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *PresignRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *PresignRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *PresignRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type PresignResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Presigned upload URL.
+	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// Public (unsigned) URL of the object once uploaded.
+	PublicUrl string `protobuf:"bytes,2,opt,name=public_url,json=publicUrl,proto3" json:"public_url,omitempty"`
+	// Lifetime of url in seconds.
+	ExpirySeconds int64 `protobuf:"varint,3,opt,name=expiry_seconds,json=expirySeconds,proto3" json:"expiry_seconds,omitempty"`
+	// Object key the upload is stored under, after MIRAIO_KEY_TEMPLATE and
+	// MIRAIO_KEY_PREFIX are applied.
+	Key string `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	// Backend reference ("<backend>:<name>") to pass as filename to download
+	// endpoints. Only set when several backends are configured.
+	Ref string `protobuf:"bytes,5,opt,name=ref,proto3" json:"ref,omitempty"`
+	// Time url stops working, RFC 3339 in UTC. Refresh before then.
+	ExpiresAt     string `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresignResponse) Reset() {
+	*x = PresignResponse{}
+	mi := &file_presignpb_presign_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresignResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresignResponse) ProtoMessage() {}
+
+func (x *PresignResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_presignpb_presign_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresignResponse.ProtoReflect.Descriptor instead.
+func (*PresignResponse) Descriptor() ([]byte, []int) {
+	return file_presignpb_presign_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PresignResponse) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *PresignResponse) GetPublicUrl() string {
+	if x != nil {
+		return x.PublicUrl
+	}
+	return ""
+}
+
+func (x *PresignResponse) GetExpirySeconds() int64 {
+	if x != nil {
+		return x.ExpirySeconds
+	}
+	return 0
+}
+
+func (x *PresignResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PresignResponse) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
+func (x *PresignResponse) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+var File_presignpb_presign_proto protoreflect.FileDescriptor
+
+const file_presignpb_presign_proto_rawDesc = "" +
+	"\n" +
+	"\x17presignpb/presign.proto\x12\x11miraio.presign.v1\"\xd3\x02\n" +
+	"\x0ePresignRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12K\n" +
+	"\bmetadata\x18\x03 \x03(\v2/.miraio.presign.v1.PresignRequest.MetadataEntryR\bmetadata\x12?\n" +
+	"\x04tags\x18\x04 \x03(\v2+.miraio.presign.v1.PresignRequest.TagsEntryR\x04tags\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xac\x01\n" +
+	"\x0fPresignResponse\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1d\n" +
+	"\n" +
+	"public_url\x18\x02 \x01(\tR\tpublicUrl\x12%\n" +
+	"\x0eexpiry_seconds\x18\x03 \x01(\x03R\rexpirySeconds\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x10\n" +
+	"\x03ref\x18\x05 \x01(\tR\x03ref\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\tR\texpiresAt2b\n" +
+	"\x0ePresignService\x12P\n" +
+	"\aPresign\x12!.miraio.presign.v1.PresignRequest\x1a\".miraio.presign.v1.PresignResponseB*Z(github.com/mirago/miraio/proto/presignpbb\x06proto3"
+
+var (
+	file_presignpb_presign_proto_rawDescOnce sync.Once
+	file_presignpb_presign_proto_rawDescData []byte
+)
+
+func file_presignpb_presign_proto_rawDescGZIP() []byte {
+	file_presignpb_presign_proto_rawDescOnce.Do(func() {
+		file_presignpb_presign_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_presignpb_presign_proto_rawDesc), len(file_presignpb_presign_proto_rawDesc)))
+	})
+	return file_presignpb_presign_proto_rawDescData
+}
+
+var file_presignpb_presign_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_presignpb_presign_proto_goTypes = []any{
+	(*PresignRequest)(nil),  // 0: miraio.presign.v1.PresignRequest
+	(*PresignResponse)(nil), // 1: miraio.presign.v1.PresignResponse
+	nil,                     // 2: miraio.presign.v1.PresignRequest.MetadataEntry
+	nil,                     // 3: miraio.presign.v1.PresignRequest.TagsEntry
+}
+var file_presignpb_presign_proto_depIdxs = []int32{
+	2, // 0: miraio.presign.v1.PresignRequest.metadata:type_name -> miraio.presign.v1.PresignRequest.MetadataEntry
+	3, // 1: miraio.presign.v1.PresignRequest.tags:type_name -> miraio.presign.v1.PresignRequest.TagsEntry
+	0, // 2: miraio.presign.v1.PresignService.Presign:input_type -> miraio.presign.v1.PresignRequest
+	1, // 3: miraio.presign.v1.PresignService.Presign:output_type -> miraio.presign.v1.PresignResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_presignpb_presign_proto_init() }
+func file_presignpb_presign_proto_init() {
+	if File_presignpb_presign_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_presignpb_presign_proto_rawDesc), len(file_presignpb_presign_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_presignpb_presign_proto_goTypes,
+		DependencyIndexes: file_presignpb_presign_proto_depIdxs,
+		MessageInfos:      file_presignpb_presign_proto_msgTypes,
+	}.Build()
+	File_presignpb_presign_proto = out.File
+	file_presignpb_presign_proto_goTypes = nil
+	file_presignpb_presign_proto_depIdxs = nil
+}
@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: presignpb/presign.proto
+
+package presignpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PresignService_Presign_FullMethodName = "/miraio.presign.v1.PresignService/Presign"
+)
+
+// PresignServiceClient is the client API for PresignService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PresignService issues presigned upload URLs, mirroring GET /presign.
+type PresignServiceClient interface {
+	// Presign returns a presigned PUT URL for an upload.
+	Presign(ctx context.Context, in *PresignRequest, opts ...grpc.CallOption) (*PresignResponse, error)
+}
+
+type presignServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPresignServiceClient(cc grpc.ClientConnInterface) PresignServiceClient {
+	return &presignServiceClient{cc}
+}
+
+func (c *presignServiceClient) Presign(ctx context.Context, in *PresignRequest, opts ...grpc.CallOption) (*PresignResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PresignResponse)
+	err := c.cc.Invoke(ctx, PresignService_Presign_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PresignServiceServer is the server API for PresignService service.
+// All implementations must embed UnimplementedPresignServiceServer
+// for forward compatibility.
+//
+// PresignService issues presigned upload URLs, mirroring GET /presign.
+type PresignServiceServer interface {
+	// Presign returns a presigned PUT URL for an upload.
+	Presign(context.Context, *PresignRequest) (*PresignResponse, error)
+	mustEmbedUnimplementedPresignServiceServer()
+}
+
+// UnimplementedPresignServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPresignServiceServer struct{}
+
+func (UnimplementedPresignServiceServer) Presign(context.Context, *PresignRequest) (*PresignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Presign not implemented")
+}
+func (UnimplementedPresignServiceServer) mustEmbedUnimplementedPresignServiceServer() {}
+func (UnimplementedPresignServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePresignServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PresignServiceServer will
+// result in compilation errors.
+type UnsafePresignServiceServer interface {
+	mustEmbedUnimplementedPresignServiceServer()
+}
+
+func RegisterPresignServiceServer(s grpc.ServiceRegistrar, srv PresignServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPresignServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PresignService_ServiceDesc, srv)
+}
+
+func _PresignService_Presign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PresignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PresignServiceServer).Presign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PresignService_Presign_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PresignServiceServer).Presign(ctx, req.(*PresignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PresignService_ServiceDesc is the grpc.ServiceDesc for PresignService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PresignService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "miraio.presign.v1.PresignService",
+	HandlerType: (*PresignServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Presign",
+			Handler:    _PresignService_Presign_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "presignpb/presign.proto",
+}
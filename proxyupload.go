@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/mirago/miraio/utils"
+)
+
+// ProxyPartSize is the multipart part size for proxied uploads sent without
+// a Content-Length. minio-go buffers one part at a time, so this bounds the
+// memory per upload; it also caps such uploads at 10000 parts (160 GiB).
+const ProxyPartSize = 16 << 20
+
+// maxUploadBytes is the largest proxied upload accepted
+// (MIRAIO_MAX_UPLOAD_BYTES). Zero means no limit beyond what S3 allows.
+var maxUploadBytes int64
+
+// errContentMD5Proxied rejects contentMd5 on proxied uploads, where the
+// server rather than the client sends the body to MinIO.
+var errContentMD5Proxied = errors.New("contentMd5 is not supported for proxied uploads")
+
+// parseMaxUploadBytes reads MIRAIO_MAX_UPLOAD_BYTES.
+func parseMaxUploadBytes(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("MIRAIO_MAX_UPLOAD_BYTES must be a non-negative number of bytes")
+	}
+	return n, nil
+}
+
+// proxyUploadResponse describes an object stored by PUT /upload.
+type proxyUploadResponse struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"etag"`
+	VersionID   string `json:"versionId,omitempty" snake:"version_id,omitempty"`
+	ContentType string `json:"contentType" snake:"content_type"`
+	PublicURL   string `json:"publicUrl" snake:"public_url"`
+	Backend     string `json:"backend,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+}
+
+// proxyUploadHandler stores the request body under the filename in the path
+// with the server's own credentials, for clients that cannot reach MinIO.
+// The filename, Content-Type and query parameters go through the same checks
+// as /presign, and the body is streamed to PutObject without being held in
+// memory.
+func proxyUploadHandler(c *gin.Context) {
+	filename := c.Param("filename")
+	key, reqParams, err := prepareUpload(uploadRequest{
+		Filename:       filename,
+		ContentType:    c.GetHeader("Content-Type"),
+		Params:         c.Request.URL.Query(),
+		SSECustomerKey: c.GetHeader(SSECustomerKeyHeader),
+		Proxied:        true,
+	})
+	if err == nil && reqParams.Has("Content-MD5") {
+		err = errContentMD5Proxied
+	}
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+	b := pickBackend(c)
+	if !checkScope(c, b, key) {
+		return
+	}
+	opts, err := putObjectOptions(reqParams)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	size := c.Request.ContentLength
+	if maxUploadBytes > 0 {
+		if size > maxUploadBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes)
+	}
+	if size < 0 {
+		opts.PartSize = ProxyPartSize
+	}
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	if key, err = resolveCollision(key, existsOn(ctx, b)); err != nil {
+		respondCollisionError(c, ctx, err)
+		return
+	}
+
+	auditKey(c, key)
+
+	// MIRAIO_REQUEST_TIMEOUT is meant for single backend calls and would cut
+	// off large bodies, and timeoutHandlers skips this route for the same
+	// reason, so the transfer is only bounded by the client connection.
+	ctx = c.Request.Context()
+	// The transfer runs at the client's pace, so a few slow uploads holding
+	// concurrency slots would starve every other route.
+	releaseConcurrencySlot(c)
+	info, err := b.client.PutObject(ctx, b.signer.Bucket, key, c.Request.Body, size, opts)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload too large"})
+			return
+		}
+		respondBackendError(c, ctx, err, "Could not upload object")
+		return
+	}
+	utils.LogInfo("Proxied upload of %s to %s (%d bytes) from %s", key, b.name, info.Size, c.ClientIP())
+
+	resp := proxyUploadResponse{
+		Key:         key,
+		Size:        info.Size,
+		ETag:        info.ETag,
+		VersionID:   info.VersionID,
+		ContentType: opts.ContentType,
+		PublicURL:   requestPublicURL(c, b.signer, key, b.signer.ObjectURL(key)),
+	}
+	if backends.multi() {
+		resp.Backend, resp.Ref = b.name, b.ref(key)
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// putObjectOptions turns the headers prepareUpload signs into a presigned
+// PUT into the equivalent PutObject options, so proxied and presigned
+// uploads store the same object. Content-MD5 has no equivalent and must be
+// rejected by the caller.
+func putObjectOptions(reqParams url.Values) (minio.PutObjectOptions, error) {
+	opts := minio.PutObjectOptions{
+		ContentType:  reqParams.Get("Content-Type"),
+		CacheControl: reqParams.Get("Cache-Control"),
+		StorageClass: reqParams.Get(storageClassHeader),
+	}
+	for header := range reqParams {
+		if name, ok := strings.CutPrefix(strings.ToLower(header), "x-amz-meta-"); ok {
+			if opts.UserMetadata == nil {
+				opts.UserMetadata = make(map[string]string)
+			}
+			opts.UserMetadata[name] = reqParams.Get(header)
+		}
+	}
+	if v := reqParams.Get("X-Amz-Tagging"); v != "" {
+		tags, err := url.ParseQuery(v)
+		if err != nil {
+			return opts, err
+		}
+		opts.UserTags = make(map[string]string, len(tags))
+		for k := range tags {
+			opts.UserTags[k] = tags.Get(k)
+		}
+	}
+	if mode := reqParams.Get("X-Amz-Object-Lock-Mode"); mode != "" {
+		until, err := time.Parse(time.RFC3339, reqParams.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+		if err != nil {
+			return opts, err
+		}
+		opts.Mode = minio.RetentionMode(mode)
+		opts.RetainUntilDate = until
+		// S3 requires Content-MD5 on object-lock uploads.
+		opts.SendContentMd5 = true
+	}
+	if key := reqParams.Get(sseCustomerKeyAmzHeader); key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return opts, err
+		}
+		if opts.ServerSideEncryption, err = encrypt.NewSSEC(raw); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
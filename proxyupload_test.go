@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyUploadRouter() *gin.Engine {
+	router := gin.New()
+	router.PUT("/upload/*filename", audited("upload"), proxyUploadHandler)
+	return router
+}
+
+func TestParseMaxUploadBytes(t *testing.T) {
+	n, err := parseMaxUploadBytes("")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = parseMaxUploadBytes("1048576")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1048576, n)
+
+	for _, bad := range []string{"-1", "1MB"} {
+		_, err := parseMaxUploadBytes(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPutObjectOptions(t *testing.T) {
+	objectLockEnabled = true
+	defer func() { objectLockEnabled = false }()
+	defaultCacheControl = "max-age=60"
+	defer func() { defaultCacheControl = "" }()
+
+	_, reqParams, err := prepareUpload(uploadRequest{
+		Filename:       "a.txt",
+		ContentType:    "text/plain",
+		Params:         url.Values{"meta.owner": {"alice"}, "tag.project": {"42"}, "retainUntil": {"2999-01-01T00:00:00Z"}},
+		SSECustomerKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		Proxied:        true,
+	})
+	require.NoError(t, err)
+
+	opts, err := putObjectOptions(reqParams)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", opts.ContentType)
+	assert.Equal(t, "max-age=60", opts.CacheControl)
+	assert.Equal(t, map[string]string{"owner": "alice"}, opts.UserMetadata)
+	assert.Equal(t, map[string]string{"project": "42"}, opts.UserTags)
+	assert.Equal(t, minio.RetentionMode(retentionMode), opts.Mode)
+	assert.Equal(t, 2999, opts.RetainUntilDate.Year())
+	assert.True(t, opts.SendContentMd5)
+	require.NotNil(t, opts.ServerSideEncryption)
+	assert.Equal(t, "SSE-C", string(opts.ServerSideEncryption.Type()))
+}
+
+func TestProxyUploadHandler(t *testing.T) {
+	setupTestEnvironment()
+	records := useAuditLog(t)
+
+	var gotPath, gotType, gotOwner, gotBody string
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		gotPath = r.URL.Path
+		gotType = r.Header.Get("Content-Type")
+		gotOwner = r.Header.Get("X-Amz-Meta-Owner")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/upload/album/hello.txt?meta.owner=alice", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	proxyUploadRouter().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, "/test-bucket/album/hello.txt", gotPath)
+	assert.Equal(t, "text/plain", gotType)
+	assert.Equal(t, "alice", gotOwner)
+	// Plain-HTTP uploads are sent with chunk signatures around the data.
+	assert.Contains(t, gotBody, "\r\nhello\r\n")
+
+	var resp proxyUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "album/hello.txt", resp.Key)
+	assert.EqualValues(t, 5, resp.Size)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", resp.ETag)
+	assert.Equal(t, "text/plain", resp.ContentType)
+	assert.Equal(t, "http://localhost:9000/test-bucket/album/hello.txt", resp.PublicURL)
+
+	audit := records()
+	require.Len(t, audit, 1)
+	assert.Equal(t, "upload", audit[0].Operation)
+	assert.Equal(t, "album/hello.txt", audit[0].Key)
+	assert.Equal(t, "success", audit[0].Result)
+}
+
+// observedReader calls onRead before the first Read of the wrapped reader.
+type observedReader struct {
+	io.Reader
+	once   sync.Once
+	onRead func()
+}
+
+func (r *observedReader) Read(p []byte) (int, error) {
+	r.once.Do(r.onRead)
+	return r.Reader.Read(p)
+}
+
+func TestProxyUploadHandler_ReleasesConcurrencySlot(t *testing.T) {
+	setupTestEnvironment()
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+	})
+
+	limiter := newConcurrencyLimiter(1)
+	router := gin.New()
+	router.PUT("/upload/*filename", limiter.middleware(), proxyUploadHandler)
+
+	inFlight := int64(-1)
+	body := &observedReader{Reader: strings.NewReader("hello"), onRead: func() { inFlight = limiter.inFlight.Load() }}
+	req := httptest.NewRequest(http.MethodPut, "/upload/hello.txt", body)
+	req.ContentLength = 5
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Zero(t, inFlight, "slot held while the body streamed")
+	assert.Zero(t, limiter.inFlight.Load())
+	release, ok := limiter.acquire()
+	require.True(t, ok, "slot not returned")
+	release()
+}
+
+func TestProxyUploadHandler_Rejected(t *testing.T) {
+	setupTestEnvironment()
+	useFakeBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected backend request %s %s", r.Method, r.URL)
+	})
+	withConfig(t, func(cfg *reloadableConfig) { cfg.allowedExtensions = []string{"txt"} })
+	maxUploadBytes = 4
+	defer func() { maxUploadBytes = 0 }()
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"Too large", "/upload/a.txt", "hello", http.StatusRequestEntityTooLarge},
+		{"Extension not allowed", "/upload/a.exe", "hi", http.StatusUnsupportedMediaType},
+		{"Relative path", "/upload/../a.txt", "hi", http.StatusBadRequest},
+		{"Content-MD5", "/upload/a.txt?contentMd5=XUFAKrxLKna5cZ2REBfFkg%3D%3D", "hi", http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "text/plain")
+			w := httptest.NewRecorder()
+			proxyUploadRouter().ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestIntegrationProxyUpload(t *testing.T) {
+	client := integrationClient(t)
+
+	// Without a Content-Length the body is streamed in multipart parts.
+	body := strings.NewReader(strings.Repeat("x", 1<<20))
+	req := httptest.NewRequest(http.MethodPut, "/upload/proxied.bin?meta.owner=alice", io.NopCloser(body))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	proxyUploadRouter().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp proxyUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	defer client.RemoveObject(context.Background(), bucketName, resp.Key, minio.RemoveObjectOptions{})
+
+	info, err := client.StatObject(context.Background(), bucketName, resp.Key, minio.StatObjectOptions{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1<<20, info.Size)
+	assert.Equal(t, "application/octet-stream", info.ContentType)
+	assert.Equal(t, "alice", info.UserMetadata["Owner"])
+}
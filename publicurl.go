@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/pkg/presign"
+	"github.com/mirago/miraio/utils"
+)
+
+// urlStyle controls how public URLs place the bucket (MIRAIO_URL_STYLE):
+// presign.StylePath or presign.StyleVHost.
+var urlStyle string
+
+// validateURLStyle checks MIRAIO_URL_STYLE against the public URL, falling
+// back to path style for buckets that cannot be addressed as a subdomain.
+func validateURLStyle() error {
+	switch urlStyle {
+	case "":
+		urlStyle = presign.StylePath
+	case presign.StylePath:
+	case presign.StyleVHost:
+		u, err := url.Parse(publicURL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("MIRAIO_URL_STYLE=vhost requires an absolute MIRAIO_MINIO_PUBLIC_URL, got %q", publicURL)
+		}
+		if !presign.VHostCompatible(u.Scheme, bucketName) {
+			utils.LogWarning("Bucket %q contains dots and cannot be served virtual-host style over https; using path style", bucketName)
+			urlStyle = presign.StylePath
+		}
+	default:
+		return fmt.Errorf("unsupported MIRAIO_URL_STYLE %q: use path or vhost", urlStyle)
+	}
+	return nil
+}
+
+// resolvePublicURLScheme completes a public URL given without a scheme using
+// the SSL setting, and reports whether an explicit scheme disagrees with it.
+// A mismatch is not fatal, since TLS may be terminated by a proxy or CDN in
+// front of MinIO, but it is often a mistake that breaks links.
+func resolvePublicURLScheme(publicURL string, useSSL bool) (resolved string, mismatch bool) {
+	want := "http"
+	if useSSL {
+		want = "https"
+	}
+	if publicURL == "" {
+		return publicURL, false
+	}
+	if !strings.Contains(publicURL, "://") {
+		return want + "://" + publicURL, false
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil {
+		return publicURL, false
+	}
+	return publicURL, !strings.EqualFold(u.Scheme, want)
+}
+
+// PublicBaseHeader lets authenticated callers choose the base of the public
+// URLs in their responses, e.g. the CDN of their tenant.
+const PublicBaseHeader = "X-Public-Base"
+
+// publicBaseKey stores an accepted PublicBaseHeader in the gin context.
+const publicBaseKey = "miraio.publicBase"
+
+// parsePublicBase accepts an absolute http(s) URL without credentials,
+// query or fragment.
+func parsePublicBase(v string) (string, error) {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("Invalid %s: must be an absolute http or https URL", PublicBaseHeader)
+	}
+	return strings.TrimRight(v, "/"), nil
+}
+
+// publicBaseOverride honours PublicBaseHeader on requests carrying a valid
+// API key. Anyone else's header is ignored, so unauthenticated clients
+// cannot make the service hand out links to a host of their choosing.
+func publicBaseOverride() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := c.GetHeader(PublicBaseHeader)
+		if v == "" {
+			c.Next()
+			return
+		}
+		if !validAPIKey(c) {
+			utils.LogDebug("Ignoring %s on unauthenticated request to %s", PublicBaseHeader, c.Request.URL.Path)
+			c.Next()
+			return
+		}
+		base, err := parsePublicBase(v)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(publicBaseKey, base)
+		c.Next()
+	}
+}
+
+// requestPublicURL returns public, the URL of key built by s, rebuilt on
+// the request's accepted public base if it has one.
+func requestPublicURL(c *gin.Context, s *presign.Signer, key, public string) string {
+	base := c.GetString(publicBaseKey)
+	if base == "" {
+		return public
+	}
+	override := *s
+	override.PublicURL = base
+	return override.ObjectURL(key)
+}
+
+// publicObjectURL builds the public (unsigned) URL of an object.
+func publicObjectURL(key string) string {
+	return signer.ObjectURL(key)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/pkg/presign"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicObjectURLStyles(t *testing.T) {
+	defer setupTestEnvironment()
+	defer func() { urlStyle = "" }()
+
+	testCases := []struct {
+		name      string
+		style     string
+		publicURL string
+		bucket    string
+		expected  string
+	}{
+		{"Default is path", "", "https://cdn.example.com", "uploads", "https://cdn.example.com/uploads/a.jpg"},
+		{"Path", "path", "https://cdn.example.com", "uploads", "https://cdn.example.com/uploads/a.jpg"},
+		{"VHost", "vhost", "https://cdn.example.com", "uploads", "https://uploads.cdn.example.com/a.jpg"},
+		{"VHost with port", "vhost", "http://localhost:9000", "uploads", "http://uploads.localhost:9000/a.jpg"},
+		{"VHost dotted bucket over https", "vhost", "https://cdn.example.com", "my.bucket", "https://cdn.example.com/my.bucket/a.jpg"},
+		{"VHost dotted bucket over http", "vhost", "http://cdn.example.com", "my.bucket", "http://my.bucket.cdn.example.com/a.jpg"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlStyle = tc.style
+			publicURL = tc.publicURL
+			bucketName = tc.bucket
+			require.NoError(t, validateURLStyle())
+			signer = newSigner()
+			assert.Equal(t, tc.expected, publicObjectURL("a.jpg"))
+		})
+	}
+}
+
+func TestValidateURLStyle_Invalid(t *testing.T) {
+	defer setupTestEnvironment()
+	defer func() { urlStyle = "" }()
+
+	urlStyle = "subdomain"
+	assert.Error(t, validateURLStyle())
+
+	urlStyle = presign.StyleVHost
+	publicURL = "not a url"
+	assert.Error(t, validateURLStyle())
+}
+
+func TestResolvePublicURLScheme(t *testing.T) {
+	testCases := []struct {
+		publicURL        string
+		useSSL           bool
+		expectedURL      string
+		expectedMismatch bool
+	}{
+		{"http://localhost:9000", false, "http://localhost:9000", false},
+		{"https://cdn.example.com", true, "https://cdn.example.com", false},
+		{"http://cdn.example.com", true, "http://cdn.example.com", true},
+		{"https://cdn.example.com", false, "https://cdn.example.com", true},
+		{"HTTPS://cdn.example.com", true, "HTTPS://cdn.example.com", false},
+		{"localhost:9000", false, "http://localhost:9000", false},
+		{"cdn.example.com", true, "https://cdn.example.com", false},
+		{"", true, "", false},
+	}
+
+	for _, tc := range testCases {
+		resolved, mismatch := resolvePublicURLScheme(tc.publicURL, tc.useSSL)
+		assert.Equal(t, tc.expectedURL, resolved, tc.publicURL)
+		assert.Equal(t, tc.expectedMismatch, mismatch, tc.publicURL)
+	}
+}
+
+func TestParsePublicBase(t *testing.T) {
+	base, err := parsePublicBase("https://cdn.tenant-a.example/assets/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.tenant-a.example/assets", base)
+
+	for _, v := range []string{"cdn.example", "ftp://cdn.example", "https://", "https://u:p@cdn.example", "https://cdn.example?x=1", "https://cdn.example/#top", "javascript:alert(1)"} {
+		_, err := parsePublicBase(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestPublicBaseOverride(t *testing.T) {
+	setupTestEnvironment()
+	apiKey = "secret"
+	defer func() { apiKey = "" }()
+
+	router := gin.New()
+	router.Use(publicBaseOverride())
+	router.GET("/presign-get", presignGetHandler)
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/presign-get?filename=a.txt", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	publicURLOf := func(w *httptest.ResponseRecorder) string {
+		var resp presignURLResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.PublicURL
+	}
+
+	t.Run("Authenticated override", func(t *testing.T) {
+		w := get(map[string]string{PublicBaseHeader: "https://cdn.tenant-a.example", "X-API-Key": "secret"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://cdn.tenant-a.example/test-bucket/a.txt", publicURLOf(w))
+	})
+
+	t.Run("Unauthenticated header is ignored", func(t *testing.T) {
+		for _, headers := range []map[string]string{
+			{PublicBaseHeader: "https://evil.example"},
+			{PublicBaseHeader: "https://evil.example", "X-API-Key": "wrong"},
+		} {
+			w := get(headers)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "http://localhost:9000/test-bucket/a.txt", publicURLOf(w))
+		}
+	})
+
+	t.Run("Invalid base", func(t *testing.T) {
+		w := get(map[string]string{PublicBaseHeader: "not a url", "X-API-Key": "secret"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("No header", func(t *testing.T) {
+		w := get(map[string]string{"X-API-Key": "secret"})
+		assert.Equal(t, "http://localhost:9000/test-bucket/a.txt", publicURLOf(w))
+	})
+}
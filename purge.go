@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// PurgeLogInterval is how many matched objects pass between progress log
+// lines during a purge.
+const PurgeLogInterval = 1000
+
+// purgeResult summarizes a purge run.
+type purgeResult struct {
+	Matched int
+	Deleted int
+	Failed  int
+}
+
+// purgeResponse is the body of POST /admin/purge.
+type purgeResponse struct {
+	Prefix    string `json:"prefix"`
+	OlderThan string `json:"olderThan" snake:"older_than"`
+	DryRun    bool   `json:"dryRun" snake:"dry_run"`
+	Matched   int    `json:"matched"`
+	Deleted   int    `json:"deleted"`
+	Failed    int    `json:"failed"`
+}
+
+// purgeHandler removes objects under prefix whose last modification is older
+// than olderThan. It is a dry run that only counts matches unless
+// apply=true.
+func purgeHandler(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing prefix"})
+		return
+	}
+	fullPrefix, err := objectKey(prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	auditKey(c, fullPrefix)
+	olderThan, err := time.ParseDuration(c.Query("olderThan"))
+	if err != nil || olderThan <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid olderThan: must be a positive duration such as 24h"})
+		return
+	}
+	apply := c.Query("apply") == "true"
+
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	cutoff := time.Now().Add(-olderThan)
+	utils.LogInfo("Purge of %s older than %s started (apply=%t)", fullPrefix, olderThan, apply)
+	result, err := purgeObjects(ctx, fullPrefix, cutoff, apply)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not purge objects")
+		return
+	}
+	utils.LogInfo("Purge of %s finished: matched=%d deleted=%d failed=%d", fullPrefix, result.Matched, result.Deleted, result.Failed)
+	auditDetail(c, fmt.Sprintf("olderThan=%s dryRun=%t matched=%d deleted=%d failed=%d", olderThan, !apply, result.Matched, result.Deleted, result.Failed))
+
+	respondJSON(c, http.StatusOK, purgeResponse{
+		Prefix:    strings.TrimPrefix(fullPrefix, keyPrefix),
+		OlderThan: olderThan.String(),
+		DryRun:    !apply,
+		Matched:   result.Matched,
+		Deleted:   result.Deleted,
+		Failed:    result.Failed,
+	})
+}
+
+// purgeObjects lists objects under prefix last modified before cutoff and,
+// when apply is set, streams them into a bulk RemoveObjects call.
+func purgeObjects(ctx context.Context, prefix string, cutoff time.Time, apply bool) (purgeResult, error) {
+	var result purgeResult
+	var listErr error
+
+	matches := make(chan minio.ObjectInfo)
+	listed := make(chan struct{})
+	go func() {
+		defer close(listed)
+		defer close(matches)
+		for obj := range minioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
+			Prefix:    prefix,
+			Recursive: true,
+		}) {
+			if obj.Err != nil {
+				listErr = obj.Err
+				return
+			}
+			if !obj.LastModified.Before(cutoff) {
+				continue
+			}
+			result.Matched++
+			if result.Matched%PurgeLogInterval == 0 {
+				utils.LogInfo("Purge of %s: %d objects matched so far", prefix, result.Matched)
+			}
+			if apply {
+				select {
+				case matches <- obj:
+				case <-ctx.Done():
+					listErr = ctx.Err()
+					return
+				}
+			}
+		}
+	}()
+
+	if apply {
+		for rerr := range minioClient.RemoveObjects(ctx, bucketName, matches, minio.RemoveObjectsOptions{}) {
+			result.Failed++
+			utils.LogError("Purge could not remove %s: %v", rerr.ObjectName, rerr.Err)
+		}
+	}
+	<-listed
+
+	if listErr != nil {
+		return result, listErr
+	}
+	if apply {
+		result.Deleted = result.Matched - result.Failed
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeHandler_InvalidParameters(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.POST("/admin/purge", purgeHandler)
+
+	for _, query := range []string{
+		"?olderThan=24h",
+		"?prefix=temp/",
+		"?prefix=temp/&olderThan=yesterday",
+		"?prefix=temp/&olderThan=-1h",
+		"?prefix=../&olderThan=24h",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/purge"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestIntegrationPurge(t *testing.T) {
+	client := integrationClient(t)
+	ctx := context.Background()
+
+	keys := []string{"purge-test/a.txt", "purge-test/b.txt"}
+	for _, key := range keys {
+		_, err := client.PutObject(ctx, bucketName, key, bytes.NewReader([]byte("x")), 1, minio.PutObjectOptions{})
+		require.NoError(t, err)
+	}
+	defer func() {
+		for _, key := range keys {
+			client.RemoveObject(ctx, bucketName, key, minio.RemoveObjectOptions{})
+		}
+	}()
+
+	router := gin.New()
+	router.POST("/admin/purge", purgeHandler)
+	purge := func(query string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/purge"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Nothing is an hour old yet.
+	assert.Equal(t, float64(0), purge("?prefix=purge-test/&olderThan=1h&apply=true")["matched"])
+
+	time.Sleep(1100 * time.Millisecond)
+	dryRun := purge("?prefix=purge-test/&olderThan=1s")
+	assert.Equal(t, true, dryRun["dryRun"])
+	assert.Equal(t, float64(2), dryRun["matched"])
+	assert.Equal(t, float64(0), dryRun["deleted"])
+
+	applied := purge("?prefix=purge-test/&olderThan=1s&apply=true")
+	assert.Equal(t, float64(2), applied["deleted"])
+	_, err := client.StatObject(ctx, bucketName, keys[0], minio.StatObjectOptions{})
+	assert.True(t, isNotFound(err))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// singleValuedParams are the query parameters the handlers read one value
+// of. Gin returns the first of several, so a repeated one is ambiguous.
+var singleValuedParams = map[string]bool{
+	"filename": true, "type": true, "expiry": true, "method": true,
+	"prefix": true, "startAfter": true, "marker": true, "maxKeys": true,
+	"maxSize": true, "versionId": true, "key": true, "uploadId": true,
+	"keyMarker": true, "uploadIdMarker": true, "limit": true,
+	"olderThan": true, "apply": true, "validate": true, "rangeProbe": true,
+	"filename-override": true, "type-override": true,
+	RedirectParam: true, contentMD5Param: true, cacheControlParam: true,
+	storageClassParam: true, sseCustomerKeyParam: true, "retainUntil": true,
+}
+
+// duplicateParam returns the first single-valued parameter, including
+// meta.<key> and tag.<key>, that rawQuery repeats. It scans the raw query
+// rather than parsing it into url.Values, since it runs on every request
+// and the handlers parse the query again anyway.
+func duplicateParam(rawQuery string) string {
+	var buf [16]string
+	seen := buf[:0]
+	for rest := rawQuery; rest != ""; {
+		var pair string
+		pair, rest, _ = strings.Cut(rest, "&")
+		name, _, _ := strings.Cut(pair, "=")
+		if strings.ContainsAny(name, "%+") {
+			var err error
+			if name, err = url.QueryUnescape(name); err != nil {
+				continue
+			}
+		}
+		if !singleValuedParams[name] && !strings.HasPrefix(name, metaParamPrefix) && !strings.HasPrefix(name, tagParamPrefix) {
+			continue
+		}
+		if slices.Contains(seen, name) {
+			return name
+		}
+		seen = append(seen, name)
+	}
+	return ""
+}
+
+// rejectDuplicateParams answers 400 when a single-valued query parameter is
+// given more than once, rather than acting on whichever value comes first.
+func rejectDuplicateParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if name := duplicateParam(c.Request.URL.RawQuery); name != "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Duplicate query parameter: " + name})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectDuplicateParams(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.Use(rejectDuplicateParams())
+	router.GET("/presign", presignHandler)
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		status int
+		param  string
+	}{
+		{"Single values", "filename=a.txt&type=text/plain&expiry=60", http.StatusOK, ""},
+		{"Duplicate filename", "filename=a.txt&filename=b.txt&type=text/plain", http.StatusBadRequest, "filename"},
+		{"Duplicate type", "filename=a.txt&type=text/plain&type=image/png", http.StatusBadRequest, "type"},
+		{"Duplicate expiry", "filename=a.txt&type=text/plain&expiry=60&expiry=600", http.StatusBadRequest, "expiry"},
+		{"Same value twice", "filename=a.txt&filename=a.txt&type=text/plain", http.StatusBadRequest, "filename"},
+		{"Duplicate filename-override", "filename=a.txt&type=text/plain&filename-override=a.txt&filename-override=b.txt", http.StatusBadRequest, "filename-override"},
+		{"Duplicate type-override", "filename=a.txt&type=text/plain&type-override=text/plain&type-override=text/html", http.StatusBadRequest, "type-override"},
+		{"Duplicate metadata", "filename=a.txt&type=text/plain&meta.owner=a&meta.owner=b", http.StatusBadRequest, "meta.owner"},
+		{"Encoded name", "filename=a.txt&type=text/plain&meta%2Eowner=a&meta.owner=b", http.StatusBadRequest, "meta.owner"},
+		{"Unknown parameter", "filename=a.txt&type=text/plain&utm=1&utm=2", http.StatusOK, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/presign?"+tc.query, nil))
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.param != "" {
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, "Duplicate query parameter: "+tc.param, body["error"])
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// RequestIDHeader carries a caller-supplied request ID, echoed in error
+// responses so failures can be matched with log entries.
+const RequestIDHeader = "X-Request-ID"
+
+// errorResponse is a JSON error carrying the caller's request ID, if any.
+type errorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"requestId,omitempty" snake:"request_id,omitempty"`
+}
+
+// recovery replaces gin.Recovery so that panics land in the application log
+// rather than on stderr, and clients get a JSON error instead of an empty
+// 500.
+func recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			requestID := c.GetHeader(RequestIDHeader)
+			utils.LogError("Panic serving %s %s (request ID %q): %v\n%s",
+				c.Request.Method, c.Request.URL.Path, requestID, r, debug.Stack())
+
+			c.Abort()
+			respondJSON(c, http.StatusInternalServerError, errorResponse{Error: "internal server error", RequestID: requestID})
+		}()
+		c.Next()
+	}
+}
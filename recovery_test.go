@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery(t *testing.T) {
+	logDir := t.TempDir()
+	t.Setenv("MIRAIO_LOG_DIR", logDir)
+	utils.InitLogger()
+
+	router := gin.New()
+	router.Use(recovery())
+	router.GET("/boom", func(c *gin.Context) {
+		panic("something went wrong")
+	})
+
+	req := httptest.NewRequest("GET", "/boom", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{
+		"error":     "internal server error",
+		"requestId": "req-123",
+	}, body)
+
+	files, err := filepath.Glob(filepath.Join(logDir, "server-*.log"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	logged, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(logged), "ERROR: ")
+	assert.Contains(t, string(logged), "something went wrong")
+	assert.Contains(t, string(logged), "req-123")
+	assert.Contains(t, string(logged), "goroutine")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedirectParam asks a GET or HEAD presign endpoint to answer with a 302 to
+// the signed URL instead of JSON.
+const RedirectParam = "redirect"
+
+// wantsRedirect reports whether the request set redirect=true.
+func wantsRedirect(c *gin.Context) bool {
+	return c.Query(RedirectParam) == "true"
+}
+
+// rejectRedirect answers 400 when redirect=true is used with a verb whose
+// URL a client cannot follow, since redirects are replayed as GET or HEAD.
+// It reports whether the request was rejected.
+func rejectRedirect(c *gin.Context) bool {
+	if !wantsRedirect(c) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "redirect is only supported for GET and HEAD presigns"})
+	return true
+}
+
+// respondRedirect sends the client to the signed URL. The redirect must not
+// be cached: the target expires, and a cached 302 would keep pointing at it.
+func respondRedirect(c *gin.Context, signed string) {
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, signed)
+}
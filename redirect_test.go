@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresignRedirect(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	router.GET("/presign-get", presignGetHandler)
+	router.GET("/presign-head", presignHeadHandler)
+	router.HEAD("/presign-head", presignHeadHandler)
+
+	for _, tc := range []struct {
+		method, target, signedMethod string
+	}{
+		{"GET", "/presign-get?filename=cat.jpg&redirect=true", "GET"},
+		{"HEAD", "/presign-head?filename=cat.jpg&redirect=true", "HEAD"},
+		{"GET", "/presign?method=GET&filename=cat.jpg&redirect=true", "GET"},
+	} {
+		t.Run(tc.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+
+			require.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			location, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, "/test-bucket/cat.jpg", location.Path)
+			assert.NotEmpty(t, location.Query().Get("X-Amz-Signature"))
+		})
+	}
+
+	t.Run("PUT is refused", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=cat.jpg&redirect=true", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "only supported for GET and HEAD")
+	})
+
+	t.Run("redirect=false returns JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-get?filename=cat.jpg&redirect=false", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"url"`)
+	})
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// reloadableKeys are the settings that can change while the server runs.
+// They are read from the environment at startup and can be overridden in
+// MIRAIO_SETTINGS_FILE, which is re-read on SIGHUP and POST /admin/reload.
+// Credentials, the MinIO client and everything else stay as started.
+var reloadableKeys = []string{
+	"MIRAIO_ALLOWED_EXTENSIONS",
+	"MIRAIO_ALLOW_CIDRS",
+	"MIRAIO_DENY_CIDRS",
+	"MIRAIO_DOWNLOAD_TYPES",
+	"MIRAIO_EXPIRY_BY_TYPE",
+	"MIRAIO_LIST_MAX_KEYS",
+	"MIRAIO_MAX_KEY_LENGTH",
+	"MIRAIO_MAX_PREFIX_OBJECTS",
+	"MIRAIO_PREFIX_POLICIES",
+}
+
+// settingsFile is MIRAIO_SETTINGS_FILE. Empty disables reloading.
+var settingsFile string
+
+// errNoSettingsFile is returned by reloadConfig without MIRAIO_SETTINGS_FILE;
+// the environment of a running process cannot change, so there is nothing to
+// reload.
+var errNoSettingsFile = errors.New("MIRAIO_SETTINGS_FILE is not set")
+
+// reloadableConfig is one consistent set of reloadable settings. It is
+// never modified once published; a reload swaps in a new one.
+type reloadableConfig struct {
+	values            map[string]string
+	allowedExtensions []string
+	expiryByType      map[string]time.Duration
+	downloadTypes     map[string]bool
+	ipFilter          ipFilter
+	listMaxKeys       int
+	maxKeyLength      int
+	maxPrefixObjects  int
+	prefixPolicies    prefixPolicies
+}
+
+var defaultReloadableConfig = reloadableConfig{
+	listMaxKeys:      DefaultListMaxKeys,
+	maxKeyLength:     MaxKeyLength,
+	maxPrefixObjects: DefaultMaxPrefixObjects,
+}
+
+// liveConfig holds the settings in effect. Handlers load it once per use,
+// so a request never sees a mix of old and new settings.
+var liveConfig atomic.Pointer[reloadableConfig]
+
+// reloadMu serialises reloads so that the logged changes are accurate.
+var reloadMu sync.Mutex
+
+// currentConfig returns the settings in effect, or the defaults before main
+// has loaded any.
+func currentConfig() *reloadableConfig {
+	if cfg := liveConfig.Load(); cfg != nil {
+		return cfg
+	}
+	return &defaultReloadableConfig
+}
+
+// parseReloadableConfig validates values, keyed by environment variable.
+// Missing keys take their defaults.
+func parseReloadableConfig(values map[string]string) (*reloadableConfig, error) {
+	cfg := &reloadableConfig{values: values}
+	var err error
+	if cfg.allowedExtensions, err = parseAllowedExtensions(values["MIRAIO_ALLOWED_EXTENSIONS"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_ALLOWED_EXTENSIONS: %w", err)
+	}
+	if cfg.expiryByType, err = parseExpiryByType(values["MIRAIO_EXPIRY_BY_TYPE"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_EXPIRY_BY_TYPE: %w", err)
+	}
+	if cfg.downloadTypes, err = parseDownloadTypes(values["MIRAIO_DOWNLOAD_TYPES"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_DOWNLOAD_TYPES: %w", err)
+	}
+	if cfg.ipFilter.allow, err = parseCIDRs(values["MIRAIO_ALLOW_CIDRS"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_ALLOW_CIDRS: %w", err)
+	}
+	if cfg.ipFilter.deny, err = parseCIDRs(values["MIRAIO_DENY_CIDRS"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_DENY_CIDRS: %w", err)
+	}
+	if cfg.listMaxKeys, err = parseListMaxKeys(values["MIRAIO_LIST_MAX_KEYS"]); err != nil {
+		return nil, err
+	}
+	if cfg.maxKeyLength, err = parseMaxKeyLength(values["MIRAIO_MAX_KEY_LENGTH"]); err != nil {
+		return nil, err
+	}
+	if cfg.maxPrefixObjects, err = parseMaxPrefixObjects(values["MIRAIO_MAX_PREFIX_OBJECTS"]); err != nil {
+		return nil, err
+	}
+	if cfg.prefixPolicies, err = parsePrefixPolicies(values["MIRAIO_PREFIX_POLICIES"]); err != nil {
+		return nil, fmt.Errorf("invalid MIRAIO_PREFIX_POLICIES: %w", err)
+	}
+	return cfg, nil
+}
+
+// reloadableValues reads the reloadable settings from getenv, overridden by
+// the settings file when path is set.
+func reloadableValues(getenv func(string) string, path string) (map[string]string, error) {
+	values := make(map[string]string, len(reloadableKeys))
+	for _, key := range reloadableKeys {
+		if v := getenv(key); v != "" {
+			values[key] = v
+		}
+	}
+	if path == "" {
+		return values, nil
+	}
+	overrides, err := readSettingsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range overrides {
+		values[key] = v
+	}
+	return values, nil
+}
+
+// readSettingsFile parses KEY=VALUE lines; blank lines and lines starting
+// with # are skipped. Only reloadable keys are accepted, so a secret put in
+// the file by mistake is reported rather than silently ignored.
+func readSettingsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE", path, n)
+		}
+		if !slices.Contains(reloadableKeys, key) {
+			return nil, fmt.Errorf("%s:%d: %s cannot be set in the settings file", path, n, key)
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// reloadConfig re-reads the settings file and publishes the result. On error
+// the settings in effect are kept. It returns the keys whose values changed.
+func reloadConfig() ([]string, error) {
+	if settingsFile == "" {
+		return nil, errNoSettingsFile
+	}
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	values, err := reloadableValues(os.Getenv, settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseReloadableConfig(values)
+	if err != nil {
+		return nil, err
+	}
+	old := currentConfig()
+	liveConfig.Store(cfg)
+
+	changed := []string{}
+	for _, key := range reloadableKeys {
+		if old.values[key] != cfg.values[key] {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		utils.LogInfo("Reloaded settings from %s: no changes", settingsFile)
+	} else {
+		utils.LogInfo("Reloaded settings from %s: changed %s", settingsFile, strings.Join(changed, ", "))
+	}
+	return changed, nil
+}
+
+// reloadOnHangup reloads the settings file on every SIGHUP.
+func reloadOnHangup() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := reloadConfig(); err != nil {
+				utils.LogError("Failed to reload settings, keeping the current ones: %v", err)
+			}
+		}
+	}()
+}
+
+// reloadHandler serves POST /admin/reload.
+func reloadHandler(c *gin.Context) {
+	changed, err := reloadConfig()
+	switch {
+	case errors.Is(err, errNoSettingsFile):
+		c.JSON(http.StatusConflict, gin.H{"error": "Reload needs MIRAIO_SETTINGS_FILE"})
+	case err != nil:
+		utils.LogError("Failed to reload settings, keeping the current ones: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid settings: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"changed": changed})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withConfig publishes a copy of the settings in effect, changed by edit,
+// until the test ends.
+func withConfig(t *testing.T, edit func(cfg *reloadableConfig)) {
+	t.Helper()
+	old := liveConfig.Load()
+	cfg := *currentConfig()
+	edit(&cfg)
+	liveConfig.Store(&cfg)
+	t.Cleanup(func() { liveConfig.Store(old) })
+}
+
+// useSettingsFile points settingsFile at a temporary file with content and
+// restores the settings in effect when the test ends.
+func useSettingsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "settings.env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	old := liveConfig.Load()
+	settingsFile = path
+	t.Cleanup(func() {
+		settingsFile = ""
+		liveConfig.Store(old)
+	})
+	return path
+}
+
+func TestParseReloadableConfig(t *testing.T) {
+	cfg, err := parseReloadableConfig(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultListMaxKeys, cfg.listMaxKeys)
+	assert.Equal(t, MaxKeyLength, cfg.maxKeyLength)
+	assert.Equal(t, DefaultMaxPrefixObjects, cfg.maxPrefixObjects)
+	assert.False(t, cfg.ipFilter.enabled())
+
+	cfg, err = parseReloadableConfig(map[string]string{
+		"MIRAIO_EXPIRY_BY_TYPE": "video/*=900",
+		"MIRAIO_ALLOW_CIDRS":    "10.0.0.0/8",
+		"MIRAIO_LIST_MAX_KEYS":  "50",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 900*time.Second, cfg.expiryByType["video/*"])
+	assert.True(t, cfg.ipFilter.enabled())
+	assert.Equal(t, 50, cfg.listMaxKeys)
+
+	_, err = parseReloadableConfig(map[string]string{"MIRAIO_DENY_CIDRS": "not-an-ip"})
+	assert.ErrorContains(t, err, "MIRAIO_DENY_CIDRS")
+}
+
+func TestReadSettingsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.env")
+	require.NoError(t, os.WriteFile(path, []byte("# limits\n\nMIRAIO_LIST_MAX_KEYS = 10\nMIRAIO_DOWNLOAD_TYPES=application/pdf\n"), 0o600))
+
+	values, err := readSettingsFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"MIRAIO_LIST_MAX_KEYS": "10", "MIRAIO_DOWNLOAD_TYPES": "application/pdf"}, values)
+
+	require.NoError(t, os.WriteFile(path, []byte("MIRAIO_MINIO_SECRET_KEY=hunter2\n"), 0o600))
+	_, err = readSettingsFile(path)
+	assert.ErrorContains(t, err, "cannot be set in the settings file")
+
+	require.NoError(t, os.WriteFile(path, []byte("MIRAIO_LIST_MAX_KEYS\n"), 0o600))
+	_, err = readSettingsFile(path)
+	assert.ErrorContains(t, err, ":1: want KEY=VALUE")
+}
+
+func TestReloadableValues_FileOverridesEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.env")
+	require.NoError(t, os.WriteFile(path, []byte("MIRAIO_LIST_MAX_KEYS=10\n"), 0o600))
+	env := map[string]string{"MIRAIO_LIST_MAX_KEYS": "500", "MIRAIO_MAX_KEY_LENGTH": "200", "MIRAIO_MINIO_SECRET_KEY": "secret"}
+
+	values, err := reloadableValues(func(k string) string { return env[k] }, path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"MIRAIO_LIST_MAX_KEYS": "10", "MIRAIO_MAX_KEY_LENGTH": "200"}, values)
+}
+
+func TestReloadHandler(t *testing.T) {
+	setupTestEnvironment()
+	router := gin.New()
+	router.POST("/admin/reload", reloadHandler)
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/admin/reload", nil))
+		return w
+	}
+
+	t.Run("without a settings file", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, reload().Code)
+	})
+
+	t.Run("applies and reports changes", func(t *testing.T) {
+		path := useSettingsFile(t, "MIRAIO_LIST_MAX_KEYS=10\n")
+
+		w := reload()
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct{ Changed []string }
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"MIRAIO_LIST_MAX_KEYS"}, resp.Changed)
+		assert.Equal(t, 10, currentConfig().listMaxKeys)
+
+		w = reload()
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"changed":[]}`, w.Body.String())
+
+		require.NoError(t, os.WriteFile(path, []byte("MIRAIO_LIST_MAX_KEYS=none\n"), 0o600))
+		w = reload()
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "MIRAIO_LIST_MAX_KEYS")
+		assert.Equal(t, 10, currentConfig().listMaxKeys, "invalid settings must not replace the current ones")
+	})
+}
+
+func TestFilterConfiguredIPs_FollowsReload(t *testing.T) {
+	router := gin.New()
+	router.Use(filterConfiguredIPs())
+	router.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	ping := func() int {
+		req := httptest.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, ping())
+
+	deny, err := parseCIDRs("192.0.2.0/24")
+	require.NoError(t, err)
+	withConfig(t, func(cfg *reloadableConfig) { cfg.ipFilter.deny = deny })
+	assert.Equal(t, http.StatusForbidden, ping())
+}
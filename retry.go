@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mirago/miraio/pkg/presign"
+	"github.com/mirago/miraio/utils"
+)
+
+// Backoff bounds for retried MinIO calls.
+const (
+	RetryBaseDelay = 100 * time.Millisecond
+	RetryMaxDelay  = 2 * time.Second
+)
+
+// maxRetries is how many times a transient MinIO failure is retried
+// (MIRAIO_MAX_RETRIES). Zero disables retries.
+var maxRetries int
+
+// withRetry runs fn, retrying transient failures with exponential backoff up
+// to maxRetries times. Non-transient errors are returned immediately, and
+// retries stop as soon as ctx is done.
+func withRetry(ctx context.Context, op string, fn func() error) error {
+	delay := RetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !isTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		utils.LogDebug("Retrying %s after transient error (attempt %d/%d, backoff %s): %v", op, attempt+1, maxRetries, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > RetryMaxDelay {
+			delay = RetryMaxDelay
+		}
+	}
+}
+
+// retryClient applies withRetry to every presign call.
+type retryClient struct {
+	presign.Client
+}
+
+func (r retryClient) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	var u *url.URL
+	err := withRetry(ctx, "presign "+method+" "+objectName, func() error {
+		var err error
+		u, err = r.Client.PresignHeader(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
+		return err
+	})
+	return u, err
+}
+
+// withRetries wraps c so that its presign calls are retried when retries are
+// enabled.
+func withRetries(c presign.Client) presign.Client {
+	if maxRetries == 0 {
+		return c
+	}
+	return retryClient{c}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transientErr is a net.Error, which isTransientError treats as retryable.
+var transientErr error = &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+func TestWithRetry(t *testing.T) {
+	maxRetries = 3
+	defer func() { maxRetries = 0 }()
+
+	t.Run("Retries transient errors until success", func(t *testing.T) {
+		calls := 0
+		err := withRetry(context.Background(), "test", func() error {
+			calls++
+			if calls < 3 {
+				return transientErr
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Gives up after max retries", func(t *testing.T) {
+		calls := 0
+		err := withRetry(context.Background(), "test", func() error {
+			calls++
+			return transientErr
+		})
+		assert.Equal(t, transientErr, err)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("Does not retry permanent errors", func(t *testing.T) {
+		calls := 0
+		permanent := errors.New("AccessDenied")
+		err := withRetry(context.Background(), "test", func() error {
+			calls++
+			return permanent
+		})
+		assert.Equal(t, permanent, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		start := time.Now()
+		err := withRetry(ctx, "test", func() error {
+			calls++
+			cancel()
+			return transientErr
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+		assert.Less(t, time.Since(start), RetryBaseDelay)
+	})
+}
+
+func TestWithRetry_Disabled(t *testing.T) {
+	maxRetries = 0
+	calls := 0
+	_ = withRetry(context.Background(), "test", func() error {
+		calls++
+		return transientErr
+	})
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// parseSlowlogThreshold reads MIRAIO_SLOWLOG_MS. Zero (the default) disables
+// the slow log.
+func parseSlowlogThreshold(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("MIRAIO_SLOWLOG_MS must be a non-negative number of milliseconds, got %q", v)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// slowlog logs a warning for every request that takes longer than
+// threshold, so latency outliers stand out without debug logging.
+func slowlog(threshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if elapsed := time.Since(start); elapsed > threshold {
+			utils.LogWarning("Slow request: %s %s took %s (status %d, query %q)",
+				c.Request.Method, c.Request.URL.Path, elapsed.Round(time.Millisecond), c.Writer.Status(), c.Request.URL.RawQuery)
+		}
+	}
+}
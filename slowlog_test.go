@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowlog(t *testing.T) {
+	logDir := t.TempDir()
+	t.Setenv("MIRAIO_LOG_DIR", logDir)
+	utils.InitLogger()
+
+	router := gin.New()
+	router.Use(slowlog(20 * time.Millisecond))
+	router.GET("/fast", func(c *gin.Context) { c.Status(200) })
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(30 * time.Millisecond)
+		c.Status(200)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast?filename=quick.txt", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow?filename=lagging.txt", nil))
+
+	files, err := filepath.Glob(filepath.Join(logDir, "server-*.log"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	logged, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	assert.Contains(t, string(logged), "WARNING: ")
+	assert.Contains(t, string(logged), "Slow request: GET /slow")
+	assert.Contains(t, string(logged), "filename=lagging.txt")
+	assert.NotContains(t, string(logged), "quick.txt")
+}
+
+func TestParseSlowlogThreshold(t *testing.T) {
+	d, err := parseSlowlogThreshold("")
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = parseSlowlogThreshold("250")
+	require.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, d)
+
+	for _, v := range []string{"-1", "1s"} {
+		_, err := parseSlowlogThreshold(v)
+		assert.Error(t, err, v)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/url"
+)
+
+// SSECustomerKeyHeader is the request header carrying a base64 SSE-C key
+// for GET /presign and /upload-bundle. It is a header rather than a query
+// parameter because request URLs end up in access and slow logs.
+const SSECustomerKeyHeader = "X-SSE-Customer-Key"
+
+// sseCustomerKeyParam is the query parameter rejected in favour of
+// SSECustomerKeyHeader.
+const sseCustomerKeyParam = "sseCustomerKey"
+
+// SSE-C request headers, and the only algorithm S3 supports for them.
+const (
+	sseCustomerAlgorithmHeader = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	sseCustomerKeyAmzHeader    = "X-Amz-Server-Side-Encryption-Customer-Key"
+	sseCustomerKeyMD5Header    = "X-Amz-Server-Side-Encryption-Customer-Key-MD5"
+	sseCustomerAlgorithm       = "AES256"
+)
+
+// errSSEKeyInQuery rejects keys sent where they would be logged.
+var errSSEKeyInQuery = errors.New("Send the SSE-C key in the " + SSECustomerKeyHeader + " header, not the query string")
+
+// addSSECustomerKey signs the SSE-C headers for key, a base64-encoded
+// 256-bit key, into reqParams. The key itself must never be logged; errors
+// describe only its shape.
+func addSSECustomerKey(key string, reqParams url.Values) error {
+	if key == "" {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return errors.New("Invalid SSE-C key: must be 32 bytes, base64-encoded")
+	}
+	sum := md5.Sum(raw)
+	reqParams.Set(sseCustomerAlgorithmHeader, sseCustomerAlgorithm)
+	reqParams.Set(sseCustomerKeyAmzHeader, key)
+	reqParams.Set(sseCustomerKeyMD5Header, base64.StdEncoding.EncodeToString(sum[:]))
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSSEKey is a base64 256-bit key.
+var testSSEKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func TestAddSSECustomerKey(t *testing.T) {
+	reqParams := make(url.Values)
+	require.NoError(t, addSSECustomerKey(testSSEKey, reqParams))
+
+	sum := md5.Sum([]byte("0123456789abcdef0123456789abcdef"))
+	assert.Equal(t, "AES256", reqParams.Get(sseCustomerAlgorithmHeader))
+	assert.Equal(t, testSSEKey, reqParams.Get(sseCustomerKeyAmzHeader))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), reqParams.Get(sseCustomerKeyMD5Header))
+
+	reqParams = make(url.Values)
+	require.NoError(t, addSSECustomerKey("", reqParams))
+	assert.Empty(t, reqParams)
+
+	short := base64.StdEncoding.EncodeToString([]byte("too short"))
+	for _, bad := range []string{"not base64!", short} {
+		err := addSSECustomerKey(bad, make(url.Values))
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), bad)
+	}
+}
+
+func TestPresignPutHandler_SSECustomerKey(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	req := httptest.NewRequest("GET", "/presign?filename=secret.bin&type=application/octet-stream", nil)
+	req.Header.Set(SSECustomerKeyHeader, testSSEKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.NotContains(t, w.Body.String(), testSSEKey)
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "AES256", resp.SSECustomerAlgorithm)
+	assert.NotEmpty(t, resp.SSECustomerKeyMD5)
+
+	signed, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	assert.NotContains(t, resp.URL, url.QueryEscape(testSSEKey))
+	signedHeaders := signed.Query().Get("X-Amz-SignedHeaders")
+	assert.True(t, strings.Contains(signedHeaders, "x-amz-server-side-encryption-customer-key"), signedHeaders)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=secret.bin&type=application/octet-stream&sseCustomerKey="+url.QueryEscape(testSSEKey), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotContains(t, w.Body.String(), testSSEKey)
+}
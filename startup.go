@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+)
+
+// StartupCheckTimeout bounds the connectivity probe run before serving.
+const StartupCheckTimeout = 10 * time.Second
+
+// createBucket makes checkBackend create a missing bucket instead of failing
+// (MIRAIO_CREATE_BUCKET).
+var createBucket bool
+
+// checkBackend verifies that MinIO is reachable and the configured bucket
+// exists, so misconfiguration surfaces at startup rather than on the first
+// request. With createBucket set, a missing bucket is created instead.
+func checkBackend(endpoint string, useSSL bool) error {
+	utils.LogInfo("Checking MinIO connectivity: endpoint=%s ssl=%t bucket=%s", endpoint, useSSL, bucketName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), StartupCheckTimeout)
+	defer cancel()
+
+	exists, err := minioClient.BucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("cannot reach MinIO at %s (ssl=%t): %w", endpoint, useSSL, err)
+	}
+	if !exists {
+		if !createBucket {
+			return fmt.Errorf("bucket %q does not exist on %s", bucketName, endpoint)
+		}
+		return createMissingBucket(ctx)
+	}
+
+	utils.LogInfo("MinIO reachable and bucket %s exists", bucketName)
+	return nil
+}
+
+// createMissingBucket creates the configured bucket and, when a policy
+// template is configured, applies it with the bucket name substituted.
+func createMissingBucket(ctx context.Context) error {
+	if err := minioClient.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: minioRegion}); err != nil {
+		return fmt.Errorf("creating bucket %s: %w", bucketName, err)
+	}
+	utils.LogInfo("Created bucket %s", bucketName)
+
+	if bucketPolicyTemplate == "" {
+		return nil
+	}
+	policy, err := renderBucketPolicy(bucketPolicyTemplate, bucketName)
+	if err != nil {
+		return err
+	}
+	if err := minioClient.SetBucketPolicy(ctx, bucketName, policy); err != nil {
+		return fmt.Errorf("applying policy to bucket %s: %w", bucketName, err)
+	}
+	utils.LogInfo("Applied policy template to bucket %s", bucketName)
+	return nil
+}
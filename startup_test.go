@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBackend_Unreachable(t *testing.T) {
+	setupTestEnvironment()
+	defer setupTestEnvironment()
+
+	var err error
+	minioClient, err = minio.New("127.0.0.1:1", &minio.Options{
+		Creds:      credentials.NewStaticV4("minio", "minio123", ""),
+		Region:     minioRegion,
+		MaxRetries: 1,
+	})
+	require.NoError(t, err)
+
+	err = checkBackend("127.0.0.1:1", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot reach MinIO at 127.0.0.1:1")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startTime is when the process started serving, for uptime reporting.
+var startTime = time.Now()
+
+// statusClasses are the keys of the per-path status breakdown.
+var statusClasses = [...]string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// pathCounters counts requests to one route by status class.
+type pathCounters struct {
+	byClass [len(statusClasses)]atomic.Int64
+}
+
+// requestStats holds in-process traffic counters. Counters are atomic, so
+// the lock is only taken to add a route the first time it is seen.
+type requestStats struct {
+	mu    sync.RWMutex
+	paths map[string]*pathCounters
+}
+
+var stats = newRequestStats()
+
+func newRequestStats() *requestStats {
+	return &requestStats{paths: make(map[string]*pathCounters)}
+}
+
+// record counts a request to path answered with status.
+func (s *requestStats) record(path string, status int) {
+	class := status/100 - 1
+	if class < 0 || class >= len(statusClasses) {
+		return
+	}
+
+	s.mu.RLock()
+	counters, ok := s.paths[path]
+	s.mu.RUnlock()
+	if !ok {
+		s.mu.Lock()
+		if counters, ok = s.paths[path]; !ok {
+			counters = &pathCounters{}
+			s.paths[path] = counters
+		}
+		s.mu.Unlock()
+	}
+	counters.byClass[class].Add(1)
+}
+
+// snapshot returns the totals, 4xx and 5xx errors, and the per-path
+// breakdown.
+func (s *requestStats) snapshot() (total, errors int64, paths map[string]map[string]int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths = make(map[string]map[string]int64, len(s.paths))
+	for path, counters := range s.paths {
+		byClass := make(map[string]int64, len(statusClasses))
+		for i, class := range statusClasses {
+			n := counters.byClass[i].Load()
+			if n == 0 {
+				continue
+			}
+			byClass[class] = n
+			total += n
+			if i >= 3 {
+				errors += n
+			}
+		}
+		paths[path] = byClass
+	}
+	return total, errors, paths
+}
+
+// countRequests records every request in stats, keyed by route pattern so
+// that object names do not create unbounded entries.
+func countRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		stats.record(path, c.Writer.Status())
+	}
+}
+
+// statsResponse is the body of GET /stats. Paths is keyed by route and
+// status class. InFlight counts MinIO-backed requests running right now;
+// MaxConcurrency is their limit, omitted when unlimited. WebhookDropped
+// counts webhook events lost to a full queue.
+type statsResponse struct {
+	TotalRequests  int64                       `json:"totalRequests" snake:"total_requests"`
+	Errors         int64                       `json:"errors"`
+	Paths          map[string]map[string]int64 `json:"paths"`
+	UptimeSeconds  int64                       `json:"uptimeSeconds" snake:"uptime_seconds"`
+	InFlight       int64                       `json:"inFlight" snake:"in_flight"`
+	MaxConcurrency int                         `json:"maxConcurrency,omitempty" snake:"max_concurrency,omitempty"`
+	WebhookDropped int64                       `json:"webhookDropped,omitempty" snake:"webhook_dropped,omitempty"`
+}
+
+// statsHandler reports request counters and uptime.
+func statsHandler(c *gin.Context) {
+	total, errors, paths := stats.snapshot()
+	respondJSON(c, http.StatusOK, statsResponse{
+		TotalRequests:  total,
+		Errors:         errors,
+		Paths:          paths,
+		UptimeSeconds:  int64(time.Since(startTime).Seconds()),
+		InFlight:       concurrency.inFlight.Load(),
+		MaxConcurrency: concurrency.limit(),
+		WebhookDropped: webhook.droppedEvents(),
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestStatsConcurrent(t *testing.T) {
+	s := newRequestStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.record("/presign", http.StatusOK)
+				s.record("/presign", http.StatusBadRequest)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total, errors, paths := s.snapshot()
+	assert.Equal(t, int64(2000), total)
+	assert.Equal(t, int64(1000), errors)
+	assert.Equal(t, map[string]int64{"2xx": 1000, "4xx": 1000}, paths["/presign"])
+}
+
+func TestStatsHandler(t *testing.T) {
+	setupTestEnvironment()
+	stats = newRequestStats()
+
+	router := gin.New()
+	router.Use(countRequests())
+	router.GET("/presign", presignHandler)
+	router.GET("/stats", statsHandler)
+
+	for _, path := range []string{"/presign?filename=a.txt&type=text/plain", "/presign", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		TotalRequests int64                       `json:"totalRequests"`
+		Errors        int64                       `json:"errors"`
+		Paths         map[string]map[string]int64 `json:"paths"`
+		UptimeSeconds int64                       `json:"uptimeSeconds"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(3), response.TotalRequests)
+	assert.Equal(t, int64(2), response.Errors)
+	assert.Equal(t, map[string]int64{"2xx": 1, "4xx": 1}, response.Paths["/presign"])
+	assert.Equal(t, map[string]int64{"4xx": 1}, response.Paths["unmatched"])
+	assert.GreaterOrEqual(t, response.UptimeSeconds, int64(0))
+}
+
+func TestStatsHandler_WebhookDropped(t *testing.T) {
+	webhook = &webhookNotifier{events: make(chan webhookEvent)}
+	defer func() { webhook = nil }()
+	webhook.notifyPresign(http.MethodPut, "a.txt", "a.txt", "text/plain", "127.0.0.1")
+
+	router := gin.New()
+	router.GET("/stats", statsHandler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		WebhookDropped int64 `json:"webhookDropped"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.EqualValues(t, 1, response.WebhookDropped)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/pkg/presign"
+)
+
+// Storage backends selectable with MIRAIO_BACKEND.
+const (
+	StorageMinIO = "minio"
+	StorageFS    = "fs"
+)
+
+// storageBackend is the configured backend (MIRAIO_BACKEND).
+var storageBackend = StorageMinIO
+
+// errObjectNotFound is returned by Storage implementations other than MinIO
+// for missing objects; isNotFound recognises it alongside MinIO's codes.
+var errObjectNotFound = errors.New("object not found")
+
+// Storage is what the core endpoints need from an object store: signing
+// uploads and downloads, and stat, list and remove with the server's own
+// access. Endpoints built on MinIO-specific features (compose, multipart,
+// tags, object lock, ...) still use the MinIO client directly and are not
+// available with other backends.
+type Storage interface {
+	// PresignPut signs an upload of key; headers must be sent verbatim.
+	PresignPut(ctx context.Context, key string, headers http.Header, expiry time.Duration) (string, error)
+	// PresignGet signs a download of key; reqParams are signed into the
+	// query string.
+	PresignGet(ctx context.Context, key string, expiry time.Duration, reqParams url.Values) (string, error)
+	// Stat describes key, or the given version of it when versionID is set.
+	Stat(ctx context.Context, key, versionID string) (storedObject, error)
+	// List returns up to max object keys under prefix that sort after
+	// startAfter, and whether more exist.
+	List(ctx context.Context, prefix, startAfter string, max int) (keys []string, truncated bool, err error)
+	// Remove deletes key, or the given version of it.
+	Remove(ctx context.Context, key, versionID string) error
+}
+
+// storedObject is what Storage.Stat reports about an object.
+type storedObject struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	VersionID    string
+}
+
+// parseStorageBackend reads MIRAIO_BACKEND, defaulting to StorageMinIO.
+func parseStorageBackend(v string) (string, error) {
+	switch v {
+	case "":
+		return StorageMinIO, nil
+	case StorageMinIO, StorageFS:
+		return v, nil
+	default:
+		return "", fmt.Errorf("MIRAIO_BACKEND must be %q or %q, got %q", StorageMinIO, StorageFS, v)
+	}
+}
+
+// storage returns the Storage behind b: the filesystem store in fs mode,
+// otherwise b's MinIO client and signer.
+func (b *backend) storage() Storage {
+	if fsStore != nil {
+		return fsStore
+	}
+	return minioStorage{client: b.client, signer: b.signer}
+}
+
+// minioStorage implements Storage on a MinIO bucket. Signing goes through
+// signer's client, so its retry, cache and tracing wrappers apply.
+type minioStorage struct {
+	client *minio.Client
+	signer *presign.Signer
+}
+
+func (s minioStorage) PresignPut(ctx context.Context, key string, headers http.Header, expiry time.Duration) (string, error) {
+	return s.presign(ctx, http.MethodPut, key, expiry, nil, headers)
+}
+
+func (s minioStorage) PresignGet(ctx context.Context, key string, expiry time.Duration, reqParams url.Values) (string, error) {
+	return s.presign(ctx, http.MethodGet, key, expiry, reqParams, nil)
+}
+
+// presign signs with the client directly rather than through the Signer's
+// URL methods, which also build a public URL that callers here do not use.
+func (s minioStorage) presign(ctx context.Context, method, key string, expiry time.Duration, reqParams url.Values, headers http.Header) (string, error) {
+	u, err := s.signer.Client.PresignHeader(ctx, method, s.signer.Bucket, key, expiry, reqParams, headers)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s minioStorage) Stat(ctx context.Context, key, versionID string) (storedObject, error) {
+	info, err := s.client.StatObject(ctx, s.signer.Bucket, key, minio.StatObjectOptions{VersionID: versionID})
+	if err != nil {
+		return storedObject{}, err
+	}
+	return storedObject{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+		VersionID:    info.VersionID,
+	}, nil
+}
+
+func (s minioStorage) List(ctx context.Context, prefix, startAfter string, max int) ([]string, bool, error) {
+	// List one extra object to learn whether the page is truncated, then
+	// stop the listing by cancelling its context.
+	listCtx, stopListing := context.WithCancel(ctx)
+	defer stopListing()
+
+	var keys []string
+	for obj := range s.client.ListObjects(listCtx, s.signer.Bucket, minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Recursive:  true,
+	}) {
+		if obj.Err != nil {
+			return nil, false, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		if len(keys) == max {
+			return keys, true, nil
+		}
+		keys = append(keys, obj.Key)
+	}
+	return keys, false, nil
+}
+
+func (s minioStorage) Remove(ctx context.Context, key, versionID string) error {
+	return s.client.RemoveObject(ctx, s.signer.Bucket, key, minio.RemoveObjectOptions{VersionID: versionID})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStorageBackend(t *testing.T) {
+	for in, want := range map[string]string{"": StorageMinIO, "minio": StorageMinIO, "fs": StorageFS} {
+		got, err := parseStorageBackend(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseStorageBackend("s3")
+	assert.Error(t, err)
+}
+
+func TestIsNotFound_StorageError(t *testing.T) {
+	assert.True(t, isNotFound(errObjectNotFound))
+	assert.True(t, isNotFound(fmt.Errorf("stat a.txt: %w", errObjectNotFound)))
+}
+
+func TestBackendStorage(t *testing.T) {
+	setupTestEnvironment()
+
+	b := backends.all()[0]
+	assert.IsType(t, minioStorage{}, b.storage())
+
+	fsStore = &fsStorage{}
+	defer func() { fsStore = nil }()
+	assert.Same(t, fsStore, b.storage())
+}
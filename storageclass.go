@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// storageClassParam is the upload parameter naming the storage class the
+// object is stored with.
+const storageClassParam = "storageClass"
+
+// storageClassHeader carries the storage class on the signed PUT.
+const storageClassHeader = "X-Amz-Storage-Class"
+
+// DefaultStorageClasses are the classes MinIO supports out of the box.
+const DefaultStorageClasses = "STANDARD,REDUCED_REDUNDANCY"
+
+// allowedStorageClasses lists the classes clients may request
+// (MIRAIO_STORAGE_CLASSES), upper-case.
+var allowedStorageClasses = strings.Split(DefaultStorageClasses, ",")
+
+// parseStorageClasses reads MIRAIO_STORAGE_CLASSES, a comma-separated list
+// of storage class names, defaulting to DefaultStorageClasses.
+func parseStorageClasses(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		v = DefaultStorageClasses
+	}
+	var classes []string
+	for _, class := range strings.Split(v, ",") {
+		class = strings.ToUpper(strings.TrimSpace(class))
+		if class == "" {
+			continue
+		}
+		if strings.ContainsFunc(class, func(r rune) bool { return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') }) {
+			return nil, fmt.Errorf("invalid storage class %q: use letters, digits and underscores", class)
+		}
+		classes = append(classes, class)
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("at least one storage class is required")
+	}
+	return classes, nil
+}
+
+// addStorageClass signs the storageClass parameter into reqParams, so MinIO
+// stores the upload in that tier. Names are matched case-insensitively.
+func addStorageClass(query url.Values, reqParams url.Values) error {
+	if !query.Has(storageClassParam) {
+		return nil
+	}
+	class := strings.ToUpper(strings.TrimSpace(query.Get(storageClassParam)))
+	if !slices.Contains(allowedStorageClasses, class) {
+		return fmt.Errorf("Invalid storageClass: must be one of %s", strings.Join(allowedStorageClasses, ", "))
+	}
+	reqParams.Set(storageClassHeader, class)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStorageClasses(t *testing.T) {
+	classes, err := parseStorageClasses("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STANDARD", "REDUCED_REDUNDANCY"}, classes)
+
+	classes, err = parseStorageClasses(" standard, GLACIER_IR ,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STANDARD", "GLACIER_IR"}, classes)
+
+	for _, bad := range []string{",", "COLD STORAGE", "a-b"} {
+		_, err := parseStorageClasses(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAddStorageClass(t *testing.T) {
+	reqParams := make(url.Values)
+	require.NoError(t, addStorageClass(url.Values{}, reqParams))
+	assert.Empty(t, reqParams)
+
+	require.NoError(t, addStorageClass(url.Values{"storageClass": {"reduced_redundancy"}}, reqParams))
+	assert.Equal(t, "REDUCED_REDUNDANCY", reqParams.Get(storageClassHeader))
+
+	for _, bad := range []string{"", "GLACIER", "STANDARD\r\nX-Evil: 1"} {
+		assert.Error(t, addStorageClass(url.Values{"storageClass": {bad}}, make(url.Values)), "%q", bad)
+	}
+}
+
+func TestPresignPutHandler_StorageClass(t *testing.T) {
+	setupTestEnvironment()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&storageClass=REDUCED_REDUNDANCY", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "REDUCED_REDUNDANCY", resp.StorageClass)
+	signed, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	assert.Contains(t, signed.Query().Get("X-Amz-SignedHeaders"), "x-amz-storage-class")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=a.txt&type=text/plain&storageClass=GLACIER", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIntegrationPresignStorageClass(t *testing.T) {
+	client := integrationClient(t)
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/presign?filename=storage-class.txt&type=text/plain&storageClass=REDUCED_REDUNDANCY", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp presignUploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	upload, err := http.NewRequest(http.MethodPut, resp.URL, strings.NewReader("hello"))
+	require.NoError(t, err)
+	upload.Header.Set("Content-Type", resp.ContentType)
+	upload.Header.Set(storageClassHeader, resp.StorageClass)
+	uploadResp, err := http.DefaultClient.Do(upload)
+	require.NoError(t, err)
+	uploadResp.Body.Close()
+	require.Equal(t, http.StatusOK, uploadResp.StatusCode)
+	defer client.RemoveObject(context.Background(), bucketName, resp.Key, minio.RemoveObjectOptions{})
+
+	info, err := client.StatObject(context.Background(), bucketName, resp.Key, minio.StatObjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "REDUCED_REDUNDANCY", info.StorageClass)
+}
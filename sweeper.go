@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// DefaultSweepInterval is how often expired cache entries are evicted when
+// MIRAIO_SWEEP_INTERVAL is unset.
+const DefaultSweepInterval = time.Minute
+
+// sweepable is a cache whose expired entries can be evicted in bulk. sweep
+// returns the number of entries removed.
+type sweepable interface {
+	sweep(now time.Time) int
+}
+
+// sweeper periodically evicts expired entries from the registered caches.
+// The caches already drop expired entries on lookup and cap their size, but
+// entries that are never looked up again would otherwise stay until pushed
+// out by newer ones.
+type sweeper struct {
+	mu      sync.Mutex
+	names   []string
+	targets []sweepable
+	now     func() time.Time
+}
+
+// janitor is the process-wide sweeper, started in main.
+var janitor = &sweeper{now: time.Now}
+
+// register adds target to the sweep under name, which is used in logs.
+func (s *sweeper) register(name string, target sweepable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names = append(s.names, name)
+	s.targets = append(s.targets, target)
+}
+
+// sweepOnce sweeps every target and returns the total number of entries
+// evicted.
+func (s *sweeper) sweepOnce() int {
+	s.mu.Lock()
+	names := append([]string(nil), s.names...)
+	targets := append([]sweepable(nil), s.targets...)
+	s.mu.Unlock()
+
+	now := s.now()
+	total := 0
+	for i, target := range targets {
+		if n := target.sweep(now); n > 0 {
+			utils.LogDebug("Evicted %d expired entries from %s", n, names[i])
+			total += n
+		}
+	}
+	return total
+}
+
+// start sweeps every interval in a background goroutine. The returned
+// function stops it and waits for a sweep in progress to finish.
+func (s *sweeper) start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepOnce()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
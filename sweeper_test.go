@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweeperEvictsExpiredEntries(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	idem := newIdempotencyCache(time.Minute, 10)
+	idem.now = clock
+	idem.begin("a", "fp")
+	idem.finish("a", 200, "application/json", []byte("{}"))
+
+	presigned := newPresignCache(10)
+	presigned.now = clock
+	presigned.add("short", &url.URL{Path: "/short"}, 10*time.Second)
+	presigned.add("long", &url.URL{Path: "/long"}, time.Hour)
+
+	s := &sweeper{now: clock}
+	s.register("idempotency cache", idem)
+	s.register("presign cache", presigned)
+
+	assert.Equal(t, 0, s.sweepOnce())
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 2, s.sweepOnce())
+	assert.Empty(t, idem.entries)
+	assert.Equal(t, 0, idem.order.Len())
+	_, ok := presigned.get("long")
+	assert.True(t, ok)
+	assert.Len(t, presigned.entries, 1)
+}
+
+func TestSweeperStartStop(t *testing.T) {
+	swept := make(chan struct{}, 1)
+	s := &sweeper{now: time.Now}
+	s.register("test", sweepFunc(func(time.Time) int {
+		select {
+		case swept <- struct{}{}:
+		default:
+		}
+		return 0
+	}))
+
+	stop := s.start(time.Millisecond)
+	select {
+	case <-swept:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not run")
+	}
+	stop()
+}
+
+type sweepFunc func(time.Time) int
+
+func (f sweepFunc) sweep(now time.Time) int { return f(now) }
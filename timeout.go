@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/utils"
+)
+
+// handlerTimeout bounds how long a request may take end to end
+// (MIRAIO_HANDLER_TIMEOUT). Zero disables the limit.
+var handlerTimeout time.Duration
+
+// untimedRoutes are exempt from the handler timeout. A proxied upload, or a
+// PUT to or GET from the fs backend, takes as long as its body does to
+// transfer, which no fixed deadline fits.
+var untimedRoutes = map[string]bool{
+	"/upload/*filename":             true,
+	FSRoutePrefix + "/:bucket/*key": true,
+}
+
+// timeoutHandlers puts a deadline of d on every request's context. Handlers
+// derive their backend contexts from it through requestContext, so MinIO
+// calls still in flight are cancelled when it passes, and the request is
+// answered with 504.
+func timeoutHandlers(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if untimedRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && handlerTimedOut(c) {
+			respondTimeout(c)
+		}
+	}
+}
+
+// handlerTimedOut reports whether the request's own deadline, set by
+// timeoutHandlers, has passed. The incoming request context carries no
+// other deadline.
+func handlerTimedOut(c *gin.Context) bool {
+	return handlerTimeout > 0 && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded)
+}
+
+// respondTimeout answers a request that ran out of time.
+func respondTimeout(c *gin.Context) {
+	utils.LogWarning("Request %s %s exceeded the handler timeout of %s", c.Request.Method, c.Request.URL.Path, handlerTimeout)
+	c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutHandlers(t *testing.T) {
+	handlerTimeout = 50 * time.Millisecond
+	defer func() { handlerTimeout = 0 }()
+
+	router := gin.New()
+	router.Use(timeoutHandlers(handlerTimeout))
+	router.GET("/fast", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	router.GET("/silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+	slow := func(c *gin.Context) {
+		time.Sleep(2 * handlerTimeout)
+		c.Status(http.StatusOK)
+	}
+	router.GET("/upload/*filename", slow)
+	router.PUT(FSRoutePrefix+"/:bucket/*key", slow)
+	router.GET("/backend", func(c *gin.Context) {
+		ctx, cancel := requestContext(c)
+		defer cancel()
+		<-ctx.Done()
+		respondBackendError(c, ctx, ctx.Err(), "Could not generate presigned URL")
+	})
+
+	for path, want := range map[string]int{
+		"GET /fast":                         http.StatusOK,
+		"GET /silent":                       http.StatusGatewayTimeout,
+		"GET /backend":                      http.StatusGatewayTimeout,
+		"GET /upload/a.bin":                 http.StatusOK,
+		"PUT " + FSRoutePrefix + "/b/a.bin": http.StatusOK,
+	} {
+		method, target, _ := strings.Cut(path, " ")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		assert.Equal(t, want, w.Code, path)
+		if want == http.StatusGatewayTimeout {
+			assert.JSONEq(t, `{"error":"Request timed out"}`, w.Body.String(), path)
+		}
+	}
+}
+
+func TestTimeoutHandlers_CancelsMinIOCalls(t *testing.T) {
+	setupTestEnvironment()
+	defer setupTestEnvironment()
+
+	// A backend that never answers; the stat must be abandoned, not leaked.
+	released := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(released)
+	}))
+	defer hanging.Close()
+
+	u, err := url.Parse(hanging.URL)
+	require.NoError(t, err)
+	minioClient, err = minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4("minio", "minio123", ""),
+		Region: minioRegion,
+	})
+	require.NoError(t, err)
+	signer = newSigner()
+
+	handlerTimeout = 100 * time.Millisecond
+	defer func() { handlerTimeout = 0 }()
+
+	router := gin.New()
+	router.Use(timeoutHandlers(handlerTimeout))
+	router.GET("/confirm", confirmHandler)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/confirm?filename=a.txt", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	select {
+	case <-released:
+	case <-time.After(2 * time.Second):
+		t.Fatal("MinIO request was not cancelled")
+	}
+}
+
+func TestContextAbortReason(t *testing.T) {
+	defer func(h, r time.Duration) { handlerTimeout, requestTimeout = h, r }(handlerTimeout, requestTimeout)
+	handlerTimeout, requestTimeout = time.Minute, time.Second
+
+	newContext := func(parent context.Context) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/presign", nil).WithContext(parent)
+		return c
+	}
+
+	c := newContext(context.Background())
+	ctx, cancel := requestContext(c)
+	assert.Empty(t, contextAbortReason(ctx, c))
+	cancel()
+	assert.Equal(t, "cancelled by client", contextAbortReason(ctx, c))
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	c = newContext(expired)
+	ctx, cancel = requestContext(c)
+	defer cancel()
+	assert.Equal(t, "exceeded the handler timeout of 1m0s", contextAbortReason(ctx, c))
+
+	c = newContext(context.Background())
+	ctx, cancel = context.WithDeadline(c.Request.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, "timed out after 1s", contextAbortReason(ctx, c))
+}
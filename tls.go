@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// loadTLSConfig returns the server TLS configuration when both
+// MIRAIO_TLS_CERT and MIRAIO_TLS_KEY are set, or nil when TLS is disabled.
+// The key pair is loaded eagerly so a bad certificate fails at startup.
+func loadTLSConfig() (*tls.Config, error) {
+	certFile := os.Getenv("MIRAIO_TLS_CERT")
+	keyFile := os.Getenv("MIRAIO_TLS_KEY")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("MIRAIO_TLS_CERT and MIRAIO_TLS_KEY must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS key pair: %w", err)
+	}
+
+	minVersion, err := parseTLSVersion(os.Getenv("MIRAIO_TLS_MIN_VERSION"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+	}, nil
+}
+
+// parseTLSVersion maps a MIRAIO_TLS_MIN_VERSION value to a tls version
+// constant. Versions below 1.2 are rejected; an empty value means 1.2.
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported MIRAIO_TLS_MIN_VERSION %q: use 1.2 or 1.3", v)
+	}
+}
+
+// minioTransport carries the TLS and connection pool settings for
+// connections to MinIO (and its STS endpoint). nil means minio-go's default
+// transport.
+var minioTransport http.RoundTripper
+
+// loadMinIOTransport builds a transport trusting the PEM certificates in
+// MIRAIO_MINIO_CA_CERT in addition to the system roots, or skipping
+// verification entirely when MIRAIO_MINIO_INSECURE_SKIP_VERIFY=true, with
+// minioTransportTuning applied. It returns nil when none of these is set.
+func loadMinIOTransport() (http.RoundTripper, error) {
+	caFile := os.Getenv("MIRAIO_MINIO_CA_CERT")
+	insecure := os.Getenv("MIRAIO_MINIO_INSECURE_SKIP_VERIFY") == "true"
+	if caFile == "" && !insecure && !minioTransportTuning.isSet() {
+		return nil, nil
+	}
+
+	transport, err := newMinIOTransport(minioTransportTuning)
+	if err != nil {
+		return nil, err
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading MIRAIO_MINIO_CA_CERT: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("MIRAIO_MINIO_CA_CERT %s contains no PEM certificates", caFile)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = insecure
+	return transport, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	v, err := parseTLSVersion("")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+
+	v, err = parseTLSVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	_, err = parseTLSVersion("1.0")
+	assert.Error(t, err)
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv("MIRAIO_TLS_CERT", "")
+		t.Setenv("MIRAIO_TLS_KEY", "")
+		cfg, err := loadTLSConfig()
+		require.NoError(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("OnlyCert", func(t *testing.T) {
+		t.Setenv("MIRAIO_TLS_CERT", "cert.pem")
+		t.Setenv("MIRAIO_TLS_KEY", "")
+		_, err := loadTLSConfig()
+		assert.Error(t, err)
+	})
+
+	t.Run("MissingFiles", func(t *testing.T) {
+		t.Setenv("MIRAIO_TLS_CERT", "/nonexistent/cert.pem")
+		t.Setenv("MIRAIO_TLS_KEY", "/nonexistent/key.pem")
+		_, err := loadTLSConfig()
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadMinIOTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	get := func(transport http.RoundTripper) error {
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_CA_CERT", "")
+		t.Setenv("MIRAIO_MINIO_INSECURE_SKIP_VERIFY", "")
+		transport, err := loadMinIOTransport()
+		require.NoError(t, err)
+		assert.Nil(t, transport)
+	})
+
+	t.Run("Custom CA", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+		t.Setenv("MIRAIO_MINIO_CA_CERT", caFile)
+
+		transport, err := loadMinIOTransport()
+		require.NoError(t, err)
+		assert.NoError(t, get(transport))
+
+		defaultTransport, err := minio.DefaultTransport(true)
+		require.NoError(t, err)
+		assert.Error(t, get(defaultTransport), "the test CA is not trusted by default")
+	})
+
+	t.Run("Insecure skip verify", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_INSECURE_SKIP_VERIFY", "true")
+		transport, err := loadMinIOTransport()
+		require.NoError(t, err)
+		assert.NoError(t, get(transport))
+	})
+
+	t.Run("Unreadable or invalid CA", func(t *testing.T) {
+		t.Setenv("MIRAIO_MINIO_CA_CERT", filepath.Join(t.TempDir(), "missing.pem"))
+		_, err := loadMinIOTransport()
+		assert.ErrorContains(t, err, "reading MIRAIO_MINIO_CA_CERT")
+
+		garbage := filepath.Join(t.TempDir(), "garbage.pem")
+		require.NoError(t, os.WriteFile(garbage, []byte("not a certificate"), 0o600))
+		t.Setenv("MIRAIO_MINIO_CA_CERT", garbage)
+		_, err = loadMinIOTransport()
+		assert.ErrorContains(t, err, "contains no PEM certificates")
+	})
+}
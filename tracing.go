@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mirago/miraio/pkg/presign"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName identifies MiraIO's spans.
+const TracerName = "github.com/mirago/miraio"
+
+// tracer is nil unless MIRAIO_OTEL_ENDPOINT is set; every tracing hook
+// checks it first so that disabled tracing costs nothing per request.
+var tracer trace.Tracer
+
+// initTracing exports spans over OTLP/HTTP to endpoint (for example
+// http://collector:4318) and installs the W3C trace-context propagator. The
+// returned function flushes pending spans.
+func initTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "miraio"),
+		attribute.String("service.version", Version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	tracer = provider.Tracer(TracerName, trace.WithInstrumentationVersion(Version))
+	return provider.Shutdown, nil
+}
+
+// traceRequests continues the trace from the incoming headers and wraps
+// each request in a server span, which handlers pass on to backend calls
+// through the request context.
+func traceRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// tracingClient records each presign call as a client span.
+type tracingClient struct {
+	presign.Client
+}
+
+func (t tracingClient) PresignHeader(ctx context.Context, method, bucketName, objectName string, expires time.Duration, reqParams url.Values, extraHeaders http.Header) (*url.URL, error) {
+	ctx, span := tracer.Start(ctx, "minio.presign",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("aws.s3.bucket", bucketName),
+			attribute.String("aws.s3.key", objectName),
+			attribute.String("miraio.presign.method", method),
+		))
+	defer span.End()
+
+	u, err := t.Client.PresignHeader(ctx, method, bucketName, objectName, expires, reqParams, extraHeaders)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "presign failed")
+	}
+	return u, err
+}
+
+// withTracing wraps c so that its presign calls are traced when tracing is
+// enabled.
+func withTracing(c presign.Client) presign.Client {
+	if tracer == nil {
+		return c
+	}
+	return tracingClient{c}
+}
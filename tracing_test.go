@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTracing_Disabled(t *testing.T) {
+	tracer = nil
+	client := &countingClient{}
+	assert.Same(t, client, withTracing(client), "no wrapper when tracing is off")
+}
+
+func TestTraceRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer = provider.Tracer(TracerName)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() { tracer = nil }()
+
+	client := withTracing(&countingClient{})
+	router := gin.New()
+	router.Use(traceRequests())
+	router.GET("/presign", func(c *gin.Context) {
+		_, err := client.PresignHeader(c.Request.Context(), http.MethodPut, "test-bucket", "photo.jpg", time.Minute, nil, nil)
+		require.NoError(t, err)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/presign", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	minioSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /presign", serverSpan.Name())
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String(), "continues the caller's trace")
+	assert.Contains(t, serverSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+
+	assert.Equal(t, "minio.presign", minioSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), minioSpan.Parent().SpanID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("aws.s3.bucket", "test-bucket"),
+		attribute.String("aws.s3.key", "photo.jpg"),
+		attribute.String("miraio.presign.method", http.MethodPut),
+	}, minioSpan.Attributes())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// transportTuning sizes the pool of connections kept open to MinIO. Zero
+// fields keep minio-go's defaults (256 idle connections, 16 per host, 1m
+// idle timeout). Raising the per-host limit to roughly the number of
+// concurrent requests avoids opening a new connection, and with TLS a new
+// handshake, for most backend calls under load.
+type transportTuning struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+// minioTransportTuning is read from the MIRAIO_MINIO_MAX_IDLE_CONNS,
+// MIRAIO_MINIO_MAX_IDLE_CONNS_PER_HOST and MIRAIO_MINIO_IDLE_CONN_TIMEOUT
+// variables.
+var minioTransportTuning transportTuning
+
+// parseTransportTuning reads the pool settings through getenv.
+func parseTransportTuning(getenv func(string) string) (transportTuning, error) {
+	var t transportTuning
+	for name, dst := range map[string]*int{
+		"MIRAIO_MINIO_MAX_IDLE_CONNS":          &t.maxIdleConns,
+		"MIRAIO_MINIO_MAX_IDLE_CONNS_PER_HOST": &t.maxIdleConnsPerHost,
+	} {
+		if v := getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return transportTuning{}, fmt.Errorf("%s must be a positive integer, got %q", name, v)
+			}
+			*dst = n
+		}
+	}
+	if v := getenv("MIRAIO_MINIO_IDLE_CONN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return transportTuning{}, fmt.Errorf("MIRAIO_MINIO_IDLE_CONN_TIMEOUT must be a positive duration such as 90s, got %q", v)
+		}
+		t.idleConnTimeout = d
+	}
+	return t, nil
+}
+
+// isSet reports whether any setting differs from minio-go's defaults.
+func (t transportTuning) isSet() bool {
+	return t != transportTuning{}
+}
+
+// newMinIOTransport returns minio-go's default transport with t applied.
+func newMinIOTransport(t transportTuning) (*http.Transport, error) {
+	transport, err := minio.DefaultTransport(true)
+	if err != nil {
+		return nil, err
+	}
+	if t.maxIdleConns > 0 {
+		transport.MaxIdleConns = t.maxIdleConns
+	}
+	if t.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = t.maxIdleConnsPerHost
+	}
+	if t.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = t.idleConnTimeout
+	}
+	return transport, nil
+}
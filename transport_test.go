@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransportTuning(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	tuning, err := parseTransportTuning(getenv)
+	require.NoError(t, err)
+	assert.False(t, tuning.isSet())
+
+	env["MIRAIO_MINIO_MAX_IDLE_CONNS"] = "512"
+	env["MIRAIO_MINIO_MAX_IDLE_CONNS_PER_HOST"] = "128"
+	env["MIRAIO_MINIO_IDLE_CONN_TIMEOUT"] = "90s"
+	tuning, err = parseTransportTuning(getenv)
+	require.NoError(t, err)
+	assert.Equal(t, transportTuning{maxIdleConns: 512, maxIdleConnsPerHost: 128, idleConnTimeout: 90 * time.Second}, tuning)
+
+	for name, bad := range map[string]string{
+		"MIRAIO_MINIO_MAX_IDLE_CONNS":          "0",
+		"MIRAIO_MINIO_MAX_IDLE_CONNS_PER_HOST": "lots",
+		"MIRAIO_MINIO_IDLE_CONN_TIMEOUT":       "90",
+	} {
+		_, err := parseTransportTuning(func(k string) string {
+			if k == name {
+				return bad
+			}
+			return ""
+		})
+		assert.ErrorContains(t, err, name)
+	}
+}
+
+func TestNewMinIOTransport(t *testing.T) {
+	defaults, err := newMinIOTransport(transportTuning{})
+	require.NoError(t, err)
+
+	tuned, err := newMinIOTransport(transportTuning{maxIdleConnsPerHost: 128})
+	require.NoError(t, err)
+	assert.Equal(t, 128, tuned.MaxIdleConnsPerHost)
+	assert.Equal(t, defaults.MaxIdleConns, tuned.MaxIdleConns)
+	assert.Equal(t, defaults.IdleConnTimeout, tuned.IdleConnTimeout)
+}
+
+func TestLoadMinIOTransport_Tuning(t *testing.T) {
+	t.Setenv("MIRAIO_MINIO_CA_CERT", "")
+	t.Setenv("MIRAIO_MINIO_INSECURE_SKIP_VERIFY", "")
+	minioTransportTuning = transportTuning{idleConnTimeout: 5 * time.Minute}
+	defer func() { minioTransportTuning = transportTuning{} }()
+
+	transport, err := loadMinIOTransport()
+	require.NoError(t, err)
+	require.IsType(t, &http.Transport{}, transport)
+	assert.Equal(t, 5*time.Minute, transport.(*http.Transport).IdleConnTimeout)
+}
+
+// BenchmarkPresignGetVersion measures /presign-get with versionId, which
+// stats the object on MinIO before signing, from many goroutines at once.
+// When more requests finish together than the pool keeps idle connections
+// per host, the extra connections are closed and later redialled; conns/op
+// reports how many connections the fake MinIO accepted per request.
+func BenchmarkPresignGetVersion(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		tuning transportTuning
+	}{
+		// net/http's own default of 2 idle connections per host.
+		{"perhost-2", transportTuning{maxIdleConnsPerHost: 2}},
+		{"default", transportTuning{}},
+		{"tuned", transportTuning{maxIdleConns: 1024, maxIdleConnsPerHost: 512}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			setupTestEnvironment()
+			defer setupTestEnvironment()
+
+			var conns atomic.Int64
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+				w.Header().Set("ETag", `"9b2cf535f27731c974343645a3985328"`)
+				w.Header().Set("x-amz-version-id", "v1")
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			transport, err := newMinIOTransport(bc.tuning)
+			require.NoError(b, err)
+			defer transport.CloseIdleConnections()
+			u, err := url.Parse(server.URL)
+			require.NoError(b, err)
+			minioClient, err = minio.New(u.Host, &minio.Options{
+				Creds:     credentials.NewStaticV4("minio", "minio123", ""),
+				Region:    minioRegion,
+				Transport: transport,
+			})
+			require.NoError(b, err)
+			signer = newSigner()
+
+			router := gin.New()
+			router.GET("/presign-get", presignGetHandler)
+
+			b.SetParallelism(64)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, httptest.NewRequest("GET", "/presign-get?filename=a.txt&versionId=v1", nil))
+					if w.Code != http.StatusOK {
+						b.Errorf("status %d: %s", w.Code, w.Body.String())
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
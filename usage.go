@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/mirago/miraio/utils"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultUsageCacheTTL is how long a usage result is served before it is
+// recomputed, when MIRAIO_USAGE_CACHE_TTL is unset.
+const DefaultUsageCacheTTL = 5 * time.Minute
+
+// UsageComputeTimeout bounds a background usage recomputation.
+const UsageComputeTimeout = 10 * time.Minute
+
+// UsageRetentionTTLs is how many TTLs a usage result is kept without being
+// recomputed. Results of prefixes that are still asked for are refreshed
+// every TTL, so only abandoned prefixes reach it.
+const UsageRetentionTTLs = 3
+
+// usageResult is the storage used under a prefix.
+type usageResult struct {
+	Prefix     string    `json:"prefix"`
+	Objects    int64     `json:"objects"`
+	Bytes      int64     `json:"bytes"`
+	ComputedAt time.Time `json:"computedAt" snake:"computed_at"`
+}
+
+type usageEntry struct {
+	result     usageResult
+	refreshing bool
+}
+
+// usageCache serves usage results and recomputes stale ones in the
+// background, so only the very first request for a prefix waits for a full
+// listing. Concurrent first requests share that listing.
+type usageCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*usageEntry
+	compute func(ctx context.Context, prefix string) (usageResult, error)
+	misses  singleflight.Group
+}
+
+var usage = newUsageCache(DefaultUsageCacheTTL, computeUsage)
+
+func newUsageCache(ttl time.Duration, compute func(context.Context, string) (usageResult, error)) *usageCache {
+	return &usageCache{ttl: ttl, entries: make(map[string]*usageEntry), compute: compute}
+}
+
+// get returns the cached usage of prefix, triggering a background refresh
+// when it is older than the TTL. Without a cached value it waits for one to
+// be computed. The listing runs detached from ctx, so callers that give up
+// do not cancel it for the others, and its result is cached either way.
+func (u *usageCache) get(ctx context.Context, prefix string) (usageResult, error) {
+	u.mu.Lock()
+	if e, ok := u.entries[prefix]; ok {
+		if time.Since(e.result.ComputedAt) > u.ttl && !e.refreshing {
+			e.refreshing = true
+			go u.refresh(prefix)
+		}
+		result := e.result
+		u.mu.Unlock()
+		return result, nil
+	}
+	u.mu.Unlock()
+
+	ch := u.misses.DoChan(prefix, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), UsageComputeTimeout)
+		defer cancel()
+		result, err := u.compute(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		u.store(prefix, result)
+		return result, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return usageResult{}, res.Err
+		}
+		return res.Val.(usageResult), nil
+	case <-ctx.Done():
+		return usageResult{}, ctx.Err()
+	}
+}
+
+func (u *usageCache) refresh(prefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), UsageComputeTimeout)
+	defer cancel()
+
+	result, err := u.compute(ctx, prefix)
+	if err != nil {
+		utils.LogWarning("Recomputing usage of %q failed; serving previous value: %v", prefix, err)
+		u.mu.Lock()
+		u.entries[prefix].refreshing = false
+		u.mu.Unlock()
+		return
+	}
+	u.store(prefix, result)
+}
+
+func (u *usageCache) store(prefix string, result usageResult) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.entries[prefix] = &usageEntry{result: result}
+}
+
+// sweep drops results older than UsageRetentionTTLs TTLs, unless they are
+// being recomputed.
+func (u *usageCache) sweep(now time.Time) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cutoff := now.Add(-UsageRetentionTTLs * u.ttl)
+	evicted := 0
+	for prefix, e := range u.entries {
+		if !e.refreshing && e.result.ComputedAt.Before(cutoff) {
+			delete(u.entries, prefix)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// computeUsage lists every object under prefix and totals their sizes.
+func computeUsage(ctx context.Context, prefix string) (usageResult, error) {
+	start := time.Now()
+	result := usageResult{Prefix: strings.TrimPrefix(prefix, keyPrefix)}
+	for obj := range minioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return usageResult{}, obj.Err
+		}
+		result.Objects++
+		result.Bytes += obj.Size
+	}
+	result.ComputedAt = time.Now().UTC()
+	utils.LogInfo("Computed usage of %q: %d objects, %d bytes in %s", prefix, result.Objects, result.Bytes, time.Since(start).Round(time.Millisecond))
+	return result, nil
+}
+
+// usageHandler reports object count and total size under an optional
+// prefix (the whole deployment prefix by default).
+func usageHandler(c *gin.Context) {
+	prefix := keyPrefix
+	if v := c.Query("prefix"); v != "" {
+		var err error
+		if prefix, err = objectKey(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ctx, cancel := requestContext(c)
+	defer cancel()
+
+	result, err := usage.get(ctx, prefix)
+	if err != nil {
+		respondBackendError(c, ctx, err, "Could not compute usage")
+		return
+	}
+	respondJSON(c, http.StatusOK, result)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageCache(t *testing.T) {
+	var calls atomic.Int64
+	refreshed := make(chan struct{}, 1)
+	cache := newUsageCache(time.Hour, func(ctx context.Context, prefix string) (usageResult, error) {
+		n := calls.Add(1)
+		defer func() {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		}()
+		return usageResult{Prefix: prefix, Objects: n, Bytes: 10 * n, ComputedAt: time.Now()}, nil
+	})
+	ctx := context.Background()
+
+	first, err := cache.get(ctx, "logs/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Objects)
+	<-refreshed
+
+	again, err := cache.get(ctx, "logs/")
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "fresh results are served from the cache")
+	assert.Equal(t, int64(1), calls.Load())
+
+	// Once stale, the old value is returned immediately and a new one is
+	// computed in the background.
+	cache.mu.Lock()
+	cache.entries["logs/"].result.ComputedAt = time.Now().Add(-2 * time.Hour)
+	cache.mu.Unlock()
+
+	stale, err := cache.get(ctx, "logs/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stale.Objects)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale usage was not recomputed")
+	}
+	require.Eventually(t, func() bool {
+		result, _ := cache.get(ctx, "logs/")
+		return result.Objects == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestUsageCache_Error(t *testing.T) {
+	cache := newUsageCache(time.Hour, func(ctx context.Context, prefix string) (usageResult, error) {
+		return usageResult{}, errors.New("listing failed")
+	})
+
+	_, err := cache.get(context.Background(), "")
+	assert.EqualError(t, err, "listing failed")
+	assert.Empty(t, cache.entries, "failures are not cached")
+}
+
+func TestUsageCache_ConcurrentMisses(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	cache := newUsageCache(time.Hour, func(ctx context.Context, prefix string) (usageResult, error) {
+		calls.Add(1)
+		<-release
+		return usageResult{Prefix: prefix, Objects: 3, ComputedAt: time.Now()}, nil
+	})
+
+	results := make(chan usageResult, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			result, err := cache.get(context.Background(), "logs/")
+			assert.NoError(t, err)
+			results <- result
+		}()
+	}
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// A caller that gives up does not cancel the listing for the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.get(ctx, "logs/")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, int64(3), (<-results).Objects)
+	}
+	assert.Equal(t, int64(1), calls.Load())
+}
+
+func TestUsageCache_Sweep(t *testing.T) {
+	cache := newUsageCache(time.Minute, nil)
+	now := time.Now()
+	cache.store("old/", usageResult{ComputedAt: now.Add(-UsageRetentionTTLs*time.Minute - time.Second)})
+	cache.store("recent/", usageResult{ComputedAt: now.Add(-2 * time.Minute)})
+	cache.store("refreshing/", usageResult{ComputedAt: now.Add(-time.Hour)})
+	cache.entries["refreshing/"].refreshing = true
+
+	assert.Equal(t, 1, cache.sweep(now))
+	assert.NotContains(t, cache.entries, "old/")
+	assert.Contains(t, cache.entries, "recent/")
+	assert.Contains(t, cache.entries, "refreshing/")
+}
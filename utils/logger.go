@@ -5,7 +5,11 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -15,66 +19,195 @@ var (
 	errorLogger   *log.Logger
 	fatalLogger   *log.Logger
 	debugLogger   *log.Logger
+
+	// mu guards the loggers, the open log file and its path.
+	mu      sync.RWMutex
+	logFile *os.File
+	logPath string
+
+	// fallbackOnce installs stderr-only loggers the first time something is
+	// logged before InitLogger has run.
+	fallbackOnce sync.Once
 )
 
-// InitLogger initializes the standard logger with custom settings
+// DefaultLogDir is where the log file goes when MIRAIO_LOG_DIR is unset.
+const DefaultLogDir = "/var/log/miraio"
+
+// InitLogger initializes the standard logger with custom settings. It is safe
+// to call concurrently; each call replaces the previous outputs.
+//
+// Logs go to stdout and to a file in MIRAIO_LOG_DIR. MIRAIO_LOG_TO_FILE=false
+// skips the file; when the directory cannot be written, for example on a
+// read-only container filesystem, a single warning is logged and the server
+// carries on with stdout only.
 func InitLogger() {
+	warning, err := initLogger()
+	if err != nil {
+		LogFatal("%v", err)
+	}
+	if warning != "" {
+		LogWarning("%s", warning)
+	}
+}
+
+// initLogger does the work of InitLogger under mu, returning a warning to
+// log once the lock is released.
+func initLogger() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	toFile := true
+	if v := os.Getenv("MIRAIO_LOG_TO_FILE"); v != "" {
+		var err error
+		if toFile, err = strconv.ParseBool(v); err != nil {
+			useStdout()
+			return "", fmt.Errorf("Invalid MIRAIO_LOG_TO_FILE: %q is not a boolean", v)
+		}
+	}
+	if !toFile {
+		useStdout()
+		return "", nil
+	}
+
 	// Set log directory
 	logDir := os.Getenv("MIRAIO_LOG_DIR")
 	if logDir == "" {
-		logDir = "/var/log/miraio"
+		logDir = DefaultLogDir
 	}
 
 	if err := os.MkdirAll(logDir, 0755); err != nil {
-		fmt.Printf("Failed to create log directory: %v\n", err)
-		os.Exit(1)
+		useStdout()
+		return fmt.Sprintf("Log directory %s is not writable, logging to stdout only: %v", logDir, err), nil
 	}
 
 	// Create log file with timestamp
 	timestamp := time.Now().Format("2006-01-02-15-04-05")
-	logFile := filepath.Join(logDir, fmt.Sprintf("server-%s.log", timestamp))
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	path := filepath.Join(logDir, fmt.Sprintf("server-%s.log", timestamp))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
-		fmt.Printf("Failed to open log file: %v\n", err)
-		os.Exit(1)
+		useStdout()
+		return fmt.Sprintf("Log directory %s is not writable, logging to stdout only: %v", logDir, err), nil
 	}
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	logPath = path
 
 	// Create multi-writer to write to both file and stdout
-	multiWriter := io.MultiWriter(os.Stdout, file)
+	setOutput(io.MultiWriter(os.Stdout, file))
+
+	infoLogger.Printf("Logger initialized with log file: %s", path)
+	return "", nil
+}
 
+// useStdout drops any open log file and logs to stdout alone. Callers must
+// hold mu.
+func useStdout() {
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = nil
+	logPath = ""
+	setOutput(os.Stdout)
+}
+
+// LogPath returns the file the log is written to, or "" when logging to
+// stdout only.
+func LogPath() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logPath
+}
+
+// ReopenLogFile closes the current log file and opens its path again, so
+// writes land in a fresh file after an external tool such as logrotate has
+// moved the old one away. It is a no-op before InitLogger.
+func ReopenLogFile() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if logPath == "" {
+		return nil
+	}
+	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	setOutput(io.MultiWriter(os.Stdout, file))
+	return nil
+}
+
+// ReopenOnHangup reopens the log file every time the process receives
+// SIGHUP, the signal log-rotation tools send after moving a file.
+func ReopenOnHangup() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := ReopenLogFile(); err != nil {
+				LogError("Failed to reopen log file: %v", err)
+				continue
+			}
+			LogInfo("Reopened log file after SIGHUP")
+		}
+	}()
+}
+
+// setOutput points every level logger at w. Callers must hold mu.
+func setOutput(w io.Writer) {
 	// Initialize loggers with different prefixes
-	infoLogger = log.New(multiWriter, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	warningLogger = log.New(multiWriter, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile)
-	errorLogger = log.New(multiWriter, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-	fatalLogger = log.New(multiWriter, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile)
-	debugLogger = log.New(multiWriter, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+	infoLogger = log.New(w, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+	warningLogger = log.New(w, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile)
+	errorLogger = log.New(w, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+	fatalLogger = log.New(w, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile)
+	debugLogger = log.New(w, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile)
+}
+
+// output writes msg through the logger selected by pick, falling back to
+// stderr when InitLogger has not been called yet.
+func output(pick func() *log.Logger, msg string) {
+	fallbackOnce.Do(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if infoLogger == nil {
+			setOutput(os.Stderr)
+		}
+	})
 
-	infoLogger.Printf("Logger initialized with log file: %s", logFile)
+	mu.RLock()
+	logger := pick()
+	mu.RUnlock()
+	logger.Output(3, msg)
 }
 
 // LogError logs an error message
 func LogError(format string, args ...interface{}) {
-	errorLogger.Output(2, fmt.Sprintf(format, args...))
+	output(func() *log.Logger { return errorLogger }, fmt.Sprintf(format, args...))
 }
 
 // LogWarning logs a warning message
 func LogWarning(format string, args ...interface{}) {
-	warningLogger.Output(2, fmt.Sprintf(format, args...))
+	output(func() *log.Logger { return warningLogger }, fmt.Sprintf(format, args...))
 }
 
 // LogInfo logs an info message
 func LogInfo(format string, args ...interface{}) {
-	infoLogger.Output(2, fmt.Sprintf(format, args...))
+	output(func() *log.Logger { return infoLogger }, fmt.Sprintf(format, args...))
 }
 
 // LogDebug logs a debug message
 func LogDebug(format string, args ...interface{}) {
-	debugLogger.Output(2, fmt.Sprintf(format, args...))
+	output(func() *log.Logger { return debugLogger }, fmt.Sprintf(format, args...))
 }
 
 // LogFatal logs a fatal message and exits
 func LogFatal(format string, args ...interface{}) {
-	fatalLogger.Output(2, fmt.Sprintf(format, args...))
+	output(func() *log.Logger { return fatalLogger }, fmt.Sprintf(format, args...))
 	os.Exit(1)
 }
 
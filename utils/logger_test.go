@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBeforeInitLogger(t *testing.T) {
+	assert.NotPanics(t, func() {
+		LogError("logged before InitLogger: %d", 42)
+		LogInfo("info before InitLogger")
+	})
+}
+
+func TestInitLoggerConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MIRAIO_LOG_DIR", dir)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			InitLogger()
+			LogInfo("concurrent init")
+		}()
+	}
+	wg.Wait()
+
+	LogWarning("after init")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	var contents strings.Builder
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		contents.Write(data)
+	}
+	assert.Contains(t, contents.String(), "WARNING: ")
+	assert.Contains(t, contents.String(), "after init")
+}
+
+func TestReopenLogFileAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MIRAIO_LOG_DIR", dir)
+	InitLogger()
+
+	mu.RLock()
+	path := logPath
+	mu.RUnlock()
+
+	LogInfo("before rotation")
+	rotated := path + ".1"
+	require.NoError(t, os.Rename(path, rotated))
+
+	require.NoError(t, ReopenLogFile())
+	LogInfo("after rotation")
+
+	old, err := os.ReadFile(rotated)
+	require.NoError(t, err)
+	assert.Contains(t, string(old), "before rotation")
+	assert.NotContains(t, string(old), "after rotation")
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(current), "after rotation")
+	assert.NotContains(t, string(current), "before rotation")
+}
+
+// captureStdout points os.Stdout at a temporary file for the rest of the
+// test and returns a function reading what was written.
+func captureStdout(t *testing.T) func() string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	require.NoError(t, err)
+	orig := os.Stdout
+	os.Stdout = f
+	t.Cleanup(func() {
+		os.Stdout = orig
+		f.Close()
+	})
+	return func() string {
+		data, err := os.ReadFile(f.Name())
+		require.NoError(t, err)
+		return string(data)
+	}
+}
+
+func TestInitLoggerUnwritableDir(t *testing.T) {
+	// A directory below a regular file cannot be created, even as root,
+	// which a permission-based setup would not guarantee.
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o644))
+	t.Setenv("MIRAIO_LOG_DIR", filepath.Join(blocker, "logs"))
+	stdout := captureStdout(t)
+
+	assert.NotPanics(t, InitLogger)
+	assert.Empty(t, LogPath())
+	require.NoError(t, ReopenLogFile(), "reopening is a no-op without a file")
+	LogInfo("still logging")
+
+	out := stdout()
+	assert.Equal(t, 1, strings.Count(out, "WARNING: "), out)
+	assert.Contains(t, out, "logging to stdout only")
+	assert.Contains(t, out, "still logging")
+}
+
+func TestInitLoggerToFileDisabled(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MIRAIO_LOG_DIR", dir)
+	t.Setenv("MIRAIO_LOG_TO_FILE", "false")
+	stdout := captureStdout(t)
+
+	InitLogger()
+	LogInfo("stdout only")
+
+	assert.Empty(t, LogPath())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Contains(t, stdout(), "stdout only")
+	assert.NotContains(t, stdout(), "WARNING: ")
+}
@@ -0,0 +1,16 @@
+package utils
+
+// MaskSecret returns a loggable form of a credential: the first four
+// characters followed by asterisks, or only asterisks for values too short to
+// partially reveal. Empty values stay empty so "unset" remains visible.
+func MaskSecret(secret string) string {
+	const visible = 4
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) <= 2*visible:
+		return "****"
+	default:
+		return secret[:visible] + "****"
+	}
+}
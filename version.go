@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build metadata, injected at build time with e.g.
+//
+//	go build -ldflags "-X main.Version=1.2.0 -X main.Commit=$(git rev-parse --short HEAD) -X main.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unset values report "dev".
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+// versionResponse is the body of GET /version.
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime" snake:"build_time"`
+}
+
+// versionHandler reports the build metadata of the running binary.
+func versionHandler(c *gin.Context) {
+	respondJSON(c, http.StatusOK, versionResponse{Version: Version, Commit: Commit, BuildTime: BuildTime})
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHandler_Defaults(t *testing.T) {
+	router := gin.New()
+	router.GET("/version", versionHandler)
+
+	req, err := http.NewRequest("GET", "/version", nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"version":"dev","commit":"dev","buildTime":"dev"}`, recorder.Body.String())
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mirago/miraio/utils"
+)
+
+// Webhook delivery limits. Events are queued and sent by a single worker so
+// a slow webhook never holds up requests; when the queue is full, events
+// are dropped and counted in /stats.
+const (
+	WebhookQueueSize   = 256
+	WebhookMaxAttempts = 4
+	WebhookTimeout     = 5 * time.Second
+)
+
+// webhookRetryDelay is the backoff before the second delivery attempt; it
+// doubles after each failure.
+var webhookRetryDelay = 500 * time.Millisecond
+
+// webhookEvent is the JSON body posted for each issued presigned URL. A
+// /presign-prefix page is reported as one presign.prefix event whose Key is
+// the prefix and Count the number of URLs issued, rather than one event per
+// object, which would flood the queue.
+type webhookEvent struct {
+	Event       string    `json:"event"`
+	Method      string    `json:"method"`
+	Filename    string    `json:"filename"`
+	Key         string    `json:"key"`
+	ContentType string    `json:"contentType,omitempty"`
+	Count       int       `json:"count,omitempty"`
+	ClientIP    string    `json:"clientIp"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// webhookNotifier posts events to MIRAIO_WEBHOOK_URL in the background.
+type webhookNotifier struct {
+	url     string
+	client  *http.Client
+	events  chan webhookEvent
+	dropped atomic.Int64
+}
+
+// webhook is nil when MIRAIO_WEBHOOK_URL is unset.
+var webhook *webhookNotifier
+
+// newWebhookNotifier starts the delivery worker for url.
+func newWebhookNotifier(url string) *webhookNotifier {
+	w := &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: WebhookTimeout},
+		events: make(chan webhookEvent, WebhookQueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// notifyPresign queues an event for an issued presigned URL without
+// blocking. It is a no-op when webhooks are disabled.
+func (w *webhookNotifier) notifyPresign(method, filename, key, contentType, clientIP string) {
+	if w == nil {
+		return
+	}
+	w.enqueue(webhookEvent{
+		Event:       "presign.issued",
+		Method:      method,
+		Filename:    filename,
+		Key:         key,
+		ContentType: contentType,
+		ClientIP:    clientIP,
+		Timestamp:   time.Now().UTC(),
+	})
+}
+
+// notifyPrefix queues a single event for a page of download URLs issued
+// under prefix. It is a no-op when webhooks are disabled or no URLs were
+// issued.
+func (w *webhookNotifier) notifyPrefix(prefix, key string, count int, clientIP string) {
+	if w == nil || count == 0 {
+		return
+	}
+	w.enqueue(webhookEvent{
+		Event:     "presign.prefix",
+		Method:    http.MethodGet,
+		Filename:  prefix,
+		Key:       key,
+		Count:     count,
+		ClientIP:  clientIP,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// enqueue hands event to the worker, dropping it when the queue is full.
+func (w *webhookNotifier) enqueue(event webhookEvent) {
+	select {
+	case w.events <- event:
+	default:
+		w.dropped.Add(1)
+		utils.LogWarning("Webhook queue full; dropping %s event for %s", event.Method, event.Key)
+	}
+}
+
+// droppedEvents returns how many events were dropped on a full queue. It is
+// zero when webhooks are disabled.
+func (w *webhookNotifier) droppedEvents() int64 {
+	if w == nil {
+		return 0
+	}
+	return w.dropped.Load()
+}
+
+func (w *webhookNotifier) run() {
+	for event := range w.events {
+		w.deliver(event)
+	}
+}
+
+// deliver posts event, retrying failures with exponential backoff, and drops
+// it with an error log once WebhookMaxAttempts is reached.
+func (w *webhookNotifier) deliver(event webhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		utils.LogError("Encoding webhook event: %v", err)
+		return
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = w.post(body)
+		if err == nil {
+			return
+		}
+		if attempt == WebhookMaxAttempts {
+			utils.LogError("Dropping webhook event for %s after %d attempts: %v", event.Key, attempt, err)
+			return
+		}
+		utils.LogDebug("Webhook delivery failed (attempt %d/%d, retrying in %s): %v", attempt, WebhookMaxAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (w *webhookNotifier) post(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan webhookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhookEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	defer server.Close()
+
+	setupTestEnvironment()
+	webhook = newWebhookNotifier(server.URL)
+	defer func() { webhook = nil }()
+
+	router := gin.New()
+	router.GET("/presign", presignHandler)
+	req := httptest.NewRequest("GET", "/presign?filename=photo.jpg&type=image/jpeg", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "presign.issued", event.Event)
+		assert.Equal(t, http.MethodPut, event.Method)
+		assert.Equal(t, "photo.jpg", event.Filename)
+		assert.Equal(t, "photo.jpg", event.Key)
+		assert.Equal(t, "image/jpeg", event.ContentType)
+		assert.Equal(t, "203.0.113.7", event.ClientIP)
+		assert.WithinDuration(t, time.Now(), event.Timestamp, time.Minute)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook event not delivered")
+	}
+}
+
+func TestWebhookNotifier_RetriesThenDrops(t *testing.T) {
+	defer func(d time.Duration) { webhookRetryDelay = d }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	n := &webhookNotifier{url: server.URL, client: server.Client()}
+	n.deliver(webhookEvent{Key: "a.txt"})
+	assert.Equal(t, int32(WebhookMaxAttempts), attempts.Load())
+}
+
+func TestWebhookNotifier_DropsWhenQueueFull(t *testing.T) {
+	// No worker drains this queue, so the second event must be dropped
+	// rather than block the caller.
+	n := &webhookNotifier{events: make(chan webhookEvent, 1)}
+
+	done := make(chan struct{})
+	go func() {
+		n.notifyPresign(http.MethodPut, "a.txt", "a.txt", "text/plain", "127.0.0.1")
+		n.notifyPresign(http.MethodPut, "b.txt", "b.txt", "text/plain", "127.0.0.1")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifyPresign blocked on a full queue")
+	}
+	assert.Len(t, n.events, 1)
+	assert.EqualValues(t, 1, n.droppedEvents())
+}
+
+func TestWebhookNotifier_PrefixIsOneEvent(t *testing.T) {
+	n := &webhookNotifier{events: make(chan webhookEvent, WebhookQueueSize)}
+
+	n.notifyPrefix("album/", "album/", 0, "127.0.0.1")
+	assert.Empty(t, n.events, "no event without URLs")
+
+	n.notifyPrefix("album/", "uploads/album/", DefaultMaxPrefixObjects, "127.0.0.1")
+	require.Len(t, n.events, 1)
+	event := <-n.events
+	assert.Equal(t, "presign.prefix", event.Event)
+	assert.Equal(t, http.MethodGet, event.Method)
+	assert.Equal(t, "album/", event.Filename)
+	assert.Equal(t, "uploads/album/", event.Key)
+	assert.Equal(t, DefaultMaxPrefixObjects, event.Count)
+}
+
+func TestWebhookNotifier_Disabled(t *testing.T) {
+	var n *webhookNotifier
+	assert.NotPanics(t, func() { n.notifyPresign(http.MethodGet, "a", "a", "", "") })
+	assert.NotPanics(t, func() { n.notifyPrefix("a/", "a/", 1, "") })
+	assert.Zero(t, n.droppedEvents())
+}